@@ -18,10 +18,14 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"syscall"
@@ -31,21 +35,30 @@ import (
 	"github.com/SealSC/SealABC/cli"
 	"github.com/SealSC/SealABC/common/utility"
 	"github.com/SealSC/SealABC/config"
+	"github.com/SealSC/SealABC/consensus"
 	"github.com/SealSC/SealABC/consensus/hotStuff"
+	"github.com/SealSC/SealABC/consensus/pbft"
+	"github.com/SealSC/SealABC/consensus/solo"
 	"github.com/SealSC/SealABC/crypto"
 	"github.com/SealSC/SealABC/crypto/ciphers"
 	"github.com/SealSC/SealABC/crypto/hashes"
 	"github.com/SealSC/SealABC/crypto/signers"
+	"github.com/SealSC/SealABC/crypto/signers/bls12381"
 	"github.com/SealSC/SealABC/crypto/signers/ecdsa/secp256k1"
 	"github.com/SealSC/SealABC/engine"
 	"github.com/SealSC/SealABC/engine/engineStartup"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/applicationCommonConfig"
+	"github.com/SealSC/SealABC/network"
 	"github.com/SealSC/SealABC/network/topology/p2p/fullyConnect"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip"
+	gossipTopology "github.com/SealSC/SealABC/network/topology/p2p/gossip/topology"
 	"github.com/SealSC/SealABC/service/application/basicAssets"
 	"github.com/SealSC/SealABC/service/application/memo"
 	"github.com/SealSC/SealABC/service/application/smartAssets"
 	"github.com/SealSC/SealABC/service/application/smartAssets/smartAssetsLedger"
+	"github.com/SealSC/SealABC/service/application/validatorSet"
+	"github.com/SealSC/SealABC/service/application/validatorSet/validatorSetInterface"
 	"github.com/SealSC/SealABC/service/system"
 	"github.com/SealSC/SealABC/service/system/blockchain"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
@@ -58,6 +71,8 @@ import (
 	"golang.org/x/term"
 )
 
+const shutdownTimeout = time.Second * 30
+
 func runtimeInit() {
 	//runtime.GOMAXPROCS(1)
 	runtime.SetMutexProfileFraction(1)
@@ -65,6 +80,70 @@ func runtimeInit() {
 	//runtime.SetCPUProfileRate(1)
 }
 
+func runOfflineCommand(chainCfg blockchain.Config) {
+	//the consensus is not started offline, so the members to verify the finality proofs are unknown
+	chainCfg.FinalityVerifier = nil
+
+	var err error
+	switch cli.Parameters.Command {
+	case cli.RevertCommand:
+		err = blockchain.RevertTo(chainCfg, cli.Parameters.Height)
+
+	case cli.ExportCommand:
+		err = blockchain.Export(chainCfg, cli.Parameters.File, cli.Parameters.From, cli.Parameters.To, cli.ShowProgress)
+
+	case cli.ImportCommand:
+		err = blockchain.Import(chainCfg, cli.Parameters.File, cli.ShowProgress)
+
+	default:
+		err = errors.New("unknown command " + cli.Parameters.Command)
+	}
+
+	if err != nil {
+		fmt.Println()
+		fmt.Println(cli.Parameters.Command, " failed: ", err.Error())
+		return
+	}
+
+	fmt.Println(cli.Parameters.Command, " done")
+}
+
+//the topology named in the config, the gossip links the consensus members directly
+func networkTopology(name string) network.ITopology {
+	if name != "gossip" {
+		return fullyConnect.NewTopology()
+	}
+
+	return gossip.NewTopology(gossipTopology.Config{
+		MaxPeers:   config.StaticConfigs.GossipConf.MaxPeers,
+		Fanout:     config.StaticConfigs.GossipConf.Fanout,
+		MaxHops:    config.StaticConfigs.GossipConf.MaxHops,
+		Validators: config.StaticConfigs.ConsensusConf.Members,
+	})
+}
+
+func smartAssetsGenesis(genesis *config.Genesis) (saGenesis smartAssetsLedger.Genesis) {
+	if genesis == nil {
+		return
+	}
+
+	for _, b := range genesis.SmartAssets.Balances {
+		saGenesis.Balances = append(saGenesis.Balances, smartAssetsLedger.GenesisBalance{
+			Address: b.Address,
+			Amount:  b.Amount,
+		})
+	}
+
+	for _, c := range genesis.SmartAssets.Contracts {
+		saGenesis.Contracts = append(saGenesis.Contracts, smartAssetsLedger.GenesisContract{
+			Address: c.Address,
+			Code:    c.Code,
+		})
+	}
+
+	return
+}
+
 func main() {
 	cli.Run()
 
@@ -80,6 +159,21 @@ func main() {
 
 	crypto.Load()
 
+	//the genesis overrides the chain settings of the config
+	var genesis *config.Genesis
+	networkID := ""
+	if config.StaticConfigs.GenesisFile != "" {
+		genesis, err = config.LoadGenesis(config.StaticConfigs.GenesisFile,
+			hashes.HashCalculatorByAlgorithmType(config.StaticConfigs.CryptoConf.HashType))
+		if err != nil {
+			fmt.Println("load genesis file error: ", err)
+			return
+		}
+
+		genesis.ApplyTo(&config.StaticConfigs)
+		networkID = hex.EncodeToString(genesis.Hash())
+	}
+
 	log.SetUpLogger(log.Config{
 		Level:   logrus.Level(config.StaticConfigs.LogConf.LogLevel),
 		LogFile: config.StaticConfigs.LogConf.LogFile})
@@ -131,6 +225,8 @@ func main() {
 	bhtConfig := hotStuff.Config{
 		MemberOnlineCheckInterval: time.Millisecond * time.Duration(config.StaticConfigs.ConsensusConf.MemberOnlineCheckInterval),
 		ConsensusTimeout:          time.Millisecond * time.Duration(config.StaticConfigs.ConsensusConf.ConsensusTimeOut),
+		MaxConsensusTimeout:       time.Millisecond * time.Duration(config.StaticConfigs.ConsensusConf.MaxConsensusTimeout),
+		DisableLeaderReputation:   config.StaticConfigs.ConsensusConf.DisableLeaderReputation,
 		SingerGenerator:           cryptoTools.SignerGenerator,
 		HashCalc:                  cryptoTools.HashCalculator,
 		SelfSigner:                selfSigner,
@@ -145,17 +241,154 @@ func main() {
 		bhtConfig.Members = append(bhtConfig.Members, newMember)
 	}
 
+	//BLS aggregate QC, the aggregate keys are in the same order of members and every key must have its proof of possession
+	if config.StaticConfigs.ConsensusConf.QCType == hotStuff.AggregateQC {
+		aggregateAcc, err := account.FromStore(config.StaticConfigs.WalletConf.AggregateWalletPath, cli.Parameters.Password)
+		if err != nil {
+			fmt.Println("restore aggregate account error: ", err)
+			return
+		}
+
+		aggregateSigner := aggregateAcc.Signer
+		if aggregateSigner.Type() != bls12381.SignerGenerator.Type() {
+			fmt.Println("aggregate account is not a ", bls12381.SignerGenerator.Type(), " account")
+			return
+		}
+
+		selfProof, err := bls12381.ProvePossession(aggregateSigner)
+		if err != nil {
+			fmt.Println("build aggregate key proof failed: ", err.Error())
+			return
+		}
+		log.Log.Println("self aggregate key: ", aggregateSigner.PublicKeyString(), " proof: ", hex.EncodeToString(selfProof))
+
+		aggregateKeys := config.StaticConfigs.ConsensusConf.AggregateMembers
+		aggregateProofs := config.StaticConfigs.ConsensusConf.AggregateProofs
+		if len(aggregateKeys) != len(bhtConfig.Members) || len(aggregateProofs) != len(bhtConfig.Members) {
+			fmt.Println("every member needs an aggregate key and its proof")
+			return
+		}
+
+		bhtConfig.QCType = hotStuff.AggregateQC
+		bhtConfig.AggregateSigner = aggregateSigner
+		for i, key := range aggregateKeys {
+			aggregateKey, err := hex.DecodeString(key)
+			if err != nil {
+				fmt.Println("invalid aggregate key of member ", i, ": ", err.Error())
+				return
+			}
+
+			proof, err := hex.DecodeString(aggregateProofs[i])
+			if err != nil {
+				fmt.Println("invalid aggregate key proof of member ", i, ": ", err.Error())
+				return
+			}
+
+			if passed, _ := bls12381.VerifyPossession(aggregateKey, proof); !passed {
+				fmt.Println("aggregate key proof of member ", i, " verify failed")
+				return
+			}
+
+			bhtConfig.Members[i].AggregateKey = aggregateKey
+		}
+	}
+
+	//consensus safety state storage
+	if config.StaticConfigs.ConsensusConf.ConsensusDB != "" {
+		consensusStorage, err := db.NewKVDatabaseDriver(dbInterface.LevelDB, levelDB.Config{
+			DBFilePath: config.StaticConfigs.ConsensusConf.ConsensusDB,
+		})
+		if err != nil {
+			fmt.Println("open consensus database failed: ", err.Error())
+			return
+		}
+		bhtConfig.StateStore = consensus.NewKVStateStore(consensusStorage)
+	}
+
+	//load validator set application, the members of hot-stuff will be reloaded from chain
+	var validatorSetApp *validatorSetInterface.ValidatorSetApplication
+	if config.StaticConfigs.ValidatorSetAppConf.ValidatorSetAppEnable {
+		validatorSet.Load()
+		validatorSetApp, err = validatorSet.NewValidatorSetApplication(validatorSet.Config{
+			Config: applicationCommonConfig.Config{
+				KVDBName: dbInterface.LevelDB,
+				KVDBConfig: levelDB.Config{
+					DBFilePath: config.StaticConfigs.ValidatorSetAppConf.ValidatorSetDB,
+				},
+				CryptoTools: cryptoTools,
+			},
+			GenesisMembers: config.StaticConfigs.ConsensusConf.Members,
+		})
+		if err != nil {
+			fmt.Println("start validator set application failed: ", err.Error())
+			return
+		}
+
+		bhtConfig.MemberSource = validatorSetApp
+	}
+
+	//the peers admitted to the secure links, the validators on chain are admitted if the validator set runs
+	var peerAdmission network.IAdmission
+	if config.StaticConfigs.PeerAdmissionConf.Enable {
+		var registry func() (keys [][]byte, err error)
+		if config.StaticConfigs.PeerAdmissionConf.AllowValidators && validatorSetApp != nil {
+			registry = validatorSetApp.ConsensusMembers
+		}
+
+		peerAdmission = network.NewAllowlist(config.StaticConfigs.PeerAdmissionConf.Allowlist, registry)
+	}
+
+	//the limits of the messages received from every peer of both networks
+	linkLimits := network.LinkLimits{
+		Workers:           config.StaticConfigs.LinkLimitsConf.Workers,
+		QueueSize:         config.StaticConfigs.LinkLimitsConf.QueueSize,
+		MessagesPerSecond: config.StaticConfigs.LinkLimitsConf.MessagesPerSecond,
+		BytesPerSecond:    config.StaticConfigs.LinkLimitsConf.BytesPerSecond,
+		DisconnectAfter:   config.StaticConfigs.LinkLimitsConf.DisconnectAfter,
+	}
+
+	//build pbft config from the same members and timers
+	pbftConfig := pbft.Config{
+		SelfSigner:                bhtConfig.SelfSigner,
+		MemberOnlineCheckInterval: bhtConfig.MemberOnlineCheckInterval,
+		ConsensusTimeout:          bhtConfig.ConsensusTimeout,
+		ConsensusInterval:         bhtConfig.ConsensusInterval,
+		CheckpointInterval:        config.StaticConfigs.ConsensusConf.CheckpointInterval,
+		StateStore:                bhtConfig.StateStore,
+		SingerGenerator:           bhtConfig.SingerGenerator,
+		HashCalc:                  bhtConfig.HashCalc,
+	}
+
+	for _, member := range bhtConfig.Members {
+		pbftConfig.Members = append(pbftConfig.Members, pbft.Member{Signer: member.Signer})
+	}
+
 	engineCfg := engineStartup.Config{}
 	engineCfg.ConsensusNetwork.ID = selfSigner.PublicKeyString()
 	engineCfg.ConsensusNetwork.ServiceAddress = config.StaticConfigs.ConsensusConf.ConsensusServiceAddress
 	engineCfg.ConsensusNetwork.ServiceProtocol = config.StaticConfigs.ConsensusConf.ConsensusServiceProtocol
 	engineCfg.ConsensusNetwork.P2PSeeds = config.StaticConfigs.ConsensusConf.ConsensusMember
-	//TODO from config file
-	engineCfg.ConsensusNetwork.Topology = fullyConnect.NewTopology()
+	engineCfg.ConsensusNetwork.NetworkID = networkID
+	engineCfg.ConsensusNetwork.LinkLimits = linkLimits
+	if config.StaticConfigs.ConsensusConf.SecureLink {
+		engineCfg.ConsensusNetwork.Signer = selfSigner
+		engineCfg.ConsensusNetwork.Admission = peerAdmission
+	}
+	engineCfg.ConsensusNetwork.Topology = networkTopology(config.StaticConfigs.ConsensusConf.ConsensusTopology)
 
 	//config consensus
 	engineCfg.ConsensusDisabled = config.StaticConfigs.ConsensusConf.ConsensusDisabled
+	engineCfg.ConsensusType = config.StaticConfigs.ConsensusConf.ConsensusType
 	engineCfg.Consensus = bhtConfig
+	switch engineCfg.ConsensusType {
+	case engineStartup.PBFT:
+		engineCfg.Consensus = pbftConfig
+
+	case engineStartup.Solo:
+		engineCfg.Consensus = solo.Config{
+			ConsensusInterval: bhtConfig.ConsensusInterval,
+		}
+	}
 
 	//load basic assets application
 	utxoCfg := basicAssets.Config{
@@ -214,6 +447,7 @@ func main() {
 			Increasable: config.SmartAssetsIncreasable,
 			Owner:       config.SmartAssetsOwner,
 		},
+		Genesis:       smartAssetsGenesis(genesis),
 		TxPoolLimit:   config.StaticConfigs.SmartAssetsAppConf.TxPoolLimit,
 		ClientTxLimit: config.StaticConfigs.SmartAssetsAppConf.ClientTxLimit,
 	}
@@ -234,6 +468,12 @@ func main() {
 	systemService.Chain.Blockchain.Signer = selfSigner
 	systemService.Chain.Blockchain.CryptoTools = cryptoTools
 	systemService.Chain.Blockchain.NewWhenGenesis = true
+	if genesis != nil {
+		systemService.Chain.Blockchain.GenesisHash = genesis.Hash()
+	}
+	systemService.Chain.Blockchain.SnapshotInterval = config.StaticConfigs.BlockChainConf.SnapshotInterval
+	systemService.Chain.Blockchain.SnapshotDir = config.StaticConfigs.BlockChainConf.SnapshotDir
+	systemService.Chain.FastSync = config.StaticConfigs.BlockChainConf.FastSync
 
 	systemService.Chain.ExternalExecutors = []chainStructure.IBlockchainExternalApplication{
 		utxoService,
@@ -241,6 +481,21 @@ func main() {
 		smartAssetsApplication,
 	}
 
+	if validatorSetApp != nil {
+		systemService.Chain.ExternalExecutors = append(systemService.Chain.ExternalExecutors, validatorSetApp)
+	}
+
+	//the synced blocks are verified by the finality proofs of hot-stuff, the members are known only if the consensus runs
+	if !engineCfg.ConsensusDisabled {
+		switch engineCfg.ConsensusType {
+		case "", engineStartup.BasicHotStuff:
+			systemService.Chain.FinalityVerifier = &hotStuff.Basic
+
+		case engineStartup.ChainedHotStuff:
+			systemService.Chain.FinalityVerifier = &hotStuff.Chained
+		}
+	}
+
 	//start load chain
 	systemService.Chain.Api.HttpJSON = config.StaticConfigs.BlockChainConf.BlockchainApiConfig
 
@@ -249,7 +504,13 @@ func main() {
 	systemService.Chain.Network.ServiceAddress = config.StaticConfigs.BlockChainConf.BlockchainServiceAddress
 	systemService.Chain.Network.ServiceProtocol = config.StaticConfigs.BlockChainConf.BlockchainServiceProtocol
 	systemService.Chain.Network.P2PSeeds = config.StaticConfigs.BlockChainConf.BlockchainServiceSeeds
-	systemService.Chain.Network.Topology = fullyConnect.NewTopology()
+	systemService.Chain.Network.NetworkID = networkID
+	systemService.Chain.Network.LinkLimits = linkLimits
+	if config.StaticConfigs.BlockChainConf.SecureLink {
+		systemService.Chain.Network.Signer = selfSigner
+		systemService.Chain.Network.Admission = peerAdmission
+	}
+	systemService.Chain.Network.Topology = networkTopology(config.StaticConfigs.BlockChainConf.BlockchainTopology)
 
 	engineCfg.Log.LogFile = config.StaticConfigs.LogConf.LogFile
 	engineCfg.Log.Level = logrus.Level(config.StaticConfigs.LogConf.LogLevel)
@@ -261,12 +522,32 @@ func main() {
 	systemService.Chain.Blockchain.StorageDriver, _ = db.NewKVDatabaseDriver(dbInterface.LevelDB, levelDB.Config{
 		DBFilePath: config.StaticConfigs.BlockChainConf.ChainDB,
 	})
-	engine.Startup(engineCfg)
 
-	system.NewBlockchainService(systemService.Chain)
+	//offline commands work on the local data without starting the node
+	if cli.Parameters.Command != "" {
+		runOfflineCommand(systemService.Chain)
+		return
+	}
 
-	for {
-		time.Sleep(time.Second)
+	err = engine.Startup(engineCfg)
+	if err != nil {
+		log.Log.Error("engine startup failed: ", err.Error())
+		return
 	}
 
+	system.NewBlockchainService(systemService.Chain)
+
+	//wait for stop signal
+	stopSignal := make(chan os.Signal, 1)
+	signal.Notify(stopSignal, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stopSignal
+	log.Log.Println("got signal ", sig, ", shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = engine.Shutdown(ctx)
+	if err != nil {
+		log.Log.Error("engine shutdown with an error: ", err.Error())
+	}
 }
@@ -24,17 +24,19 @@ import (
 	cliV2 "github.com/urfave/cli/v2"
 )
 
-func SetAction(app *cliV2.App) {
-	app.Action = func(c *cliV2.Context) error {
-		//config file
-		cfgFile := c.String(cliFlags.Config)
-		if "" == cfgFile {
-			return errors.New("must set config file")
-		}
+func setGlobalParameters(c *cliV2.Context) error {
+	//config file
+	cfgFile := c.String(cliFlags.Config)
+	if "" == cfgFile {
+		return errors.New("must set config file")
+	}
 
-		Parameters.ConfigFile = cfgFile
-		Parameters.Password = c.String(cliFlags.Password)
+	Parameters.ConfigFile = cfgFile
+	Parameters.Password = c.String(cliFlags.Password)
 
-		return nil
-	}
+	return nil
+}
+
+func SetAction(app *cliV2.App) {
+	app.Action = setGlobalParameters
 }
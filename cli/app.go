@@ -49,6 +49,7 @@ func Run() (app *cliV2.App) {
 
 	cliFlags.SetFlags(app)
 	SetAction(app)
+	SetCommands(app)
 
 	//run
 	err := app.Run(os.Args)
@@ -40,3 +40,40 @@ func newPasswordFlag() cliV2.Flag {
 		Required: false,
 	})
 }
+
+func NewHeightFlag(usage string) cliV2.Flag {
+	return &(cliV2.Uint64Flag{
+		Name:     Height,
+		Usage:    usage,
+		Hidden:   false,
+		Required: true,
+	})
+}
+
+func NewFileFlag(usage string) cliV2.Flag {
+	return &(cliV2.StringFlag{
+		Name:     File,
+		Usage:    usage,
+		Aliases:  []string{"f"},
+		Hidden:   false,
+		Required: true,
+	})
+}
+
+func NewFromFlag(usage string) cliV2.Flag {
+	return &(cliV2.Uint64Flag{
+		Name:     From,
+		Usage:    usage,
+		Hidden:   false,
+		Required: false,
+	})
+}
+
+func NewToFlag(usage string) cliV2.Flag {
+	return &(cliV2.Uint64Flag{
+		Name:     To,
+		Usage:    usage,
+		Hidden:   false,
+		Required: false,
+	})
+}
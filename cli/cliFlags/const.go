@@ -20,4 +20,8 @@ package cliFlags
 const (
 	Config   = "config"
 	Password = "password"
+	Height   = "height"
+	File     = "file"
+	From     = "from"
+	To       = "to"
 )
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cli
+
+import (
+	"github.com/SealSC/SealABC/cli/cliFlags"
+	cliV2 "github.com/urfave/cli/v2"
+	"math"
+)
+
+//the offline commands, they run on the local data with the node stopped
+const (
+	RevertCommand = "revert"
+	ExportCommand = "export"
+	ImportCommand = "import"
+)
+
+func newRevertCommand() *cliV2.Command {
+	return &cliV2.Command{
+		Name:      RevertCommand,
+		Usage:     "revert the chain and the application states to a height",
+		UsageText: "SealABC -c config.json revert --height <height>",
+		Flags: []cliV2.Flag{
+			cliFlags.NewHeightFlag("the height to revert to"),
+		},
+		Action: func(c *cliV2.Context) error {
+			Parameters.Command = RevertCommand
+			Parameters.Height = c.Uint64(cliFlags.Height)
+			return setGlobalParameters(c)
+		},
+	}
+}
+
+func newExportCommand() *cliV2.Command {
+	return &cliV2.Command{
+		Name:      ExportCommand,
+		Usage:     "export the blocks into a compressed archive file",
+		UsageText: "SealABC -c config.json export --file <archive> [--from <height>] [--to <height>]",
+		Flags: []cliV2.Flag{
+			cliFlags.NewFileFlag("the archive file to write"),
+			cliFlags.NewFromFlag("the first height to export"),
+			cliFlags.NewToFlag("the last height to export, the last block if not set"),
+		},
+		Action: func(c *cliV2.Context) error {
+			Parameters.Command = ExportCommand
+			Parameters.File = c.String(cliFlags.File)
+			Parameters.From = c.Uint64(cliFlags.From)
+			Parameters.To = math.MaxUint64
+			if c.IsSet(cliFlags.To) {
+				Parameters.To = c.Uint64(cliFlags.To)
+			}
+			return setGlobalParameters(c)
+		},
+	}
+}
+
+func newImportCommand() *cliV2.Command {
+	return &cliV2.Command{
+		Name:      ImportCommand,
+		Usage:     "verify and import the blocks of an archive file above the local height",
+		UsageText: "SealABC -c config.json import --file <archive>",
+		Flags: []cliV2.Flag{
+			cliFlags.NewFileFlag("the archive file to read"),
+		},
+		Action: func(c *cliV2.Context) error {
+			Parameters.Command = ImportCommand
+			Parameters.File = c.String(cliFlags.File)
+			return setGlobalParameters(c)
+		},
+	}
+}
+
+func SetCommands(app *cliV2.App) {
+	app.Commands = []*cliV2.Command{
+		newRevertCommand(),
+		newExportCommand(),
+		newImportCommand(),
+	}
+}
@@ -20,9 +20,16 @@ package cli
 type parameters struct {
 	ConfigFile string
 	Password   string
+
+	//the offline command to run instead of the node, and its arguments
+	Command string
+	Height  uint64
+	File    string
+	From    uint64
+	To      uint64
 }
 
 var Parameters = parameters{
-	"",
-	"",
+	ConfigFile: "",
+	Password:   "",
 }
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+const progressBarWidth = 40
+
+//draw the progress bar in place, a new line is printed when it is done
+func ShowProgress(done uint64, total uint64) {
+	if total == 0 {
+		return
+	}
+
+	if done > total {
+		done = total
+	}
+
+	filled := int(done * progressBarWidth / total)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Printf("\r[%s] %3d%% %d/%d", bar, done*100/total, done, total)
+
+	if done == total {
+		fmt.Println()
+	}
+}
@@ -27,8 +27,9 @@ import (
 	"github.com/SealSC/SealABC/network/http"
 )
 
-//TODO add hotstuff/pbft in ConsensusConf
 type Config struct {
+	GenesisFile string `json:"genesis_file"`
+
 	ConsensusConf struct {
 		ConsensusDisabled         bool     `json:"consensus_disabled"`
 		ConsensusServiceProtocol  string   `json:"consensus_service_protocol"`
@@ -37,8 +38,17 @@ type Config struct {
 		Members                   []string `json:"members"`
 		MemberOnlineCheckInterval uint64   `json:"online_check_interval"`
 		ConsensusTimeOut          uint64   `json:"consensus_timeout"`
+		MaxConsensusTimeout       uint64   `json:"max_consensus_timeout"`
+		DisableLeaderReputation   bool     `json:"disable_leader_reputation"`
 		ConsensusInterval         uint64   `json:"consensus_Interval"`
 		ConsensusTopology         string   `json:"consensus_topology"`
+		ConsensusDB               string   `json:"consensus_db"`
+		ConsensusType             string   `json:"consensus_type"`
+		CheckpointInterval        uint64   `json:"checkpoint_interval"`
+		QCType                    string   `json:"qc_type"`
+		AggregateMembers          []string `json:"aggregate_members"`
+		AggregateProofs           []string `json:"aggregate_proofs"`
+		SecureLink                bool     `json:"secure_link"`
 	} `json:"consensus_conf"`
 	BlockChainConf struct {
 		BlockchainServiceAddress  string      `json:"blockchain_service_address"`
@@ -46,7 +56,29 @@ type Config struct {
 		BlockchainApiConfig       http.Config `json:"blockchain_api_config"`
 		ChainDB                   string      `json:"chain_db"`
 		BlockchainServiceProtocol string      `json:"blockchain_service_protocol"`
+		SnapshotInterval          uint64      `json:"snapshot_interval"`
+		SnapshotDir               string      `json:"snapshot_dir"`
+		FastSync                  bool        `json:"fast_sync"`
+		SecureLink                bool        `json:"secure_link"`
+		BlockchainTopology        string      `json:"blockchain_topology"`
 	} `json:"block_chain_conf"`
+	GossipConf struct {
+		MaxPeers int `json:"max_peers"`
+		Fanout   int `json:"fanout"`
+		MaxHops  int `json:"max_hops"`
+	} `json:"gossip_conf"`
+	PeerAdmissionConf struct {
+		Enable          bool     `json:"enable"`
+		Allowlist       []string `json:"allowlist"`
+		AllowValidators bool     `json:"allow_validators"`
+	} `json:"peer_admission_conf"`
+	LinkLimitsConf struct {
+		Workers           int `json:"workers"`
+		QueueSize         int `json:"queue_size"`
+		MessagesPerSecond int `json:"messages_per_second"`
+		BytesPerSecond    int `json:"bytes_per_second"`
+		DisconnectAfter   int `json:"disconnect_after"`
+	} `json:"link_limits_conf"`
 	DebugConf struct {
 		PProfPort string `json:"pprof_port"`
 	} `json:"debug_conf"`
@@ -55,7 +87,8 @@ type Config struct {
 		LogLevel uint32 `json:"log_level"`
 	} `json:"log_conf"`
 	WalletConf struct {
-		WalletPath string `json:"wallet_path"`
+		WalletPath          string `json:"wallet_path"`
+		AggregateWalletPath string `json:"aggregate_wallet_path"`
 	} `json:"wallet_conf"`
 	UTXOAppConf struct {
 		UTXOAppEnable bool   `json:"utxo_app_enable"`
@@ -65,6 +98,10 @@ type Config struct {
 		MemoAppEnable bool   `json:"memo_app_enable"`
 		MemoDB        string `json:"memo_db"`
 	} `json:"memo_app_conf"`
+	ValidatorSetAppConf struct {
+		ValidatorSetAppEnable bool   `json:"validator_set_app_enable"`
+		ValidatorSetDB        string `json:"validator_set_db"`
+	} `json:"validator_set_app_conf"`
 	SmartAssetsAppConf struct {
 		SmartAssetsEnable bool   `json:"smart_assets_enable"`
 		SmartAssetsDB     string `json:"smart_assets_db"`
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/SealSC/SealABC/crypto/hashes"
+)
+
+type GenesisValidator struct {
+	Key            string `json:"key"`
+	AggregateKey   string `json:"aggregate_key"`
+	AggregateProof string `json:"aggregate_proof"`
+}
+
+type GenesisConsensus struct {
+	ConsensusType             string `json:"consensus_type"`
+	QCType                    string `json:"qc_type"`
+	MemberOnlineCheckInterval uint64 `json:"online_check_interval"`
+	ConsensusTimeOut          uint64 `json:"consensus_timeout"`
+	MaxConsensusTimeout       uint64 `json:"max_consensus_timeout"`
+	DisableLeaderReputation   bool   `json:"disable_leader_reputation"`
+	ConsensusInterval         uint64 `json:"consensus_interval"`
+	CheckpointInterval        uint64 `json:"checkpoint_interval"`
+}
+
+type GenesisBalance struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+type GenesisContract struct {
+	Address string `json:"address"`
+	Code    string `json:"code"`
+}
+
+type GenesisSmartAssets struct {
+	Name        string            `json:"name"`
+	Symbol      string            `json:"symbol"`
+	Supply      string            `json:"supply"`
+	Precision   uint8             `json:"precision"`
+	Increasable bool              `json:"increasable"`
+	Owner       string            `json:"owner"`
+	Balances    []GenesisBalance  `json:"balances"`
+	Contracts   []GenesisContract `json:"contracts"`
+}
+
+//the application settings must be same on every node, because they change the states and the blocks
+type GenesisApplications struct {
+	ValidatorSetAppEnable bool   `json:"validator_set_app_enable"`
+	SnapshotInterval      uint64 `json:"snapshot_interval"`
+}
+
+//the genesis file defines everything the nodes of a chain must agree on before the first block.
+//its hash is committed by the block 0 as the previous block, and the nodes of different genesis refuse to peer.
+//the hash is over the bytes of the file without the byte order mark by the hash calculator of the chain,
+//so every node must use the same file.
+type Genesis struct {
+	ChainID      string              `json:"chain_id"`
+	Validators   []GenesisValidator  `json:"validators"`
+	Consensus    GenesisConsensus    `json:"consensus"`
+	SmartAssets  GenesisSmartAssets  `json:"smart_assets"`
+	Applications GenesisApplications `json:"applications"`
+
+	hash []byte
+}
+
+func LoadGenesis(file string, hashCalc hashes.IHashCalculator) (g *Genesis, err error) {
+	if hashCalc == nil {
+		err = errors.New("no hash calculator for genesis")
+		return
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	g = &Genesis{}
+	err = json.Unmarshal(data, g)
+	if err != nil {
+		return
+	}
+
+	if g.ChainID == "" {
+		err = errors.New("no chain id in genesis")
+		return
+	}
+
+	if len(g.Validators) == 0 {
+		err = errors.New("no validators in genesis")
+		return
+	}
+
+	g.hash = hashCalc.Sum(data)
+	return
+}
+
+func (g *Genesis) Hash() []byte {
+	return g.hash
+}
+
+//the genesis overrides the settings of the node config
+func (g *Genesis) ApplyTo(c *Config) {
+	c.ConsensusConf.Members = nil
+	c.ConsensusConf.AggregateMembers = nil
+	c.ConsensusConf.AggregateProofs = nil
+	for _, v := range g.Validators {
+		c.ConsensusConf.Members = append(c.ConsensusConf.Members, v.Key)
+		c.ConsensusConf.AggregateMembers = append(c.ConsensusConf.AggregateMembers, v.AggregateKey)
+		c.ConsensusConf.AggregateProofs = append(c.ConsensusConf.AggregateProofs, v.AggregateProof)
+	}
+
+	c.ConsensusConf.ConsensusType = g.Consensus.ConsensusType
+	c.ConsensusConf.QCType = g.Consensus.QCType
+	c.ConsensusConf.MemberOnlineCheckInterval = g.Consensus.MemberOnlineCheckInterval
+	c.ConsensusConf.ConsensusTimeOut = g.Consensus.ConsensusTimeOut
+	c.ConsensusConf.MaxConsensusTimeout = g.Consensus.MaxConsensusTimeout
+	c.ConsensusConf.DisableLeaderReputation = g.Consensus.DisableLeaderReputation
+	c.ConsensusConf.ConsensusInterval = g.Consensus.ConsensusInterval
+	c.ConsensusConf.CheckpointInterval = g.Consensus.CheckpointInterval
+
+	c.ValidatorSetAppConf.ValidatorSetAppEnable = g.Applications.ValidatorSetAppEnable
+	c.BlockChainConf.SnapshotInterval = g.Applications.SnapshotInterval
+
+	SmartAssetsName = g.SmartAssets.Name
+	SmartAssetsSymbol = g.SmartAssets.Symbol
+	SmartAssetsSupply = g.SmartAssets.Supply
+	SmartAssetsPrecision = strconv.Itoa(int(g.SmartAssets.Precision))
+	SmartAssetsIncreasable = g.SmartAssets.Increasable
+	SmartAssetsOwner = g.SmartAssets.Owner
+}
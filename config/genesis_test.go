@@ -0,0 +1,79 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/crypto/hashes/sm3"
+)
+
+const testGenesis = `{"chain_id": "test", "validators": [{"key": "01"}]}`
+
+func writeTestGenesis(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "genesis.json")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestGenesisHash(t *testing.T) {
+	sha3.Load()
+	sm3.Load()
+
+	g, err := LoadGenesis(writeTestGenesis(t, testGenesis), sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(g.Hash(), sha3.Sha256.Sum([]byte(testGenesis))) {
+		t.Fatal("genesis hash is not the hash of the file")
+	}
+
+	bom, _ := LoadGenesis(writeTestGenesis(t, "\xef\xbb\xbf"+testGenesis), sha3.Sha256)
+	if bom == nil || !bytes.Equal(g.Hash(), bom.Hash()) {
+		t.Fatal("the byte order mark changed the genesis hash")
+	}
+
+	sm3Genesis, err := LoadGenesis(writeTestGenesis(t, testGenesis), sm3.Sm3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(g.Hash(), sm3Genesis.Hash()) {
+		t.Fatal("genesis hash is not by the hash calculator")
+	}
+
+	//a field unknown to the node still changes the hash
+	extended, _ := LoadGenesis(writeTestGenesis(t, `{"chain_id": "test", "validators": [{"key": "01"}], "extra": 1}`), sha3.Sha256)
+	if extended == nil || bytes.Equal(g.Hash(), extended.Hash()) {
+		t.Fatal("an unknown field not committed by the genesis hash")
+	}
+
+	if _, err = LoadGenesis(writeTestGenesis(t, `{"validators": [{"key": "01"}]}`), sha3.Sha256); err == nil {
+		t.Fatal("genesis without chain id loaded")
+	}
+
+	if _, err = LoadGenesis(writeTestGenesis(t, testGenesis), nil); err == nil {
+		t.Fatal("genesis loaded without hash calculator")
+	}
+}
@@ -31,6 +31,13 @@ func (d *driver) Start(cfg interface{}) (err error) {
 	return d.service.Start(cfg)
 }
 
+func (d *driver) Stop() (err error) {
+	if d.service == nil {
+		return
+	}
+	return d.service.Stop()
+}
+
 func (d *driver) messageProcessor(msg network.Message) (reply *network.Message) {
 	replyMsg := d.service.Feed(msg.Message)
 	if nil != replyMsg {
@@ -43,5 +50,6 @@ func (d *driver) messageProcessor(msg network.Message) (reply *network.Message)
 
 func (d *driver) consensusRegister(consensusService IConsensusService, networkService network.IService) {
 	d.service = consensusService
+	network.SetMessagePriority(d.service.GetMessageFamily(), "", network.PRIORITY_HIGH)
 	networkService.RegisterMessageProcessor(d.service.GetMessageFamily(), d.messageProcessor)
 }
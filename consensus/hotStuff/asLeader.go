@@ -54,8 +54,18 @@ func (b *basicService) pickHighQC() (highQC QC) {
 	}
 
 	if highView == 0 {
+		//a member votes only once in the genesis QC
+		voted := map[string]bool{}
 		for _, newView := range b.newViews {
-			blankNewViewQC.Votes = append(blankNewViewQC.Votes, newView.Justify.Votes...)
+			for _, v := range newView.Justify.Votes {
+				voter := string(v.SignerPublicKey)
+				if voted[voter] {
+					continue
+				}
+
+				voted[voter] = true
+				blankNewViewQC.Votes = append(blankNewViewQC.Votes, v)
+			}
 		}
 		highQC.Votes = blankNewViewQC.Votes
 	}
@@ -115,21 +125,30 @@ func (b *basicService) gotVote(consensusData SignedConsensusData) (reply *messag
 		return
 	}
 
+	//same as the replicas, the QC of prepare votes is the prepare QC and the QC of pre-commit votes is locked
 	switch b.currentPhase {
-	case consensusPhases.PreCommit:
+	case consensusPhases.Prepare:
 		b.prepareQC = &votedQC
 
-	case consensusPhases.Commit:
+	case consensusPhases.PreCommit:
 		b.lockedQC = &votedQC
 	}
 
+	//the next phase message carries the leader's own vote
+	if b.saveSafetyState() != nil {
+		return
+	}
+
 	b.currentPhase = nextPhase
 	b.votedMessage = map[string]SignedConsensusData{}
 	b.broadCastMessage(nextPhaseMsg)
 
 	if nextPhase == consensusPhases.Decide {
+		b.proposalData = nil
+		b.viewDecided()
 		if b.externalProcessor != nil {
-			b.externalProcessor.EventProcessor(consensus.Event.Success, consensusData.Payload.CustomerData)
+			b.externalProcessor.EventProcessor(consensus.Event.Success, consensusData.Payload.CustomerData,
+				buildFinalityProof(votedQC, nil))
 		}
 
 		b.currentPhase = consensusPhases.NewView
@@ -160,13 +179,17 @@ func (b *basicService) gotNewView(consensusData SignedConsensusData) (_ *message
 func (b *basicService) startLeadingConsensus() {
 	time.Sleep(b.config.ConsensusInterval)
 
+	if b.isStopped() {
+		return
+	}
+
 	if !b.hasEnoughVotes(len(b.newViews)) {
 		return
 	}
 
 	highQC := b.pickHighQC()
 
-	prepareMsg, err := b.buildPrepareMessage(highQC)
+	prepareMsg, payload, err := b.buildPrepareMessage(highQC)
 	if err != nil {
 		log.Log.Error("build prepare message failed.")
 		b.newViews = map[string]SignedConsensusData{}
@@ -175,6 +198,8 @@ func (b *basicService) startLeadingConsensus() {
 
 	b.newViews = map[string]SignedConsensusData{}
 	b.currentPhase = consensusPhases.Prepare
+	b.proposalData = payload.CustomerData
+	b.proposalJustifyView = highQC.ViewNumber
 	b.broadCastMessage(prepareMsg)
 }
 
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/crypto"
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/crypto/signers/ecdsa/secp256k1"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/network"
+	"testing"
+	"time"
+)
+
+type testEnvelope struct {
+	from string
+	to   string
+	msg  message.Message
+}
+
+//a network delivers nothing by itself, the messages are pumped to the services by the test
+type testNetwork struct {
+	self  network.Node
+	nodes []network.Node
+	hub   chan testEnvelope
+}
+
+func (n *testNetwork) Self() (node network.Node)                                  { return n.self }
+func (n *testNetwork) Create(cfg network.Config) (err error)                      { return }
+func (n *testNetwork) Stop()                                                      {}
+func (n *testNetwork) ConnectTo(network.Node) (err error)                         { return }
+func (n *testNetwork) Join(seeds []network.Node, cfg *network.Config) (err error) { return }
+func (n *testNetwork) Leave()                                                     {}
+func (n *testNetwork) StaticInformation() network.StaticInformation {
+	return network.StaticInformation{}
+}
+
+func (n *testNetwork) RegisterMessageProcessor(msgFamily string, processor network.MessageProcessor) {
+}
+
+func (n *testNetwork) GetAllLinkedNode() (nodes []network.Node) {
+	for _, node := range n.nodes {
+		if node.ID != n.self.ID {
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+func (n *testNetwork) SendTo(node network.Node, msg message.Message) (_ int, err error) {
+	n.hub <- testEnvelope{from: n.self.ID, to: node.ID, msg: msg}
+	return
+}
+
+func (n *testNetwork) Broadcast(msg message.Message) (err error) {
+	n.hub <- testEnvelope{from: n.self.ID, msg: msg}
+	return
+}
+
+type testCustomerData []byte
+
+func (d testCustomerData) Verify() (passed bool, err error) { return true, nil }
+func (d testCustomerData) Bytes() ([]byte, error)           { return d, nil }
+
+type testProcessor struct{}
+
+func (p *testProcessor) EventProcessor(event enum.Element, customerData []byte, finalityProof []byte) {
+}
+
+func (p *testProcessor) NewDataBasedOnConsensus(pending [][]byte) (consensus.ICustomerData, error) {
+	return testCustomerData("next data"), nil
+}
+
+func (p *testProcessor) CustomerDataToConsensus() (consensus.ICustomerData, error) {
+	return testCustomerData("data"), nil
+}
+
+func (p *testProcessor) CustomerDataFromConsensus(data []byte) (consensus.ICustomerData, error) {
+	return testCustomerData(data), nil
+}
+
+type testCluster struct {
+	services []*basicService
+	hub      chan testEnvelope
+}
+
+func loadTestEnvironment() {
+	log.SetUpLogger(log.Config{})
+	structSerializer.Load()
+	crypto.Load()
+	enum.Build(&consensus.States, 0, "")
+	enum.Build(&consensus.Event, 0, "")
+	enum.Build(&consensusPhases, 0, "")
+}
+
+func newTestCluster(t *testing.T, size int) (c *testCluster) {
+	loadTestEnvironment()
+
+	c = &testCluster{hub: make(chan testEnvelope, 1024)}
+
+	var members []Member
+	var nodes []network.Node
+	for i := 0; i < size; i++ {
+		signer, err := secp256k1.SignerGenerator.NewSigner(nil)
+		if err != nil {
+			t.Fatal("create signer failed: ", err)
+		}
+
+		members = append(members, Member{Signer: signer})
+		nodes = append(nodes, network.Node{ID: signer.PublicKeyString()})
+	}
+
+	for i := 0; i < size; i++ {
+		cfg := Config{
+			SelfSigner:                members[i].Signer,
+			Members:                   append([]Member{}, members...),
+			MemberOnlineCheckInterval: time.Hour,
+			ConsensusTimeout:          time.Hour,
+			SingerGenerator:           secp256k1.SignerGenerator,
+			HashCalc:                  sha3.Sha256,
+		}
+
+		svc := &basicService{}
+		svc.config = cfg
+		svc.Load(&testNetwork{self: nodes[i], nodes: nodes, hub: c.hub}, &testProcessor{})
+		svc.currentState = consensus.States.Running
+		svc.currentPhase = consensusPhases.NewView
+		svc.stopSignal = make(chan struct{})
+		svc.refreshMembers()
+
+		c.services = append(c.services, svc)
+	}
+
+	return
+}
+
+func (c *testCluster) leaderOfView(view uint64) *basicService {
+	for _, svc := range c.services {
+		if svc.isViewLeader(view, svc.config.SelfSigner.PublicKeyBytes()) {
+			return svc
+		}
+	}
+	return nil
+}
+
+func (c *testCluster) locked(svc *basicService, f func() bool) bool {
+	svc.phaseLock.Lock()
+	defer svc.phaseLock.Unlock()
+	return f()
+}
+
+//deliver the messages sent by the services until the condition is met
+func (c *testCluster) pump(t *testing.T, until func() bool) {
+	timeout := time.After(10 * time.Second)
+	for !until() {
+		select {
+		case env := <-c.hub:
+			for _, svc := range c.services {
+				self := svc.config.SelfSigner.PublicKeyString()
+				if self == env.from || (env.to != "" && env.to != self) {
+					continue
+				}
+				svc.Feed(env.msg)
+			}
+
+		case <-timeout:
+			t.Fatal("condition not met before timeout")
+		}
+	}
+}
+
+//the leader keeps the same QCs as the replicas, so the new view it sends after a view change is accepted.
+func TestLeaderThroughViewChange(t *testing.T) {
+	c := newTestCluster(t, 4)
+
+	leader := c.leaderOfView(0)
+	for _, svc := range c.services {
+		if svc != leader {
+			svc.newRound()
+		}
+	}
+
+	c.pump(t, func() bool {
+		return c.locked(leader, func() bool { return leader.hasEnoughVotes(len(leader.newViews)) })
+	})
+	leader.startLeadingConsensus()
+
+	c.pump(t, func() bool {
+		return c.locked(leader, func() bool { return leader.lockedQC != nil })
+	})
+
+	if leader.prepareQC == nil || leader.prepareQC.Phase != consensusPhases.Prepare.String() {
+		t.Fatal("prepare QC of the leader is not built from the prepare votes")
+	}
+
+	if leader.lockedQC.Phase != consensusPhases.PreCommit.String() {
+		t.Fatal("locked QC of the leader is not built from the pre-commit votes")
+	}
+
+	//the new leader must not start leading during the test
+	for _, svc := range c.services {
+		svc.config.ConsensusInterval = time.Hour
+	}
+
+	for _, svc := range c.services {
+		svc.viewChange()
+	}
+
+	newLeader := c.leaderOfView(1)
+	if newLeader == leader {
+		t.Fatal("leader is not rotated by the view change")
+	}
+
+	leaderKey := leader.config.SelfSigner.PublicKeyString()
+	c.pump(t, func() bool {
+		return c.locked(newLeader, func() bool {
+			newView, exists := newLeader.newViews[leaderKey]
+			return exists && newView.ViewNumber == 1
+		})
+	})
+
+	newView := newLeader.newViews[leaderKey]
+	if newView.Justify.ViewNumber != 0 || newView.Justify.Phase != consensusPhases.Prepare.String() {
+		t.Fatal("new view of the old leader is not justified by its prepare QC")
+	}
+}
@@ -46,6 +46,7 @@ func (b *basicService) gotPrepare(consensusData SignedConsensusData) (reply *mes
 	}
 
 	if b.currentView < consensusData.ViewNumber {
+		b.cancelProposal()
 		b.currentView = consensusData.ViewNumber
 		log.Log.Warn("local view is lower then network view, set view to network view")
 	}
@@ -57,7 +58,13 @@ func (b *basicService) gotPrepare(consensusData SignedConsensusData) (reply *mes
 	}
 
 	b.currentPhase = consensusPhases.Prepare
-	b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+	b.proposalData = consensusData.Payload.CustomerData
+	b.proposalJustifyView = consensusData.Justify.ViewNumber
+	b.viewChangeTrigger.Reset(b.viewTimeout())
+
+	if b.saveSafetyState() != nil {
+		return
+	}
 	b.sendMessageToLeader(voteMsg)
 	return
 }
@@ -91,8 +98,11 @@ func (b *basicService) gotCommonPhaseMessage(consensusData SignedConsensusData)
 	b.processCommonPhaseMessage(consensusData.ConsensusData)
 
 	if b.currentPhase == consensusPhases.Decide {
+		b.proposalData = nil
+		b.viewDecided()
 		if b.externalProcessor != nil {
-			b.externalProcessor.EventProcessor(consensus.Event.Success, consensusData.Justify.Payload.CustomerData)
+			b.externalProcessor.EventProcessor(consensus.Event.Success, consensusData.Justify.Payload.CustomerData,
+				buildFinalityProof(consensusData.Justify, nil))
 		}
 
 		b.currentPhase = consensusPhases.NewView
@@ -110,10 +120,13 @@ func (b *basicService) gotCommonPhaseMessage(consensusData SignedConsensusData)
 		log.Log.Error("build vote message failed")
 		return
 	}
-	b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+	b.viewChangeTrigger.Reset(b.viewTimeout())
 
 	//log.Log.Println("build vote message in phase ", consensusData.Phase, " over")
 
+	if b.saveSafetyState() != nil {
+		return
+	}
 	b.sendMessageToLeader(voteMsg)
 	return
 }
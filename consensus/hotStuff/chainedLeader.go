@@ -0,0 +1,183 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/metadata/seal"
+	"time"
+)
+
+func (c *chainedService) collect(pool map[uint64]map[string]SignedConsensusData, consensusData SignedConsensusData) (collected map[string]SignedConsensusData) {
+	collected, exists := pool[consensusData.ViewNumber]
+	if !exists {
+		collected = map[string]SignedConsensusData{}
+		pool[consensusData.ViewNumber] = collected
+	}
+
+	collected[hex.EncodeToString(consensusData.Seal.SignerPublicKey)] = consensusData
+	return
+}
+
+func (c *chainedService) gotVote(consensusData SignedConsensusData) (_ *message.Message) {
+	if consensusData.Phase != chainedPhases.Generic.String() {
+		return
+	}
+
+	//votes for the node of view N are collected by the leader of view N+1
+	nextView := consensusData.ViewNumber + 1
+	if nextView < c.currentView || c.proposedView >= nextView {
+		return
+	}
+
+	if !c.isSelf(c.leaderOfView(nextView)) {
+		return
+	}
+
+	votes := c.collect(c.votes, consensusData)
+
+	//votes must be on the same node
+	votedHash := c.nodeHash(consensusData.Payload)
+	var seals []seal.Entity
+	for _, v := range votes {
+		if bytes.Equal(c.nodeHash(v.Payload), votedHash) {
+			seals = append(seals, v.Seal)
+		}
+	}
+
+	//the count of enough votes excludes the leader self
+	if !c.hasEnoughVotes(len(seals) - 1) {
+		return
+	}
+
+	newQC := QC{}
+	newQC.Phase = chainedPhases.Generic.String()
+	newQC.ViewNumber = consensusData.ViewNumber
+	newQC.Payload = consensusData.Payload
+	newQC.Votes = seals
+
+	c.processQC(newQC)
+	c.advanceView(nextView)
+	c.propose(nextView)
+	return
+}
+
+func (c *chainedService) gotNewView(consensusData SignedConsensusData) (_ *message.Message) {
+	viewNumber := consensusData.ViewNumber
+	if viewNumber < c.currentView || c.proposedView >= viewNumber {
+		return
+	}
+
+	if !c.isSelf(c.leaderOfView(viewNumber)) {
+		return
+	}
+
+	newViews := c.collect(c.newViews, consensusData)
+	if !c.hasEnoughVotes(len(newViews) - 1) {
+		return
+	}
+
+	highQC := c.genericQC
+	for _, nv := range newViews {
+		if nv.Justify.ViewNumber > highQC.ViewNumber {
+			highQC = nv.Justify
+		}
+	}
+
+	c.processQC(highQC)
+	c.advanceView(viewNumber)
+	c.propose(viewNumber)
+	return
+}
+
+func (c *chainedService) propose(viewNumber uint64) {
+	if c.proposedView >= viewNumber {
+		return
+	}
+	c.proposedView = viewNumber
+
+	//a proposal extends an unknown node carries no customer data because the data it's built on is unknown
+	parent := c.getQCNode(c.genericQC)
+	if parent == nil && !c.isGenesisQC(c.genericQC) {
+		c.broadcastProposal(viewNumber, false)
+		return
+	}
+
+	go func() {
+		time.Sleep(c.config.ConsensusInterval)
+
+		c.stateLock.Lock()
+		defer c.stateLock.Unlock()
+
+		if c.isStopped() || c.currentView != viewNumber {
+			return
+		}
+
+		c.broadcastProposal(viewNumber, true)
+	}()
+}
+
+func (c *chainedService) broadcastProposal(viewNumber uint64, withCustomerData bool) {
+	payload := ConsensusPayload{}
+	payload.Parent = c.nodeHash(c.genericQC.Payload)
+
+	if withCustomerData {
+		customerData, err := c.newCustomerData()
+		if err != nil {
+			log.Log.Error("get customer data failed.")
+			return
+		}
+
+		payload.CustomerData, err = customerData.Bytes()
+		if err != nil {
+			log.Log.Error("customer data builder returns an error: ", err)
+			return
+		}
+	}
+
+	proposal, signedData, err := c.buildProposalMessage(viewNumber, payload)
+	if err != nil {
+		log.Log.Error("build proposal message failed.")
+		return
+	}
+
+	c.broadcastMessage(proposal)
+
+	//the leader is a replica too
+	c.gotProposal(signedData)
+}
+
+//the customer data of a new node is built on the customer data not committed yet in its branch, so the nodes
+//in the pipeline carry the customer data in turn without waiting for their ancestors committed.
+func (c *chainedService) newCustomerData() (data consensus.ICustomerData, err error) {
+	pending := c.pendingCustomerData(c.getQCNode(c.genericQC))
+	if len(pending) == 0 {
+		return c.externalProcessor.CustomerDataToConsensus()
+	}
+
+	return c.externalProcessor.NewDataBasedOnConsensus(pending)
+}
+
+func (c *chainedService) registerChainedLeaderProcessor() {
+	c.consensusProcessor[chainedMessageTypes.NewView.String()] = c.gotNewView
+	c.consensusProcessor[chainedMessageTypes.Vote.String()] = c.gotVote
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"encoding/json"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+const ChainedMessageFamily = "chained-hot-stuff-consensus"
+const ChainedMessageVersion = "chained.0.0.1"
+
+type chainedMessageType struct {
+	NewView  enum.Element
+	Proposal enum.Element
+	Vote     enum.Element
+}
+
+type chainedPhase struct {
+	NewView enum.Element
+	Generic enum.Element
+}
+
+var chainedMessageTypes chainedMessageType
+var chainedPhases chainedPhase
+
+func (c *chainedService) buildSignedMessage(msgType enum.Element, data ConsensusData) (msg message.Message, signedData SignedConsensusData, err error) {
+	dataForSign := QCData{
+		Phase:      data.Phase,
+		ViewNumber: data.ViewNumber,
+		Payload:    data.Payload,
+	}
+
+	signedData.ConsensusData = data
+	signedData.Seal, err = c.buildVote(dataForSign)
+	if err != nil {
+		log.Log.Error("sign chained consensus data failed")
+		return
+	}
+
+	msgPayload, err := json.Marshal(signedData)
+	if err != nil {
+		log.Log.Error("consensus data marshal to json failed.")
+		return
+	}
+
+	msg.Family = ChainedMessageFamily
+	msg.Version = ChainedMessageVersion
+	msg.Type = msgType.String()
+	msg.Payload = msgPayload
+	return
+}
+
+func (c *chainedService) buildNewViewMessage() (msg message.Message, signedData SignedConsensusData, err error) {
+	return c.buildSignedMessage(chainedMessageTypes.NewView, ConsensusData{
+		ViewNumber: c.currentView,
+		Phase:      chainedPhases.NewView.String(),
+		Justify:    c.genericQC,
+	})
+}
+
+func (c *chainedService) buildProposalMessage(viewNumber uint64, payload ConsensusPayload) (msg message.Message, signedData SignedConsensusData, err error) {
+	return c.buildSignedMessage(chainedMessageTypes.Proposal, ConsensusData{
+		ViewNumber: viewNumber,
+		Phase:      chainedPhases.Generic.String(),
+		Payload:    payload,
+		Justify:    c.genericQC,
+	})
+}
+
+func (c *chainedService) buildVoteMessage(proposal ConsensusData) (msg message.Message, signedData SignedConsensusData, err error) {
+	return c.buildSignedMessage(chainedMessageTypes.Vote, ConsensusData{
+		ViewNumber: proposal.ViewNumber,
+		Phase:      chainedPhases.Generic.String(),
+		Payload:    proposal.Payload,
+	})
+}
+
+//the message to the leader of the view will be handled locally if I am that leader
+func (c *chainedService) sendToViewLeader(viewNumber uint64, msg message.Message, signedData SignedConsensusData) {
+	leader := c.leaderOfView(viewNumber)
+	if c.isSelf(leader) {
+		if handle, exists := c.consensusProcessor[msg.Type]; exists {
+			handle(signedData)
+		}
+		return
+	}
+
+	go c.network.SendTo(leader.FromNode, msg)
+}
+
+func (c *chainedService) broadcastMessage(msg message.Message) {
+	go c.network.Broadcast(msg)
+}
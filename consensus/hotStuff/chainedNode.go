@@ -0,0 +1,199 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/log"
+)
+
+//a node of the chained hot-stuff tree, every node is justified by the QC of its parent in the happy path.
+type chainedNode struct {
+	View    uint64
+	Payload ConsensusPayload
+	Justify QC
+}
+
+func (c *chainedService) nodeHash(payload ConsensusPayload) []byte {
+	return c.payloadHash(payload)
+}
+
+func (c *chainedService) getNode(hash []byte) (node *chainedNode) {
+	return c.nodes[hex.EncodeToString(hash)]
+}
+
+func (c *chainedService) getQCNode(qc QC) (node *chainedNode) {
+	return c.getNode(c.nodeHash(qc.Payload))
+}
+
+func (c *chainedService) storeNode(node *chainedNode) {
+	c.nodes[hex.EncodeToString(c.nodeHash(node.Payload))] = node
+}
+
+func (c *chainedService) isParentOf(parent *chainedNode, child *chainedNode) bool {
+	return bytes.Equal(child.Payload.Parent, c.nodeHash(parent.Payload))
+}
+
+func (c *chainedService) genesisQC() (qc QC) {
+	qc.Phase = chainedPhases.Generic.String()
+	return
+}
+
+func (c *chainedService) isGenesisQC(qc QC) bool {
+	return qc.ViewNumber == 0 &&
+		len(qc.Votes) == 0 &&
+		len(qc.Payload.Parent) == 0 &&
+		len(qc.Payload.CustomerData) == 0
+}
+
+func (c *chainedService) verifyQC(qc QC) (passed bool) {
+	if qc.Phase != chainedPhases.Generic.String() {
+		return
+	}
+
+	if c.isGenesisQC(qc) {
+		return true
+	}
+
+	return c.isValidQC(qc)
+}
+
+//the three-chain rule: a new QC updates the generic QC, locks its grandparent and commits the great-grandparent
+//if the three nodes are linked by their parents at consecutive views.
+func (c *chainedService) processQC(qc QC) {
+	if qc.ViewNumber > c.genericQC.ViewNumber {
+		c.genericQC = qc
+	}
+
+	b2 := c.getQCNode(qc)
+	if b2 == nil {
+		return
+	}
+
+	b1 := c.getQCNode(b2.Justify)
+	if b1 == nil {
+		return
+	}
+
+	if b2.Justify.ViewNumber > c.lockedQC.ViewNumber {
+		c.lockedQC = b2.Justify
+	}
+
+	b0 := c.getQCNode(b1.Justify)
+	if b0 == nil {
+		return
+	}
+
+	if c.isParentOf(b1, b2) && c.isParentOf(b0, b1) && b2.View == b1.View+1 && b1.View == b0.View+1 {
+		c.commit(b0, qc, b2, b1)
+	}
+}
+
+//the three-chain of b2 and b1 certified by the QC is the finality proof of the committed node,
+//the proof of an ancestor committed together carries the payloads between them too.
+func (c *chainedService) commit(node *chainedNode, qc QC, b2 *chainedNode, b1 *chainedNode) {
+	if node.View <= c.committedView {
+		return
+	}
+
+	var nodesToCommit []*chainedNode
+	for n := node; n != nil && n.View > c.committedView; n = c.getNode(n.Payload.Parent) {
+		nodesToCommit = append(nodesToCommit, n)
+	}
+
+	for i := len(nodesToCommit) - 1; i >= 0; i-- {
+		n := nodesToCommit[i]
+		c.recordCommittedView(n.Justify.ViewNumber, n.View)
+		if len(n.Payload.CustomerData) == 0 {
+			continue
+		}
+
+		var ancestors []ConsensusPayload
+		for _, a := range nodesToCommit[1 : i+1] {
+			ancestors = append(ancestors, a.Payload)
+		}
+
+		log.Log.Println("chained consensus commit node @view ", n.View)
+		if c.externalProcessor != nil {
+			c.externalProcessor.EventProcessor(consensus.Event.Success, n.Payload.CustomerData, buildChainedFinalityProof(qc, b2, b1, ancestors))
+		}
+	}
+
+	c.committedView = node.View
+	c.pruneNodes(node)
+}
+
+//a view timeout doesn't fail the pending nodes because they could still be committed by a later QC,
+//but the pruned nodes not in the committed branch never will, their customer data is returned as failed.
+func (c *chainedService) pruneNodes(committed *chainedNode) {
+	committedBranch := map[string]bool{}
+	for n := committed; n != nil; n = c.getNode(n.Payload.Parent) {
+		committedBranch[hex.EncodeToString(c.nodeHash(n.Payload))] = true
+	}
+
+	for k, n := range c.nodes {
+		if n.View >= c.committedView {
+			continue
+		}
+
+		delete(c.nodes, k)
+		if committedBranch[k] || len(n.Payload.CustomerData) == 0 {
+			continue
+		}
+
+		log.Log.Warn("chained consensus node @view ", n.View, " forked out")
+		if c.externalProcessor != nil {
+			c.externalProcessor.EventProcessor(consensus.Event.Failed, n.Payload.CustomerData, nil)
+		}
+	}
+}
+
+//the customer data of the uncommitted nodes in a branch from the oldest, the customer data of a new node is built on them.
+func (c *chainedService) pendingCustomerData(node *chainedNode) (pending [][]byte) {
+	for n := node; n != nil && n.View > c.committedView; n = c.getNode(n.Payload.Parent) {
+		if len(n.Payload.CustomerData) > 0 {
+			pending = append([][]byte{n.Payload.CustomerData}, pending...)
+		}
+	}
+
+	return
+}
+
+func (c *chainedService) extendsFrom(node *chainedNode, ancestorHash []byte) bool {
+	for n := node; n != nil; n = c.getNode(n.Payload.Parent) {
+		if bytes.Equal(c.nodeHash(n.Payload), ancestorHash) {
+			return true
+		}
+
+		if n.View <= c.lockedQC.ViewNumber {
+			break
+		}
+	}
+
+	return false
+}
+
+func (c *chainedService) safeNode(node *chainedNode) bool {
+	if node.Justify.ViewNumber > c.lockedQC.ViewNumber {
+		return true
+	}
+
+	return c.extendsFrom(node, c.nodeHash(c.lockedQC.Payload))
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"bytes"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+func (c *chainedService) verifyProposal(consensusData SignedConsensusData) (passed bool) {
+	if consensusData.Phase != chainedPhases.Generic.String() {
+		return
+	}
+
+	if !c.isViewLeader(consensusData.ViewNumber, consensusData.Seal.SignerPublicKey) {
+		log.Log.Error("proposal is not from the leader @view ", consensusData.ViewNumber)
+		return
+	}
+
+	justify := consensusData.Justify
+	if justify.ViewNumber >= consensusData.ViewNumber {
+		log.Log.Error("QC view number must small than proposal view number. [ QC@",
+			justify.ViewNumber, " : proposal@", consensusData.ViewNumber, " ]")
+		return
+	}
+
+	if !bytes.Equal(consensusData.Payload.Parent, c.nodeHash(justify.Payload)) {
+		log.Log.Error("proposal is not extends from its justify")
+		return
+	}
+
+	return true
+}
+
+//the justify of new view and proposal must be a generic QC not higher than the message view, a vote carries no justify.
+func (c *chainedService) verifyJustify(msgType string, consensusData SignedConsensusData) (passed bool) {
+	justify := consensusData.Justify
+	if msgType == chainedMessageTypes.Vote.String() {
+		return len(justify.Votes) == 0 && len(justify.AggregateSignature) == 0
+	}
+
+	if justify.ViewNumber > consensusData.ViewNumber {
+		log.Log.Error("justify is not in the expected [VIEW]. [message@", consensusData.ViewNumber, " : justify@", justify.ViewNumber, "]")
+		return
+	}
+
+	return c.verifyQC(justify)
+}
+
+func (c *chainedService) verifyCustomerData(data []byte) (passed bool) {
+	if len(data) == 0 {
+		return true
+	}
+
+	customerData, err := c.externalProcessor.CustomerDataFromConsensus(data)
+	if err != nil {
+		log.Log.Error("get customer data interface from message failed.")
+		return
+	}
+
+	passed, err = customerData.Verify()
+	if !passed {
+		log.Log.Error("customer data verify failed: ", err)
+	}
+	return
+}
+
+func (c *chainedService) gotProposal(consensusData SignedConsensusData) (_ *message.Message) {
+	if !c.verifyProposal(consensusData) {
+		return
+	}
+
+	node := &chainedNode{
+		View:    consensusData.ViewNumber,
+		Payload: consensusData.Payload,
+		Justify: consensusData.Justify,
+	}
+	c.storeNode(node)
+
+	//the justify is a valid QC, so it is safe to process it before vote
+	c.processQC(node.Justify)
+
+	if node.View <= c.lastVotedView {
+		return
+	}
+
+	if !c.safeNode(node) {
+		log.Log.Warn("proposal @view ", node.View, " is not safe to vote")
+		return
+	}
+
+	if !c.verifyCustomerData(node.Payload.CustomerData) {
+		return
+	}
+
+	voteMsg, signedVote, err := c.buildVoteMessage(consensusData.ConsensusData)
+	if err != nil {
+		log.Log.Error("build vote message failed")
+		return
+	}
+
+	c.lastVotedView = node.View
+	if c.saveSafetyState() != nil {
+		return
+	}
+
+	c.advanceView(node.View + 1)
+	c.sendToViewLeader(node.View+1, voteMsg, signedVote)
+	return
+}
+
+func (c *chainedService) registerChainedReplicaProcessor() {
+	c.consensusProcessor[chainedMessageTypes.Proposal.String()] = c.gotProposal
+}
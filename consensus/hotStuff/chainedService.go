@@ -0,0 +1,363 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/network"
+	"sync"
+	"time"
+)
+
+type chainedHotStuffInformation struct {
+	Network           network.StaticInformation
+	Members           []string
+	ConsensusInterval time.Duration
+	ConsensusTimeout  time.Duration
+	CurrentView       uint64
+	CommittedView     uint64
+}
+
+//chained hot-stuff piggybacks the phases of a node on the proposals of its successors,
+//every view needs only one round trip: a proposal from the leader and the votes to the next leader.
+type chainedService struct {
+	consensusBase
+
+	currentState enum.Element
+	stateLock    sync.Mutex
+
+	currentView   uint64
+	lastVotedView uint64
+	proposedView  uint64
+	committedView uint64
+
+	genericQC QC
+	lockedQC  QC
+	nodes     map[string]*chainedNode
+
+	votes    map[uint64]map[string]SignedConsensusData
+	newViews map[uint64]map[string]SignedConsensusData
+
+	viewTimer  *time.Timer
+	stopSignal chan struct{}
+
+	consensusProcessor map[string]consensusProcessor
+	externalProcessor  consensus.ExternalProcessor
+}
+
+var Chained chainedService
+
+func (c *chainedService) isStopped() bool {
+	return c.currentState.String() == consensus.States.Stopped.String()
+}
+
+func (c *chainedService) advanceView(viewNumber uint64) {
+	if viewNumber < c.currentView {
+		return
+	}
+
+	c.currentView = viewNumber
+	c.viewTimer.Reset(c.config.ConsensusTimeout)
+
+	for v := range c.votes {
+		if v+1 < c.currentView {
+			delete(c.votes, v)
+		}
+	}
+
+	for v := range c.newViews {
+		if v < c.currentView {
+			delete(c.newViews, v)
+		}
+	}
+}
+
+func (c *chainedService) sendNewView() {
+	c.refreshMembers()
+
+	newViewMsg, signedData, err := c.buildNewViewMessage()
+	if err != nil {
+		log.Log.Error("build new view message failed.")
+		return
+	}
+
+	c.sendToViewLeader(c.currentView, newViewMsg, signedData)
+}
+
+func (c *chainedService) viewTimeout() {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.isStopped() {
+		return
+	}
+
+	c.advanceView(c.currentView + 1)
+	log.Log.Println("chained consensus timeout, change to new view ", c.currentView)
+
+	c.sendNewView()
+}
+
+func (c *chainedService) startPacemaker() {
+	log.Log.Println("start chained consensus pacemaker : ", c.config.ConsensusTimeout)
+
+	for {
+		select {
+		case <-c.viewTimer.C:
+			c.viewTimeout()
+
+		case <-c.stopSignal:
+			log.Log.Println("chained consensus pacemaker stopped")
+			return
+		}
+	}
+}
+
+func (c *chainedService) initService() {
+	onlineCheck := time.NewTimer(c.config.MemberOnlineCheckInterval)
+	for {
+		select {
+		case <-onlineCheck.C:
+		case <-c.stopSignal:
+			return
+		}
+
+		if c.isQuorumOnline() {
+			log.Log.Println("consensus quorum online now!")
+			break
+		}
+
+		onlineCheck.Reset(c.config.MemberOnlineCheckInterval)
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.isStopped() {
+		return
+	}
+
+	c.currentState = consensus.States.Running
+	go c.startPacemaker()
+
+	c.advanceView(c.currentView + 1)
+	c.sendNewView()
+}
+
+func (c *chainedService) saveSafetyState() (err error) {
+	if c.config.StateStore == nil {
+		return
+	}
+
+	genericQC := c.genericQC
+	lockedQC := c.lockedQC
+	err = c.config.StateStore.Save(chainedSafetyStateKey, SafetyState{
+		CurrentView: c.lastVotedView,
+		PrepareQC:   &genericQC,
+		LockedQC:    &lockedQC,
+
+		LeaderFailures: c.leaderFailures,
+	})
+
+	if err != nil {
+		log.Log.Error("save consensus safety state failed: ", err.Error())
+	}
+	return
+}
+
+func (c *chainedService) loadSafetyState() {
+	if c.config.StateStore == nil {
+		return
+	}
+
+	state := SafetyState{}
+	exists, err := c.config.StateStore.Load(chainedSafetyStateKey, &state)
+	if err != nil {
+		log.Log.Error("load consensus safety state failed: ", err.Error())
+		return
+	}
+
+	if !exists {
+		return
+	}
+
+	c.currentView = state.CurrentView
+	c.lastVotedView = state.CurrentView
+	if state.PrepareQC != nil {
+		c.genericQC = *state.PrepareQC
+	}
+
+	if state.LockedQC != nil {
+		c.lockedQC = *state.LockedQC
+	}
+
+	c.restoreLeaderFailures(state.LeaderFailures)
+
+	log.Log.Println("consensus safety state recovered @view ", c.currentView)
+}
+
+func (c *chainedService) Feed(msg message.Message) (reply *message.Message) {
+	if msg.Family != ChainedMessageFamily {
+		return
+	}
+
+	consensusData := SignedConsensusData{}
+	err := json.Unmarshal(msg.Payload, &consensusData)
+	if err != nil {
+		log.Log.Error("invalid consensusData")
+		return
+	}
+
+	if !c.isMemberKey(consensusData.Seal.SignerPublicKey) {
+		log.Log.Error("not a member of this consensus network")
+		return
+	}
+
+	dataForSign := QCData{
+		Phase:      consensusData.Phase,
+		ViewNumber: consensusData.ViewNumber,
+		Payload:    consensusData.Payload,
+	}
+
+	if !c.verifySignature(dataForSign, consensusData.Seal) {
+		log.Log.Error("invalid chained consensus message signature")
+		return
+	}
+
+	if !c.verifyJustify(msg.Type, consensusData) {
+		log.Log.Error("invalid justify of message ", msg.Type)
+		return
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.isStopped() {
+		return
+	}
+
+	if handle, exists := c.consensusProcessor[msg.Type]; exists {
+		reply = handle(consensusData)
+	}
+	return
+}
+
+func (c *chainedService) Start(cfg interface{}) (err error) {
+	config, ok := cfg.(Config)
+	if !ok {
+		err = errors.New("invalid config")
+		return
+	}
+
+	c.config = config
+	c.currentState = consensus.States.Init
+	c.stopSignal = make(chan struct{})
+
+	c.genericQC = c.genesisQC()
+	c.lockedQC = c.genesisQC()
+	c.storeNode(&chainedNode{
+		Justify: c.genesisQC(),
+	})
+
+	c.loadSafetyState()
+
+	go c.initService()
+	return
+}
+
+func (c *chainedService) Stop() (err error) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.isStopped() || c.stopSignal == nil {
+		return
+	}
+
+	c.currentState = consensus.States.Stopped
+	c.viewTimer.Stop()
+	close(c.stopSignal)
+
+	err = c.saveSafetyState()
+	log.Log.Println("chained consensus stopped @view ", c.currentView)
+	return
+}
+
+func (c *chainedService) RegisterExternalProcessor(processor consensus.ExternalProcessor) {
+	c.externalProcessor = processor
+}
+
+func (c *chainedService) GetMessageFamily() (family string) {
+	return ChainedMessageFamily
+}
+
+func (c *chainedService) GetExternalProcessor() (processor consensus.ExternalProcessor) {
+	return c.externalProcessor
+}
+
+func (c *chainedService) GetConsensusCustomerData(msg message.Message) (data []byte, err error) {
+	consensusData := SignedConsensusData{}
+	err = json.Unmarshal(msg.Payload, &consensusData)
+	if err != nil {
+		return
+	}
+
+	data = consensusData.Payload.CustomerData
+	return
+}
+
+func (c *chainedService) Load(networkService network.IService, processor consensus.ExternalProcessor) {
+	enum.Build(&chainedMessageTypes, 0, "chained-hot-stuff-")
+	enum.Build(&chainedPhases, 0, "")
+
+	c.viewTimer = time.NewTimer(c.config.ConsensusTimeout)
+	c.viewTimer.Stop()
+
+	c.network = networkService
+
+	c.leaderFailures = map[string]uint64{}
+	c.nodes = map[string]*chainedNode{}
+	c.votes = map[uint64]map[string]SignedConsensusData{}
+	c.newViews = map[uint64]map[string]SignedConsensusData{}
+	c.consensusProcessor = map[string]consensusProcessor{}
+
+	c.externalProcessor = processor
+
+	c.registerChainedLeaderProcessor()
+	c.registerChainedReplicaProcessor()
+}
+
+func (c *chainedService) StaticInformation() interface{} {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	info := chainedHotStuffInformation{}
+
+	info.Network = c.network.StaticInformation()
+	info.Members = c.allMembersKey()
+	info.ConsensusInterval = c.config.ConsensusInterval
+	info.ConsensusTimeout = c.config.ConsensusTimeout
+	info.CurrentView = c.currentView
+	info.CommittedView = c.committedView
+
+	return info
+}
@@ -18,6 +18,7 @@
 package hotStuff
 
 import (
+	"github.com/SealSC/SealABC/consensus"
 	"github.com/SealSC/SealABC/crypto/hashes"
 	"github.com/SealSC/SealABC/crypto/signers"
 	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
@@ -36,6 +37,12 @@ type Config struct {
 	MemberOnlineCheckInterval time.Duration
 	ConsensusTimeout          time.Duration
 
+	//the view timeout backs off exponentially on consecutive failed views, no more than this if it is not zero
+	MaxConsensusTimeout time.Duration
+
+	//the members who failed to lead recently are skipped in the leader schedule, unless it's disabled
+	DisableLeaderReputation bool
+
 	//new consensus round interval
 	ConsensusInterval time.Duration
 
@@ -45,4 +52,14 @@ type Config struct {
 	//crypto
 	SingerGenerator signers.ISignerGenerator
 	HashCalc        hashes.IHashCalculator
+
+	//the QC type, SignaturesQC if it is empty. the AggregateSigner is a BLS signer used by the AggregateQC
+	QCType          string
+	AggregateSigner signerCommon.ISigner
+
+	//safety state storage, state will not be persisted if it is nil
+	StateStore consensus.IStateStore
+
+	//on chain member list, the members above are used all the time if it is nil
+	MemberSource IMemberSource
 }
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+)
+
+//the finality proof of a decided customer data. the QC certifies the first payload, every ancestor is the parent
+//of the payload before it, and the customer data of the last one is decided.
+//basic hot-stuff decides the payload of the commit QC itself, so it has no ancestors.
+type FinalityProof struct {
+	QC        QC
+	Ancestors []ConsensusPayload
+}
+
+//a QC on a node of the chained hot-stuff doesn't prove the node is committed, a forked out node is certified too.
+//so the proof carries the three-chain committing it: the QC certifies b2, b2 is justified by the QC on its parent b1,
+//and b1 by the QC on its parent b0, at consecutive views. the ancestors follow b0 as in the FinalityProof.
+type ChainedFinalityProof struct {
+	QC        QC
+	B2        chainedNode
+	B1        chainedNode
+	Ancestors []ConsensusPayload
+}
+
+func buildFinalityProof(qc QC, ancestors []ConsensusPayload) (proof []byte) {
+	proof, _ = json.Marshal(FinalityProof{
+		QC:        qc,
+		Ancestors: ancestors,
+	})
+	return
+}
+
+//the proof is verified with the members at the height, so a proof built before the members changed still passes.
+func (b *consensusBase) verifyFinalityProof(proofBytes []byte, phase string, height uint64) (customerData []byte, err error) {
+	proof := FinalityProof{}
+	err = json.Unmarshal(proofBytes, &proof)
+	if err != nil {
+		return
+	}
+
+	if proof.QC.Phase != phase || proof.QC.isGenesis() {
+		err = errors.New("not a decide QC")
+		return
+	}
+
+	members, err := b.membersAt(height)
+	if err != nil {
+		return
+	}
+
+	err = proof.QC.Verify(members, b.config.HashCalc)
+	if err != nil {
+		return
+	}
+
+	return b.decidedCustomerData(proof.QC.Payload, proof.Ancestors)
+}
+
+func (b *consensusBase) payloadHash(payload ConsensusPayload) []byte {
+	payloadBytes, _ := structSerializer.ToMFBytes(payload)
+	return b.config.HashCalc.Sum(payloadBytes)
+}
+
+//the customer data of the last ancestor, every ancestor must be the parent of the payload before it
+func (b *consensusBase) decidedCustomerData(payload ConsensusPayload, ancestors []ConsensusPayload) (customerData []byte, err error) {
+	for _, ancestor := range ancestors {
+		if !bytes.Equal(payload.Parent, b.payloadHash(ancestor)) {
+			err = errors.New("ancestor is not linked to the QC")
+			return
+		}
+
+		payload = ancestor
+	}
+
+	customerData = payload.CustomerData
+	return
+}
+
+func buildChainedFinalityProof(qc QC, b2 *chainedNode, b1 *chainedNode, ancestors []ConsensusPayload) (proof []byte) {
+	proof, _ = json.Marshal(ChainedFinalityProof{
+		QC:        qc,
+		B2:        *b2,
+		B1:        *b1,
+		Ancestors: ancestors,
+	})
+	return
+}
+
+//the generic QC must be signed by the members and certify the node at the view of the node
+func (c *chainedService) verifyNodeQC(qc QC, node chainedNode, members []Member) (err error) {
+	if qc.Phase != chainedPhases.Generic.String() || c.isGenesisQC(qc) {
+		return errors.New("not a generic QC")
+	}
+
+	err = qc.Verify(members, c.config.HashCalc)
+	if err != nil {
+		return
+	}
+
+	if qc.ViewNumber != node.View || !bytes.Equal(c.nodeHash(qc.Payload), c.nodeHash(node.Payload)) {
+		return errors.New("QC doesn't certify the node")
+	}
+	return
+}
+
+//the three-chain is checked as the commit rule of processQC, all the QCs are verified with the members at the height.
+func (c *chainedService) verifyChainedFinalityProof(proofBytes []byte, height uint64) (customerData []byte, err error) {
+	proof := ChainedFinalityProof{}
+	err = json.Unmarshal(proofBytes, &proof)
+	if err != nil {
+		return
+	}
+
+	members, err := c.membersAt(height)
+	if err != nil {
+		return
+	}
+
+	b0 := chainedNode{
+		View:    proof.B1.Justify.ViewNumber,
+		Payload: proof.B1.Justify.Payload,
+	}
+
+	err = c.verifyNodeQC(proof.QC, proof.B2, members)
+	if err != nil {
+		return
+	}
+
+	err = c.verifyNodeQC(proof.B2.Justify, proof.B1, members)
+	if err != nil {
+		return
+	}
+
+	err = c.verifyNodeQC(proof.B1.Justify, b0, members)
+	if err != nil {
+		return
+	}
+
+	if !c.isParentOf(&proof.B1, &proof.B2) || !c.isParentOf(&b0, &proof.B1) {
+		err = errors.New("nodes of the three-chain are not linked")
+		return
+	}
+
+	if proof.B2.View != proof.B1.View+1 || proof.B1.View != b0.View+1 {
+		err = errors.New("nodes of the three-chain are not at consecutive views")
+		return
+	}
+
+	return c.decidedCustomerData(b0.Payload, proof.Ancestors)
+}
+
+func (b *basicService) VerifyFinality(proof []byte, height uint64) (customerData []byte, err error) {
+	b.phaseLock.Lock()
+	defer b.phaseLock.Unlock()
+
+	return b.verifyFinalityProof(proof, consensusPhases.Commit.String(), height)
+}
+
+func (c *chainedService) VerifyFinality(proof []byte, height uint64) (customerData []byte, err error) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.verifyChainedFinalityProof(proof, height)
+}
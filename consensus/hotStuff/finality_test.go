@@ -0,0 +1,173 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package hotStuff
+
+import (
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/crypto/signers/ecdsa/secp256k1"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"testing"
+)
+
+//the member set changes at the effective height
+type testMemberSource struct {
+	effectiveHeight uint64
+	oldKeys         [][]byte
+	newKeys         [][]byte
+}
+
+func (s testMemberSource) ConsensusMembers() (keys [][]byte, err error) {
+	return s.newKeys, nil
+}
+
+func (s testMemberSource) ConsensusMembersAt(height uint64) (keys [][]byte, err error) {
+	if height < s.effectiveHeight {
+		return s.oldKeys, nil
+	}
+	return s.newKeys, nil
+}
+
+func memberKeysOf(members []Member) (keys [][]byte) {
+	for _, m := range members {
+		keys = append(keys, m.Signer.PublicKeyBytes())
+	}
+	return
+}
+
+func TestFinalityVerifiedByMembersAtHeight(t *testing.T) {
+	oldVoters, oldMembers := newTestVoters(t, 4)
+	_, newMembers := newTestVoters(t, 4)
+
+	b := consensusBase{config: Config{
+		Members:         newMembers,
+		SingerGenerator: secp256k1.SignerGenerator,
+		HashCalc:        sha3.Sha256,
+		MemberSource: testMemberSource{
+			effectiveHeight: 10,
+			oldKeys:         memberKeysOf(oldMembers),
+			newKeys:         memberKeysOf(newMembers),
+		},
+	}}
+
+	qc := newTestQC(1)
+	qc.Phase = consensusPhases.Commit.String()
+	qc.Votes = signVotes(t, qc, oldVoters[:3])
+	proof := buildFinalityProof(qc, nil)
+
+	data, err := b.verifyFinalityProof(proof, consensusPhases.Commit.String(), 9)
+	if err != nil {
+		t.Fatal("proof of the members at the height not verified: ", err)
+	}
+
+	if string(data) != string(qc.Payload.CustomerData) {
+		t.Fatal("wrong decided data")
+	}
+
+	if _, err = b.verifyFinalityProof(proof, consensusPhases.Commit.String(), 10); err == nil {
+		t.Fatal("proof verified by the members of another height")
+	}
+
+	b.config.MemberSource = nil
+	if _, err = b.verifyFinalityProof(proof, consensusPhases.Commit.String(), 9); err == nil {
+		t.Fatal("proof of old members verified by the current members")
+	}
+}
+
+type testChain struct {
+	c      *chainedService
+	voters []testVoter
+}
+
+func newTestChain(t *testing.T) (tc testChain) {
+	voters, members := newTestVoters(t, 4)
+	enum.Build(&chainedPhases, 0, "")
+
+	tc.c = &chainedService{}
+	tc.c.config = Config{
+		Members:         members,
+		SingerGenerator: secp256k1.SignerGenerator,
+		HashCalc:        sha3.Sha256,
+	}
+	tc.voters = voters
+	return
+}
+
+//a node at the view extends the certified parent, and the returned QC certifies the node
+func (tc testChain) certifiedNode(t *testing.T, view uint64, data string, parent QC) (node chainedNode, qc QC) {
+	node.View = view
+	node.Justify = parent
+	node.Payload = ConsensusPayload{Parent: tc.c.nodeHash(parent.Payload), CustomerData: []byte(data)}
+
+	qc.Phase = chainedPhases.Generic.String()
+	qc.ViewNumber = view
+	qc.Payload = node.Payload
+	qc.Votes = signVotes(t, qc, tc.voters[:3])
+	return
+}
+
+func TestChainedFinalityByThreeChain(t *testing.T) {
+	tc := newTestChain(t)
+
+	_, qc0 := tc.certifiedNode(t, 1, "decided", tc.c.genesisQC())
+	b1, qc1 := tc.certifiedNode(t, 2, "", qc0)
+	b2, qc2 := tc.certifiedNode(t, 3, "", qc1)
+
+	proof := buildChainedFinalityProof(qc2, &b2, &b1, nil)
+	data, err := tc.c.verifyChainedFinalityProof(proof, 1)
+	if err != nil {
+		t.Fatal("three-chain proof not verified: ", err)
+	}
+
+	if string(data) != "decided" {
+		t.Fatal("wrong decided data")
+	}
+
+	//a three-chain must be signed by the members
+	tc.c.config.Members = tc.c.config.Members[1:]
+	if _, err = tc.c.verifyChainedFinalityProof(proof, 1); err == nil {
+		t.Fatal("three-chain of other members verified")
+	}
+}
+
+//a fork node certified by the members but not committed by a three-chain is never final
+func TestChainedFinalityForkRejected(t *testing.T) {
+	tc := newTestChain(t)
+
+	_, qc0 := tc.certifiedNode(t, 1, "committed", tc.c.genesisQC())
+	fork, forkQC := tc.certifiedNode(t, 2, "forked", qc0)
+
+	//the QC on the fork alone, as the proof of a basic hot-stuff
+	if _, err := tc.c.verifyChainedFinalityProof(buildFinalityProof(forkQC, nil), 1); err == nil {
+		t.Fatal("QC of a fork node verified as finality")
+	}
+
+	//the fork is certified and extended, but its children are not at consecutive views
+	b1, qc1 := tc.certifiedNode(t, 4, "", forkQC)
+	b2, qc2 := tc.certifiedNode(t, 5, "", qc1)
+	proof := buildChainedFinalityProof(qc2, &b2, &b1, nil)
+	if _, err := tc.c.verifyChainedFinalityProof(proof, 1); err == nil {
+		t.Fatal("fork node verified by a three-chain not at consecutive views")
+	}
+
+	//a three-chain mixed from two branches
+	_, otherQC := tc.certifiedNode(t, 3, "", qc0)
+	b2.Justify = otherQC
+	proof = buildChainedFinalityProof(qc2, &b2, &fork, nil)
+	if _, err := tc.c.verifyChainedFinalityProof(proof, 1); err == nil {
+		t.Fatal("fork node verified by the nodes of another branch")
+	}
+}
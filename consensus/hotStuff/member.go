@@ -35,17 +35,36 @@
 package hotStuff
 
 import (
+	"bytes"
+	"errors"
 	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/network"
 )
 
+//the config and network shared by basic and chained hot-stuff
+type consensusBase struct {
+	config  Config
+	network network.IService
+
+	//the last view at which a member failed to lead, derived from the committed proposals only
+	leaderFailures map[string]uint64
+}
+
+//the source of the member list for the next consensus round, such as the validator set on chain.
+//ConsensusMembersAt returns the members decided the data at the height, it's used to verify the finality proofs.
+type IMemberSource interface {
+	ConsensusMembers() (keys [][]byte, err error)
+	ConsensusMembersAt(height uint64) (keys [][]byte, err error)
+}
+
 type Member struct {
-	Signer   signerCommon.ISigner
-	FromNode network.Node
-	online   bool
+	Signer       signerCommon.ISigner
+	AggregateKey []byte
+	FromNode     network.Node
 }
 
-func (b *basicService) isMemberKey(memberKey []byte) bool {
+func (b *consensusBase) isMemberKey(memberKey []byte) bool {
 	ret := false
 	for _, m := range b.config.Members {
 		ret = m.Signer.PublicKeyCompare(memberKey)
@@ -56,34 +75,154 @@ func (b *basicService) isMemberKey(memberKey []byte) bool {
 	return ret
 }
 
-func (b *basicService) isAllMembersOnline() bool {
-	allNodes := b.network.GetAllLinkedNode()
+func (b *consensusBase) isSelf(m Member) bool {
+	return b.config.SelfSigner.PublicKeyCompare(m.Signer.PublicKeyBytes())
+}
 
-	memberCount := len(b.config.Members) - 1 //exclude self from member count
-	onlineCount := map[string]bool{}
+//refresh members' nodes from the linked nodes, returns the count of online members exclude self.
+func (b *consensusBase) refreshMembers() (onlineCount int) {
+	allNodes := b.network.GetAllLinkedNode()
 
+	linkedNodes := map[string]network.Node{}
 	for _, n := range allNodes {
+		linkedNodes[n.ID] = n
+	}
 
-		for idx, m := range b.config.Members {
+	for idx, m := range b.config.Members {
+		if b.isSelf(m) {
+			continue
+		}
 
-			if n.ID == m.Signer.PublicKeyString() {
-				b.config.Members[idx].FromNode = n
-				b.config.Members[idx].online = true
-			}
+		n, linked := linkedNodes[m.Signer.PublicKeyString()]
+		if !linked {
+			continue
+		}
 
-			if n.ID == m.Signer.PublicKeyString() {
-				onlineCount[n.ID] = true
-			}
+		b.config.Members[idx].FromNode = n
+		onlineCount += 1
+	}
+
+	return
+}
+
+func (b *consensusBase) isSameMembers(keys [][]byte) bool {
+	if len(keys) != len(b.config.Members) {
+		return false
+	}
+
+	for i, k := range keys {
+		if !b.config.Members[i].Signer.PublicKeyCompare(k) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//reload the member list from the member source, the leader schedule follows the new list from the next round.
+func (b *consensusBase) reloadMembers() {
+	if b.config.MemberSource == nil {
+		return
+	}
+
+	keys, err := b.config.MemberSource.ConsensusMembers()
+	if err != nil || len(keys) == 0 {
+		log.Log.Error("load consensus members failed: ", err)
+		return
+	}
+
+	if b.isSameMembers(keys) {
+		return
+	}
+
+	members, err := b.membersFromKeys(keys)
+	if err != nil {
+		log.Log.Error("invalid consensus member key: ", err.Error())
+		return
+	}
+
+	b.config.Members = members
+	log.Log.Println("consensus members reloaded, member count: ", len(members))
+}
+
+//the aggregate key of a member is kept if the member is in the current list
+func (b *consensusBase) membersFromKeys(keys [][]byte) (members []Member, err error) {
+	for _, k := range keys {
+		signer, keyErr := b.config.SingerGenerator.FromRawPublicKey(k)
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+
+		newMember := Member{Signer: signer}
+		if idx := memberIndex(b.config.Members, k); idx >= 0 {
+			newMember.AggregateKey = b.config.Members[idx].AggregateKey
 		}
+
+		members = append(members, newMember)
 	}
 
-	return memberCount <= len(onlineCount)
+	return
 }
 
-func (b *basicService) allMembersKey() (keys []string) {
+//the members decided the data at the height, they are the current members if there is no member source.
+func (b *consensusBase) membersAt(height uint64) (members []Member, err error) {
+	if b.config.MemberSource == nil {
+		members = b.config.Members
+		return
+	}
+
+	keys, err := b.config.MemberSource.ConsensusMembersAt(height)
+	if err != nil {
+		return
+	}
+
+	if len(keys) == 0 {
+		err = errors.New("no consensus members at the height")
+		return
+	}
+
+	return b.membersFromKeys(keys)
+}
+
+func (b *consensusBase) isQuorumOnline() bool {
+	return b.hasEnoughVotes(b.refreshMembers())
+}
+
+func (b *consensusBase) isAllMembersOnline() bool {
+	memberCount := len(b.config.Members) - 1 //exclude self from member count
+	return memberCount <= b.refreshMembers()
+}
+
+func (b *consensusBase) allMembersKey() (keys []string) {
 	for _, m := range b.config.Members {
 		keys = append(keys, m.Signer.PublicKeyString())
 	}
 
 	return
 }
+
+//leader rotates in members order, it depends on the view number and the member list only, so all members
+//agree on it whatever they are linked to. the members failed to lead recently, such as the offline ones, are
+//skipped unless the leader reputation is disabled, the failures are derived from the committed proposals,
+//see recordCommittedView.
+func (b *consensusBase) leaderOfView(viewNumber uint64) (leader Member) {
+	memberCount := uint64(len(b.config.Members))
+	if !b.config.DisableLeaderReputation {
+		for i := uint64(0); i < memberCount; i++ {
+			candidate := b.config.Members[(viewNumber+1+i)%memberCount]
+			if !b.isDemoted(candidate, viewNumber) {
+				leader = candidate
+				return
+			}
+		}
+	}
+
+	leader = b.config.Members[(viewNumber+1)%memberCount]
+	return
+}
+
+func (b *consensusBase) isViewLeader(viewNumber uint64, key []byte) (isLeader bool) {
+	leader := b.leaderOfView(viewNumber)
+	return bytes.Equal(key, leader.Signer.PublicKeyBytes())
+}
@@ -37,7 +37,6 @@ package hotStuff
 import (
 	"encoding/json"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
-	"github.com/SealSC/SealABC/crypto/hashes/sha3"
 	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/message"
@@ -68,14 +67,14 @@ func (b *basicService) consensusDataFromMessage(msg message.Message) (consensusD
 	return
 }
 
-func (b *basicService) buildVote(qcData QCData) (vote seal.Entity, err error) {
+func (b *consensusBase) buildVote(qcData QCData) (vote seal.Entity, err error) {
 	qcBytes, err := structSerializer.ToMFBytes(qcData)
 	if err != nil {
 		log.Log.Error("serialize QC data failed")
 		return
 	}
 
-	qcHash := sha3.Sha256.Sum(qcBytes)
+	qcHash := b.config.HashCalc.Sum(qcBytes)
 	sig, err := b.config.SelfSigner.Sign(qcHash)
 	if err != nil {
 		return
@@ -112,13 +111,19 @@ func (b *basicService) buildConsensusMessage(phase string, payload ConsensusPayl
 	}
 
 	//sign
-	consensusMsg.Seal.Hash = sha3.Sha256.Sum(consensusBytes)
+	consensusMsg.Seal.Hash = b.config.HashCalc.Sum(consensusBytes)
 	consensusMsg.Seal.SignerPublicKey = b.config.SelfSigner.PublicKeyBytes()
 	consensusMsg.Seal.Signature, err = b.config.SelfSigner.Sign(consensusMsg.Seal.Hash)
 	if err != nil {
 		return
 	}
 
+	//the leader aggregates the BLS signatures of votes into an aggregate QC
+	consensusMsg.AggregateVote, err = b.buildAggregateVote(consensusMsg.Seal.Hash)
+	if err != nil {
+		return
+	}
+
 	//marshal data to json
 	msgPayload, err = json.Marshal(consensusMsg)
 	if err != nil {
@@ -155,7 +160,8 @@ func (b *basicService) buildNewViewQC() (qc *QC) {
 		}
 	}
 
-	if newQC.ViewNumber == 0 {
+	//only the genesis QC is voted by the sender, a prepare QC of view 0 is complete already
+	if newQC.isGenesis() {
 		appendPrevVotes := true
 		if len(newQC.Votes) > 0 {
 			appendPrevVotes = !b.config.SelfSigner.PublicKeyCompare(newQC.Votes[0].SignerPublicKey)
@@ -188,11 +194,7 @@ func (b *basicService) buildNewViewMessage() (msg message.Message, err error) {
 	return
 }
 
-func (b *basicService) buildCommonPhaseMessage(
-	phase enum.Element,
-	msgType enum.Element,
-	votedQC *QC) (msg message.Message, err error) {
-
+func (b *basicService) buildSignaturesQC(votedQC *QC) (err error) {
 	for _, votedMsg := range b.votedMessage {
 		votedQC.Votes = append(votedQC.Votes, votedMsg.Seal)
 	}
@@ -203,6 +205,37 @@ func (b *basicService) buildCommonPhaseMessage(
 	}
 
 	votedQC.Votes = append(votedQC.Votes, selfVote)
+	return
+}
+
+func (b *basicService) buildAggregateQC(votedQC *QC) (err error) {
+	qcHash, err := votedQC.hash(b.config.HashCalc)
+	if err != nil {
+		return
+	}
+
+	selfSig, err := b.buildAggregateVote(qcHash)
+	if err != nil {
+		return
+	}
+
+	return b.aggregateVotes(votedQC, b.votedMessage, selfSig)
+}
+
+func (b *basicService) buildCommonPhaseMessage(
+	phase enum.Element,
+	msgType enum.Element,
+	votedQC *QC) (msg message.Message, err error) {
+
+	if b.useAggregateQC() {
+		err = b.buildAggregateQC(votedQC)
+	} else {
+		err = b.buildSignaturesQC(votedQC)
+	}
+
+	if err != nil {
+		return
+	}
 
 	msgPayload, err := b.buildConsensusMessage(
 		phase.String(),
@@ -236,8 +269,8 @@ func (b *basicService) buildLeafNode(basedQC QC) (node ConsensusPayload, err err
 	return
 }
 
-func (b *basicService) buildPrepareMessage(highQC QC) (msg message.Message, err error) {
-	payload, err := b.buildLeafNode(highQC)
+func (b *basicService) buildPrepareMessage(highQC QC) (msg message.Message, payload ConsensusPayload, err error) {
+	payload, err = b.buildLeafNode(highQC)
 	if err != nil {
 		return
 	}
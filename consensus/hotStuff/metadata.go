@@ -86,6 +86,10 @@ type QCData struct {
 type QC struct {
 	QCData
 	Votes []seal.Entity
+
+	//only used by the aggregate QC
+	AggregateSignature []byte
+	AggregateSigners   []byte
 }
 
 type ConsensusData struct {
@@ -98,4 +102,7 @@ type ConsensusData struct {
 type SignedConsensusData struct {
 	ConsensusData
 	Seal seal.Entity
+
+	//the BLS signature of a vote, it will be aggregated into the QC by the leader
+	AggregateVote []byte
 }
@@ -39,7 +39,7 @@ import (
 )
 
 func (b *basicService) sendMessageToLeader(msg message.Message) {
-	b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+	b.viewChangeTrigger.Reset(b.viewTimeout())
 	leader := b.getLeader()
 	//todo : modular log system
 	//log.Log.Println("send message to leader node: ", leader.FromNode, " msg : ", msg.Type)
@@ -47,7 +47,7 @@ func (b *basicService) sendMessageToLeader(msg message.Message) {
 }
 
 func (b *basicService) broadCastMessage(msg message.Message) {
-	b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+	b.viewChangeTrigger.Reset(b.viewTimeout())
 
 	//todo: modular log system
 	//log.Log.Println("broadcast message: ", msg.Type)
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"bytes"
+	"errors"
+	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/crypto/signers/bls12381"
+	"github.com/SealSC/SealABC/log"
+)
+
+//the QC types, a signatures QC carries a signature of every voter and an aggregate QC carries
+//one BLS signature aggregated from all voters and a bitmap of voters in members order.
+const SignaturesQC = "signatures"
+const AggregateQC = "bls-aggregate"
+
+func memberIndex(members []Member, key []byte) int {
+	for i, m := range members {
+		if m.Signer.PublicKeyCompare(key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//the QC of genesis has no payload and needs no quorum.
+func (q QC) isGenesis() bool {
+	return q.ViewNumber == 0 &&
+		len(q.AggregateSignature) == 0 &&
+		len(q.Payload.Parent) == 0 &&
+		len(q.Payload.CustomerData) == 0
+}
+
+func (q QC) hash(hashCalc hashes.IHashCalculator) (qcHash []byte, err error) {
+	qcBytes, err := structSerializer.ToMFBytes(q.QCData)
+	if err != nil {
+		return
+	}
+
+	qcHash = hashCalc.Sum(qcBytes)
+	return
+}
+
+//verify the QC is signed by a quorum of the members, every voter counts once and every vote must be
+//signed on the phase, view and payload of this QC.
+func (q QC) Verify(members []Member, hashCalc hashes.IHashCalculator) (err error) {
+	qcHash, err := q.hash(hashCalc)
+	if err != nil {
+		return
+	}
+
+	if len(q.AggregateSignature) > 0 {
+		return q.verifyAggregate(members, qcHash)
+	}
+
+	return q.verifyVotes(members, qcHash)
+}
+
+func (q QC) verifyVotes(members []Member, qcHash []byte) (err error) {
+	if len(q.AggregateSigners) > 0 {
+		return errors.New("signers bitmap without aggregate signature")
+	}
+
+	voted := map[int]bool{}
+	for _, v := range q.Votes {
+		idx := memberIndex(members, v.SignerPublicKey)
+		if idx < 0 {
+			return errors.New("vote is not from a member")
+		}
+
+		if voted[idx] {
+			return errors.New("duplicate voter in QC")
+		}
+
+		if !bytes.Equal(v.Hash, qcHash) {
+			return errors.New("vote is not for the phase and view of the QC")
+		}
+
+		passed, _ := members[idx].Signer.Verify(qcHash, v.Signature)
+		if !passed {
+			return errors.New("invalid vote signature")
+		}
+
+		voted[idx] = true
+	}
+
+	if q.isGenesis() {
+		return
+	}
+
+	if len(voted) < consensus.QuorumOf(len(members)) {
+		err = errors.New("not enough votes in QC")
+	}
+	return
+}
+
+func (q QC) verifyAggregate(members []Member, qcHash []byte) (err error) {
+	if len(q.Votes) > 0 {
+		return errors.New("votes list with aggregate signature")
+	}
+
+	if len(q.AggregateSigners) != (len(members)+7)/8 {
+		return errors.New("invalid signers bitmap size")
+	}
+
+	var keys [][]byte
+	for i := 0; i < len(q.AggregateSigners)*8; i++ {
+		if q.AggregateSigners[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+
+		if i >= len(members) {
+			return errors.New("signer out of members")
+		}
+
+		if len(members[i].AggregateKey) == 0 {
+			return errors.New("signer has no aggregate key")
+		}
+
+		keys = append(keys, members[i].AggregateKey)
+	}
+
+	if len(keys) < consensus.QuorumOf(len(members)) {
+		return errors.New("not enough signers in QC")
+	}
+
+	passed, err := bls12381.VerifyAggregated(qcHash, q.AggregateSignature, keys)
+	if err != nil {
+		return
+	}
+
+	if !passed {
+		err = errors.New("invalid aggregate signature")
+	}
+	return
+}
+
+func (b *consensusBase) isValidQC(qc QC) (passed bool) {
+	err := qc.Verify(b.config.Members, b.config.HashCalc)
+	if err != nil {
+		log.Log.Error("invalid QC @view ", qc.ViewNumber, " phase ", qc.Phase, ": ", err.Error())
+		return
+	}
+
+	return true
+}
+
+//the aggregate QC is used only if all members have an aggregate key, or the leader can't count every voter in.
+func (b *consensusBase) useAggregateQC() bool {
+	if b.config.QCType != AggregateQC || b.config.AggregateSigner == nil {
+		return false
+	}
+
+	for _, m := range b.config.Members {
+		if len(m.AggregateKey) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *consensusBase) buildAggregateVote(qcHash []byte) (sig []byte, err error) {
+	if b.config.AggregateSigner == nil {
+		return
+	}
+
+	return b.config.AggregateSigner.Sign(qcHash)
+}
+
+func (b *consensusBase) verifyAggregateVote(memberKey []byte, qcHash []byte, sig []byte) (passed bool) {
+	idx := memberIndex(b.config.Members, memberKey)
+	if idx < 0 || len(b.config.Members[idx].AggregateKey) == 0 {
+		return
+	}
+
+	signer, err := bls12381.SignerGenerator.FromRawPublicKey(b.config.Members[idx].AggregateKey)
+	if err != nil {
+		return
+	}
+
+	passed, _ = signer.Verify(qcHash, sig)
+	return
+}
+
+//aggregate the votes and the leader's own vote into the QC, the votes must be verified one by one before.
+func (b *consensusBase) aggregateVotes(qc *QC, votes map[string]SignedConsensusData, selfSig []byte) (err error) {
+	bitmap := make([]byte, (len(b.config.Members)+7)/8)
+	sigs := [][]byte{selfSig}
+
+	selfIdx := memberIndex(b.config.Members, b.config.SelfSigner.PublicKeyBytes())
+	if selfIdx < 0 {
+		return errors.New("leader is not a member")
+	}
+	bitmap[selfIdx/8] |= 1 << uint(selfIdx%8)
+
+	for _, v := range votes {
+		idx := memberIndex(b.config.Members, v.Seal.SignerPublicKey)
+		if idx < 0 || bitmap[idx/8]&(1<<uint(idx%8)) != 0 {
+			continue
+		}
+
+		bitmap[idx/8] |= 1 << uint(idx%8)
+		sigs = append(sigs, v.AggregateVote)
+	}
+
+	qc.AggregateSignature, err = bls12381.AggregateSignatures(sigs)
+	if err != nil {
+		return
+	}
+
+	qc.AggregateSigners = bitmap
+	qc.Votes = nil
+	return
+}
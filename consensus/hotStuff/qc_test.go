@@ -0,0 +1,173 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/crypto/signers/bls12381"
+	"github.com/SealSC/SealABC/crypto/signers/ecdsa/secp256k1"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"github.com/SealSC/SealABC/metadata/seal"
+	"testing"
+)
+
+type testVoter struct {
+	signer          signerCommon.ISigner
+	aggregateSigner signerCommon.ISigner
+}
+
+func newTestVoters(t *testing.T, count int) (voters []testVoter, members []Member) {
+	loadTestEnvironment()
+
+	for i := 0; i < count; i++ {
+		signer, err := secp256k1.SignerGenerator.NewSigner(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		aggregateSigner, err := bls12381.SignerGenerator.NewSigner(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		voters = append(voters, testVoter{signer: signer, aggregateSigner: aggregateSigner})
+		members = append(members, Member{Signer: signer, AggregateKey: aggregateSigner.PublicKeyBytes()})
+	}
+	return
+}
+
+func newTestQC(view uint64) (qc QC) {
+	qc.Phase = consensusPhases.Prepare.String()
+	qc.ViewNumber = view
+	qc.Payload = ConsensusPayload{Parent: []byte("parent"), CustomerData: []byte("data")}
+	return
+}
+
+func signVotes(t *testing.T, qc QC, voters []testVoter) (votes []seal.Entity) {
+	qcHash, err := qc.hash(sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range voters {
+		sig, err := v.signer.Sign(qcHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		votes = append(votes, seal.Entity{Hash: qcHash, SignerPublicKey: v.signer.PublicKeyBytes(), Signature: sig})
+	}
+	return
+}
+
+func aggregateVotes(t *testing.T, qc *QC, voters []testVoter, signerIndexes []int) {
+	qcHash, err := qc.hash(sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bitmap := make([]byte, (len(voters)+7)/8)
+	var sigs [][]byte
+	for _, i := range signerIndexes {
+		sig, err := voters[i].aggregateSigner.Sign(qcHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sigs = append(sigs, sig)
+		bitmap[i/8] |= 1 << uint(i%8)
+	}
+
+	qc.AggregateSignature, err = bls12381.AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qc.AggregateSigners = bitmap
+}
+
+func TestSignaturesQCVerify(t *testing.T) {
+	voters, members := newTestVoters(t, 4)
+
+	qc := newTestQC(1)
+	qc.Votes = signVotes(t, qc, voters[:3])
+	if err := qc.Verify(members, sha3.Sha256); err != nil {
+		t.Fatal("QC of a quorum not verified: ", err)
+	}
+
+	notEnough := qc
+	notEnough.Votes = qc.Votes[:2]
+	if notEnough.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("QC without a quorum verified")
+	}
+
+	duplicated := qc
+	duplicated.Votes = append([]seal.Entity{}, qc.Votes[:2]...)
+	duplicated.Votes = append(duplicated.Votes, qc.Votes[0])
+	if duplicated.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("QC with a duplicated voter verified")
+	}
+
+	otherView := newTestQC(2)
+	otherView.Votes = qc.Votes
+	if otherView.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("QC verified by the votes of another view")
+	}
+
+	outsiders, _ := newTestVoters(t, 1)
+	withOutsider := qc
+	withOutsider.Votes = append(append([]seal.Entity{}, qc.Votes[:2]...), signVotes(t, qc, outsiders)...)
+	if withOutsider.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("QC with a vote of non-member verified")
+	}
+}
+
+func TestAggregateQCVerify(t *testing.T) {
+	voters, members := newTestVoters(t, 4)
+
+	qc := newTestQC(1)
+	aggregateVotes(t, &qc, voters, []int{0, 2, 3})
+	if err := qc.Verify(members, sha3.Sha256); err != nil {
+		t.Fatal("aggregate QC of a quorum not verified: ", err)
+	}
+
+	notEnough := newTestQC(1)
+	aggregateVotes(t, &notEnough, voters, []int{0, 2})
+	if notEnough.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("aggregate QC without a quorum verified")
+	}
+
+	//the bitmap claims a signer not aggregated
+	wrongSigners := qc
+	wrongSigners.AggregateSigners = []byte{0x07}
+	if wrongSigners.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("aggregate QC verified with wrong signers")
+	}
+
+	otherView := newTestQC(2)
+	otherView.AggregateSignature = qc.AggregateSignature
+	otherView.AggregateSigners = qc.AggregateSigners
+	if otherView.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("aggregate QC verified on another view")
+	}
+
+	withVotes := qc
+	withVotes.Votes = signVotes(t, qc, voters[:1])
+	if withVotes.Verify(members, sha3.Sha256) == nil {
+		t.Fatal("aggregate QC verified with a votes list")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"time"
+)
+
+//the view timeout doubles at most this many times
+const maxTimeoutBackoff = 6
+
+//a member failed to lead is demoted until a full rotation of views passed since its failure.
+func (b *consensusBase) isDemoted(m Member, viewNumber uint64) bool {
+	failedAt, failed := b.leaderFailures[m.Signer.PublicKeyString()]
+	if !failed {
+		return false
+	}
+
+	return viewNumber <= failedAt+uint64(len(b.config.Members))
+}
+
+//the views between the justify QC of a committed proposal and the proposal itself produced no QC, their leaders
+//are recorded as failed and the leader of the committed proposal is cleared. only the committed proposals are
+//counted, so the members committed the same proposals derive the same leader schedule.
+func (b *consensusBase) recordCommittedView(justifyView uint64, committedView uint64) {
+	if b.config.DisableLeaderReputation || committedView <= justifyView {
+		return
+	}
+
+	//a failure older than a full rotation demotes nobody
+	fromView := justifyView + 1
+	memberCount := uint64(len(b.config.Members))
+	if committedView > memberCount && fromView < committedView-memberCount {
+		fromView = committedView - memberCount
+	}
+
+	//the leaders are resolved by the schedule in effect before this commit
+	failedLeaders := map[string]uint64{}
+	for v := fromView; v < committedView; v++ {
+		leader := b.leaderOfView(v)
+		failedLeaders[leader.Signer.PublicKeyString()] = v
+	}
+
+	committedLeader := b.leaderOfView(committedView)
+
+	for key, v := range failedLeaders {
+		b.leaderFailures[key] = v
+	}
+	delete(b.leaderFailures, committedLeader.Signer.PublicKeyString())
+}
+
+//the leaders of the views from the given view in a full rotation
+func (b *consensusBase) leaderSchedule(fromView uint64) (schedule []string) {
+	for i := 0; i < len(b.config.Members); i++ {
+		leader := b.leaderOfView(fromView + uint64(i))
+		schedule = append(schedule, leader.Signer.PublicKeyString())
+	}
+
+	return
+}
+
+//the view timeout doubles for every consecutive failed view, and is reset by a decide.
+func (b *basicService) viewTimeout() (timeout time.Duration) {
+	timeout = b.config.ConsensusTimeout << b.failedViews
+	if b.config.MaxConsensusTimeout > 0 && timeout > b.config.MaxConsensusTimeout {
+		timeout = b.config.MaxConsensusTimeout
+	}
+
+	return
+}
+
+func (b *basicService) updateSchedule() {
+	b.scheduleLock.Lock()
+	defer b.scheduleLock.Unlock()
+
+	b.currentSchedule = b.leaderSchedule(b.currentView)
+	b.currentTimeout = b.viewTimeout()
+}
+
+func (b *basicService) viewFailed() {
+	if b.failedViews < maxTimeoutBackoff {
+		b.failedViews += 1
+	}
+}
+
+func (b *basicService) viewDecided() {
+	b.recordCommittedView(b.proposalJustifyView, b.currentView)
+	b.failedViews = 0
+}
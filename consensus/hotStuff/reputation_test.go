@@ -0,0 +1,48 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"testing"
+)
+
+//an offline leader failed its view is skipped by default, and the committed proposals give every member the same schedule
+func TestOfflineLeaderSkipped(t *testing.T) {
+	c := newTestCluster(t, 4)
+	svc := c.services[0]
+
+	offline := svc.leaderOfView(1)
+	svc.recordCommittedView(0, 2)
+
+	for view := uint64(2); view <= 5; view++ {
+		if svc.leaderOfView(view).Signer.PublicKeyCompare(offline.Signer.PublicKeyBytes()) {
+			t.Fatal("failed leader is not skipped at view ", view)
+		}
+	}
+
+	if !svc.leaderOfView(6).Signer.PublicKeyCompare(svc.config.Members[3].Signer.PublicKeyBytes()) {
+		t.Fatal("failed leader is not restored after a full rotation")
+	}
+
+	disabled := c.services[1]
+	disabled.config.DisableLeaderReputation = true
+	disabled.recordCommittedView(0, 2)
+	if !disabled.leaderOfView(5).Signer.PublicKeyCompare(offline.Signer.PublicKeyBytes()) {
+		t.Fatal("leader is skipped with the leader reputation disabled")
+	}
+}
@@ -54,10 +54,12 @@ type basicHotStuffInformation struct {
 	Members           []string
 	ConsensusInterval time.Duration
 	ConsensusTimeout  time.Duration
+	CurrentTimeout    time.Duration
+	LeaderSchedule    []string
 }
 
 type basicService struct {
-	config Config
+	consensusBase
 
 	currentState enum.Element
 	currentPhase enum.Element
@@ -69,13 +71,23 @@ type basicService struct {
 	lockedQC          *QC
 	viewChangeTrigger *time.Timer
 	currentView       uint64
+	failedViews       uint
+	proposalData      []byte
+	recovered         bool
+	stopSignal        chan struct{}
 
 	consensusProcessor map[string]consensusProcessor
 	externalProcessor  consensus.ExternalProcessor
 
-	network network.IService
+	//the view of the QC justified the current proposal, used by the leader reputation when it is decided
+	proposalJustifyView uint64
 
 	information *basicHotStuffInformation
+
+	//the snapshot of leader schedule and view timeout for information, updated at every new round
+	scheduleLock    sync.RWMutex
+	currentSchedule []string
+	currentTimeout  time.Duration
 }
 
 var Basic basicService
@@ -87,16 +99,8 @@ func (b *basicService) isCurrentLeader() (isLeader bool) {
 	return bytes.Equal(selfKey, leader.Signer.PublicKeyBytes())
 }
 
-func (b *basicService) isViewLeader(viewNumber uint64, key []byte) (isLeader bool) {
-	leaderIndex := (viewNumber + 1) % uint64(len(b.config.Members))
-	leader := b.config.Members[leaderIndex]
-	return bytes.Equal(key, leader.Signer.PublicKeyBytes())
-}
-
 func (b *basicService) getLeader() (leader Member) {
-	leaderIndex := (b.currentView + 1) % uint64(len(b.config.Members))
-	leader = b.config.Members[leaderIndex]
-	return
+	return b.leaderOfView(b.currentView)
 }
 
 func (b *basicService) clearNewView() {
@@ -113,8 +117,15 @@ func (b *basicService) clearNewView() {
 }
 
 func (b *basicService) newRound() {
-	b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+	b.viewChangeTrigger.Reset(b.viewTimeout())
 	b.clearNewView()
+	b.reloadMembers()
+	b.refreshMembers()
+	b.updateSchedule()
+
+	if b.saveSafetyState() != nil {
+		return
+	}
 
 	if !b.isCurrentLeader() {
 		newViewMsg, err := b.buildNewViewMessage()
@@ -140,19 +151,40 @@ func (b *basicService) startViewChangeMonitor() {
 	for {
 		select {
 		case <-b.viewChangeTrigger.C:
-			//do view change
+			//do view change, the timer is reset with the backed off timeout by the new round
 			b.viewChange()
 
-			//reset the timer
-			b.viewChangeTrigger.Reset(b.config.ConsensusTimeout)
+		case <-b.stopSignal:
+			log.Log.Println("view change monitor stopped")
+			return
 		}
 	}
 }
 
+//the customer data of the proposal not decided before view change is returned to the external processor as failed.
+func (b *basicService) cancelProposal() {
+	proposalData := b.proposalData
+	b.proposalData = nil
+
+	if len(proposalData) == 0 || b.externalProcessor == nil {
+		return
+	}
+
+	log.Log.Warn("consensus failed @view ", b.currentView)
+	b.externalProcessor.EventProcessor(consensus.Event.Failed, proposalData, nil)
+}
+
 func (b *basicService) viewChange() {
 	b.phaseLock.Lock()
 	defer b.phaseLock.Unlock()
 
+	if b.isStopped() {
+		return
+	}
+
+	b.cancelProposal()
+	b.viewFailed()
+
 	b.currentView += 1
 	b.currentPhase = consensusPhases.NewView
 	log.Log.Println("view change to new view ", b.currentView)
@@ -160,37 +192,67 @@ func (b *basicService) viewChange() {
 	b.newRound()
 }
 
-func (b *basicService) initService() {
+//a recovered replica rejoins the view it had reached before it stopped, the other members are
+//already running, so there is no need to wait for all of them before sending the new view.
+func (b *basicService) recoverService() {
 	onlineCheck := time.NewTimer(b.config.MemberOnlineCheckInterval)
-	allMemberOnline := false
 	for {
-		if b.currentState.String() == consensus.States.Stopped.String() {
-			break
-		}
-
 		select {
 		case <-onlineCheck.C:
-			allMemberOnline = b.isAllMembersOnline()
+		case <-b.stopSignal:
+			return
 		}
 
-		if allMemberOnline {
-			log.Log.Println("all members online now!")
+		if b.refreshMembers() > 0 {
 			break
 		}
 
 		onlineCheck.Reset(b.config.MemberOnlineCheckInterval)
 	}
 
-	if b.currentState.String() == consensus.States.Stopped.String() {
+	log.Log.Println("rejoin consensus @view ", b.currentView)
+
+	b.phaseLock.Lock()
+	defer b.phaseLock.Unlock()
+	if b.isStopped() {
 		return
 	}
+	go b.startViewChangeMonitor()
+
+	b.currentPhase = consensusPhases.NewView
+	b.newRound()
+}
 
-	if !allMemberOnline {
+//consensus starts once a BFT quorum of members is online, the late members will catch up by the new view flow.
+func (b *basicService) initService() {
+	if b.recovered {
+		b.recoverService()
 		return
 	}
 
+	onlineCheck := time.NewTimer(b.config.MemberOnlineCheckInterval)
+	quorumOnline := false
+	for {
+		select {
+		case <-onlineCheck.C:
+			quorumOnline = b.isQuorumOnline()
+		case <-b.stopSignal:
+			return
+		}
+
+		if quorumOnline {
+			log.Log.Println("consensus quorum online now!")
+			break
+		}
+
+		onlineCheck.Reset(b.config.MemberOnlineCheckInterval)
+	}
+
 	b.phaseLock.Lock()
 	defer b.phaseLock.Unlock()
+	if b.isStopped() {
+		return
+	}
 	go b.startViewChangeMonitor()
 
 	b.newRound()
@@ -223,9 +285,18 @@ func (b *basicService) Feed(msg message.Message) (reply *message.Message) {
 		return
 	}
 
+	if !b.verifyJustify(msg.Type, consensusData) {
+		log.Log.Error("invalid justify of message ", msg.Type)
+		return
+	}
+
 	b.phaseLock.Lock()
 	defer b.phaseLock.Unlock()
 
+	if b.isStopped() {
+		return
+	}
+
 	if consensusData.ViewNumber < b.currentView {
 		return
 	}
@@ -242,18 +313,38 @@ func (b *basicService) Feed(msg message.Message) (reply *message.Message) {
 func (b *basicService) Start(cfg interface{}) (err error) {
 	config, ok := cfg.(Config)
 	if !ok {
-		errors.New("invalid config")
+		err = errors.New("invalid config")
 		return
 	}
 
 	Basic.config = config
 	Basic.currentState = consensus.States.Init
+	Basic.recovered = Basic.loadSafetyState()
+	Basic.stopSignal = make(chan struct{})
 
 	go b.initService()
 	return
 }
 
+func (b *basicService) isStopped() bool {
+	return b.currentState.String() == consensus.States.Stopped.String()
+}
+
+//stop the timers and goroutines of consensus, the in-flight message will be finished before stop.
 func (b *basicService) Stop() (err error) {
+	b.phaseLock.Lock()
+	defer b.phaseLock.Unlock()
+
+	if b.isStopped() || b.stopSignal == nil {
+		return
+	}
+
+	b.currentState = consensus.States.Stopped
+	b.viewChangeTrigger.Stop()
+	close(b.stopSignal)
+
+	err = b.saveSafetyState()
+	log.Log.Println("consensus stopped @view ", b.currentView)
 	return
 }
 
@@ -290,6 +381,7 @@ func (b *basicService) Load(networkService network.IService, processor consensus
 
 	b.network = networkService
 
+	b.leaderFailures = map[string]uint64{}
 	b.newViews = map[string]SignedConsensusData{}
 	b.votedMessage = map[string]SignedConsensusData{}
 	b.consensusProcessor = map[string]consensusProcessor{}
@@ -301,7 +393,13 @@ func (b *basicService) Load(networkService network.IService, processor consensus
 }
 
 func (b *basicService) StaticInformation() interface{} {
+	//the leader schedule and the timeout change with the views
+	b.scheduleLock.Lock()
+	defer b.scheduleLock.Unlock()
+
 	if b.information != nil {
+		b.information.CurrentTimeout = b.currentTimeout
+		b.information.LeaderSchedule = b.currentSchedule
 		return b.information
 	}
 
@@ -311,6 +409,8 @@ func (b *basicService) StaticInformation() interface{} {
 	info.Members = b.allMembersKey()
 	info.ConsensusInterval = b.config.ConsensusInterval
 	info.ConsensusTimeout = b.config.ConsensusTimeout
+	info.CurrentTimeout = b.currentTimeout
+	info.LeaderSchedule = b.currentSchedule
 
 	if !b.isAllMembersOnline() {
 		return info
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package hotStuff
+
+import (
+	"github.com/SealSC/SealABC/log"
+)
+
+//basic and chained hot-stuff keep their states under different keys, so they could share one store
+const (
+	basicSafetyStateKey   = "basicHotStuffSafetyState"
+	chainedSafetyStateKey = "chainedHotStuffSafetyState"
+)
+
+//the values a replica must not forget after a restart, otherwise it may vote against a locked value.
+type SafetyState struct {
+	CurrentView uint64
+	PrepareQC   *QC
+	LockedQC    *QC
+
+	//the leader failures derived from the committed proposals, the leader schedule depends on it
+	LeaderFailures map[string]uint64
+}
+
+func (b *basicService) saveSafetyState() (err error) {
+	if b.config.StateStore == nil {
+		return
+	}
+
+	err = b.config.StateStore.Save(basicSafetyStateKey, SafetyState{
+		CurrentView: b.currentView,
+		PrepareQC:   b.prepareQC,
+		LockedQC:    b.lockedQC,
+
+		LeaderFailures: b.leaderFailures,
+	})
+
+	if err != nil {
+		log.Log.Error("save consensus safety state failed: ", err.Error())
+	}
+	return
+}
+
+func (b *basicService) loadSafetyState() (recovered bool) {
+	if b.config.StateStore == nil {
+		return
+	}
+
+	state := SafetyState{}
+	exists, err := b.config.StateStore.Load(basicSafetyStateKey, &state)
+	if err != nil {
+		log.Log.Error("load consensus safety state failed: ", err.Error())
+		return
+	}
+
+	if !exists {
+		return
+	}
+
+	b.currentView = state.CurrentView
+	b.prepareQC = state.PrepareQC
+	b.lockedQC = state.LockedQC
+	b.restoreLeaderFailures(state.LeaderFailures)
+
+	log.Log.Println("consensus safety state recovered @view ", b.currentView)
+	return true
+}
+
+func (b *consensusBase) restoreLeaderFailures(failures map[string]uint64) {
+	b.leaderFailures = map[string]uint64{}
+	for k, v := range failures {
+		b.leaderFailures[k] = v
+	}
+}
@@ -36,48 +36,12 @@ package hotStuff
 
 import (
 	"bytes"
+	"github.com/SealSC/SealABC/consensus"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
-	"github.com/SealSC/SealABC/crypto/hashes/sha3"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/seal"
 )
 
-func (b *basicService) verifyQCVotes(qc QC) (passed bool) {
-	passed = false
-
-	voteCounter := map[string]bool{}
-
-	signedData := qc.QCData
-	signedBytes, _ := structSerializer.ToMFBytes(signedData)
-
-	voterCount := len(qc.Votes)
-	for _, v := range qc.Votes {
-		if !b.isMemberKey(v.SignerPublicKey) {
-			log.Log.Error("signature not a from a member!")
-			continue
-		}
-
-		signer, _ := b.config.SingerGenerator.FromRawPublicKey(v.SignerPublicKey)
-		qcHash := sha3.Sha256.Sum(signedBytes)
-		if passed, _ = signer.Verify(qcHash, v.Signature); passed {
-			voteCounter[signer.PublicKeyString()] = true
-		} else {
-			log.Log.Error("invalid signature")
-		}
-	}
-
-	if voterCount != 1 {
-		passed = b.hasEnoughVotes(len(voteCounter))
-	} else {
-		passed = len(voteCounter) == 1
-	}
-
-	if !passed {
-		log.Log.Error("not enough valid vote: ", voterCount, " counter: ", len(voteCounter))
-	}
-	return
-}
-
 func (b *basicService) newQCForMatching(consensusData ConsensusData) (qcForMatching *QCData) {
 	allPhases := consensusPhases
 	qcForMatching = &QCData{}
@@ -99,7 +63,7 @@ func (b *basicService) newQCForMatching(consensusData ConsensusData) (qcForMatch
 	return
 }
 
-func (b *basicService) verifySignature(data interface{}, sig seal.Entity) (passed bool) {
+func (b *consensusBase) verifySignature(data interface{}, sig seal.Entity) (passed bool) {
 	passed = false
 	dataBytes, err := structSerializer.ToMFBytes(data)
 	if err != nil {
@@ -107,7 +71,7 @@ func (b *basicService) verifySignature(data interface{}, sig seal.Entity) (passe
 		return
 	}
 
-	hash := sha3.Sha256.Sum(dataBytes)
+	hash := b.config.HashCalc.Sum(dataBytes)
 	signer, _ := b.config.SingerGenerator.FromRawPublicKey(sig.SignerPublicKey)
 	passed, _ = signer.Verify(hash, sig.Signature)
 	return
@@ -128,10 +92,9 @@ func (b *basicService) verifyPhase(consensusData ConsensusData) (passed bool) {
 		if qcData.ViewNumber < matchingQC.ViewNumber {
 			log.Log.Error("in phase ", qcData.Phase, " verify found QC not in the same [VIEW]. [local@", matchingQC.ViewNumber, " : remote@", qcData.ViewNumber, "]")
 		} else {
-			passed = b.verifyQCVotes(qc)
-			if passed {
-				log.Log.Warn("in phase ", qcData.Phase, " current view is lower then network, but QC is valid. maybe I am behind")
-			}
+			//the QC has been verified when the message was fed
+			passed = true
+			log.Log.Warn("in phase ", qcData.Phase, " current view is lower then network, but QC is valid. maybe I am behind")
 		}
 		return
 	}
@@ -141,8 +104,7 @@ func (b *basicService) verifyPhase(consensusData ConsensusData) (passed bool) {
 		return
 	}
 
-	passed = b.verifyQCVotes(qc)
-
+	passed = true
 	return
 }
 
@@ -169,6 +131,20 @@ func (b *basicService) verifyVoteMessage(consensusData SignedConsensusData) (pas
 		return
 	}
 
+	//a bad BLS vote will spoil the aggregate QC, so it must be checked before collected
+	if b.useAggregateQC() {
+		votedQC := QC{}
+		votedQC.Phase = consensusData.Phase
+		votedQC.ViewNumber = consensusData.ViewNumber
+		votedQC.Payload = consensusData.Payload
+
+		qcHash, _ := votedQC.hash(b.config.HashCalc)
+		if !b.verifyAggregateVote(memberKey, qcHash, consensusData.AggregateVote) {
+			log.Log.Error("invalid aggregate vote!")
+			return
+		}
+	}
+
 	singer, _ := b.config.SingerGenerator.FromRawPublicKey(memberKey)
 	singerHexKey := singer.PublicKeyString()
 	if _, voted := b.votedMessage[singerHexKey]; voted {
@@ -188,8 +164,9 @@ func (b *basicService) verifyNewViewMessage(consensusData SignedConsensusData) (
 		return
 	}
 
-	if len(justify.Votes) > 0 {
-		passed = b.verifyQCVotes(justify)
+	//the votes of justify have been verified when the message was fed
+	if len(justify.Votes) > 0 || len(justify.AggregateSignature) > 0 {
+		passed = true
 	} else if b.currentView == 0 {
 		passed = true
 	}
@@ -213,12 +190,6 @@ func (b *basicService) verifyPrepareMessage(consensusData SignedConsensusData) (
 		return
 	}
 
-	//verify vote
-	if !b.verifyQCVotes(justify) {
-		log.Log.Error("invalid prepare's justify.")
-		return
-	}
-
 	//verify block
 	customerData, err := b.externalProcessor.CustomerDataFromConsensus(consensusData.Payload.CustomerData)
 	if err != nil {
@@ -274,11 +245,50 @@ func (b *basicService) verifyPrepareMessage(consensusData SignedConsensusData) (
 	return
 }
 
-func (b *basicService) hasEnoughVotes(voteCount int) bool {
-	memberCount := len(b.config.Members)
-	bftCount := memberCount / 3
+//the justify of every message must be a valid QC of the phase before the message's phase.
+func (b *basicService) verifyJustify(msgType string, consensusData SignedConsensusData) (passed bool) {
+	justify := consensusData.Justify
+	allPhases := consensusPhases
+
+	expectedPhase := ""
+	sameView := true
+	switch msgType {
+	case messageTypes.Vote.String():
+		//a vote carries no justify
+		return len(justify.Votes) == 0 && len(justify.AggregateSignature) == 0
+
+	case messageTypes.NewView.String(), messageTypes.Prepare.String():
+		expectedPhase = allPhases.Prepare.String()
+		sameView = false
+
+	case messageTypes.PreCommit.String():
+		expectedPhase = allPhases.Prepare.String()
+
+	case messageTypes.Commit.String():
+		expectedPhase = allPhases.PreCommit.String()
+
+	case messageTypes.Decide.String():
+		expectedPhase = allPhases.Commit.String()
+
+	default:
+		return
+	}
+
+	if justify.Phase != expectedPhase {
+		log.Log.Error("justify is not in the expected [PHASE]. [expected@", expectedPhase, " : justify@", justify.Phase, "]")
+		return
+	}
+
+	if justify.ViewNumber > consensusData.ViewNumber || (sameView && justify.ViewNumber != consensusData.ViewNumber) {
+		log.Log.Error("justify is not in the expected [VIEW]. [message@", consensusData.ViewNumber, " : justify@", justify.ViewNumber, "]")
+		return
+	}
+
+	return b.isValidQC(justify)
+}
 
-	needCount := memberCount - bftCount - 1 //exclude leader self in enough count.
+func (b *consensusBase) hasEnoughVotes(voteCount int) bool {
+	needCount := consensus.QuorumOf(len(b.config.Members)) - 1 //exclude leader self in enough count.
 
 	return needCount <= voteCount
 }
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+func (p *pbftService) makeCheckpoint(sequence uint64) {
+	p.broadcast(PBFTData{
+		Type:       messageTypes.Checkpoint.String(),
+		ViewNumber: p.currentView,
+		Sequence:   sequence,
+		Digest:     p.stateDigest,
+	})
+}
+
+func (p *pbftService) gotCheckpoint(data SignedPBFTData) (_ *message.Message) {
+	if data.Sequence <= p.lowWaterMark {
+		return
+	}
+
+	checkpoints, exists := p.checkpoints[data.Sequence]
+	if !exists {
+		checkpoints = map[string]SignedPBFTData{}
+		p.checkpoints[data.Sequence] = checkpoints
+	}
+
+	checkpoints[signerKey(data)] = data
+
+	proof := p.matchingMessages(checkpoints, data.Digest)
+	if len(proof) < p.quorum() {
+		return
+	}
+
+	p.stabilizeCheckpoint(data.Sequence, data.Digest, proof)
+	return
+}
+
+//a stable checkpoint moves the watermarks and discards the logs before it.
+func (p *pbftService) stabilizeCheckpoint(sequence uint64, digest []byte, proof []SignedPBFTData) {
+	if sequence <= p.lowWaterMark {
+		return
+	}
+
+	p.lowWaterMark = sequence
+	p.stableDigest = digest
+	p.stableProof = proof
+
+	for s := range p.logs {
+		if s <= sequence {
+			delete(p.logs, s)
+		}
+	}
+
+	for s := range p.checkpoints {
+		if s <= sequence {
+			delete(p.checkpoints, s)
+		}
+	}
+
+	//the customer data of the skipped sequences will be caught up by the block synchronization
+	if p.lastExecuted < sequence {
+		log.Log.Warn("pbft fall behind the stable checkpoint @sequence ", sequence)
+		p.lastExecuted = sequence
+		p.stateDigest = digest
+	}
+
+	if p.assignedSequence < sequence {
+		p.assignedSequence = sequence
+	}
+
+	_ = p.saveSafetyState()
+	log.Log.Println("pbft checkpoint stable @sequence ", sequence)
+	p.scheduleProposal()
+}
+
+//a checkpoint proof holds quorum distinct signatures on the same sequence and digest
+func (p *pbftService) verifyCheckpointProof(sequence uint64, digest []byte, proof []SignedPBFTData) (passed bool) {
+	if sequence == 0 {
+		return len(proof) == 0
+	}
+
+	signers := map[string]bool{}
+	for _, c := range proof {
+		if c.Type != messageTypes.Checkpoint.String() ||
+			c.Sequence != sequence ||
+			!bytes.Equal(c.Digest, digest) {
+			return
+		}
+
+		if !p.verifySignedData(c) {
+			return
+		}
+
+		signers[signerKey(c)] = true
+	}
+
+	return len(signers) >= p.quorum()
+}
+
+func (p *pbftService) registerCheckpointProcessor() {
+	p.consensusProcessor[messageTypes.Checkpoint.String()] = p.gotCheckpoint
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/crypto/signers"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"time"
+)
+
+const defaultCheckpointInterval = 16
+
+type Config struct {
+	//signer and members
+	SelfSigner signerCommon.ISigner
+
+	//member list, the primary of view v is Members[v % len(Members)]
+	Members []Member
+
+	//timers config
+	MemberOnlineCheckInterval time.Duration
+	ConsensusTimeout          time.Duration
+
+	//new sequence proposing interval
+	ConsensusInterval time.Duration
+
+	//a checkpoint is made every CheckpointInterval sequences, the log window is two checkpoint intervals
+	CheckpointInterval uint64
+
+	//safety state storage, state will not be persisted if it is nil
+	StateStore consensus.IStateStore
+
+	//crypto
+	SingerGenerator signers.ISignerGenerator
+	HashCalc        hashes.IHashCalculator
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"github.com/SealSC/SealABC/network"
+)
+
+type Member struct {
+	Signer   signerCommon.ISigner
+	FromNode network.Node
+	online   bool
+}
+
+func (p *pbftService) isMemberKey(memberKey []byte) bool {
+	for _, m := range p.config.Members {
+		if m.Signer.PublicKeyCompare(memberKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *pbftService) isSelf(m Member) bool {
+	return p.config.SelfSigner.PublicKeyCompare(m.Signer.PublicKeyBytes())
+}
+
+//refresh members' online state from the linked nodes, returns the count of online members exclude self.
+func (p *pbftService) refreshMembers() (onlineCount int) {
+	allNodes := p.network.GetAllLinkedNode()
+
+	linkedNodes := map[string]network.Node{}
+	for _, n := range allNodes {
+		linkedNodes[n.ID] = n
+	}
+
+	for idx, m := range p.config.Members {
+		if p.isSelf(m) {
+			p.config.Members[idx].online = true
+			continue
+		}
+
+		n, linked := linkedNodes[m.Signer.PublicKeyString()]
+		p.config.Members[idx].online = linked
+		if !linked {
+			continue
+		}
+
+		p.config.Members[idx].FromNode = n
+		onlineCount += 1
+	}
+
+	return
+}
+
+//the count of faulty members the consensus could tolerate
+func (p *pbftService) faultTolerance() int {
+	return (len(p.config.Members) - 1) / 3
+}
+
+//any two quorums intersect in at least one honest member
+func (p *pbftService) quorum() int {
+	return len(p.config.Members) - p.faultTolerance()
+}
+
+func (p *pbftService) isQuorumOnline() bool {
+	return p.refreshMembers()+1 >= p.quorum()
+}
+
+//unlike hot-stuff, the primary never skips an offline member, a dead primary is replaced by view change.
+func (p *pbftService) primaryOfView(viewNumber uint64) (primary Member) {
+	memberCount := uint64(len(p.config.Members))
+	return p.config.Members[viewNumber%memberCount]
+}
+
+func (p *pbftService) isPrimary(viewNumber uint64, key []byte) bool {
+	primary := p.primaryOfView(viewNumber)
+	return bytes.Equal(key, primary.Signer.PublicKeyBytes())
+}
+
+func (p *pbftService) isSelfPrimary(viewNumber uint64) bool {
+	return p.isSelf(p.primaryOfView(viewNumber))
+}
+
+func (p *pbftService) allMembersKey() (keys []string) {
+	for _, m := range p.config.Members {
+		keys = append(keys, m.Signer.PublicKeyString())
+	}
+
+	return
+}
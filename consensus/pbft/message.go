@@ -0,0 +1,117 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+const MessageFamily = "pbft-consensus"
+const MessageVersion = "pbft.0.0.1"
+
+func signerKey(data SignedPBFTData) string {
+	return hex.EncodeToString(data.Seal.SignerPublicKey)
+}
+
+func (p *pbftService) digestOf(payload []byte) []byte {
+	return p.config.HashCalc.Sum(payload)
+}
+
+func (p *pbftService) signData(data PBFTData) (signedData SignedPBFTData, err error) {
+	dataBytes, err := structSerializer.ToMFBytes(data)
+	if err != nil {
+		log.Log.Error("serialize pbft data failed")
+		return
+	}
+
+	signedData.PBFTData = data
+	signedData.Seal.Hash = p.config.HashCalc.Sum(dataBytes)
+	signedData.Seal.SignerPublicKey = p.config.SelfSigner.PublicKeyBytes()
+	signedData.Seal.Signature, err = p.config.SelfSigner.Sign(signedData.Seal.Hash)
+	return
+}
+
+//the signed data must be signed by a member, it is used for both the message and the proofs carried by it
+func (p *pbftService) verifySignedData(data SignedPBFTData) (passed bool) {
+	if !p.isMemberKey(data.Seal.SignerPublicKey) {
+		return
+	}
+
+	dataBytes, err := structSerializer.ToMFBytes(data.PBFTData)
+	if err != nil {
+		log.Log.Error("serialize pbft data failed")
+		return
+	}
+
+	hash := p.config.HashCalc.Sum(dataBytes)
+	if !bytes.Equal(hash, data.Seal.Hash) {
+		return
+	}
+
+	signer, err := p.config.SingerGenerator.FromRawPublicKey(data.Seal.SignerPublicKey)
+	if err != nil {
+		return
+	}
+
+	passed, _ = signer.Verify(hash, data.Seal.Signature)
+	return
+}
+
+func (p *pbftService) buildMessage(signedData SignedPBFTData) (msg message.Message, err error) {
+	msgPayload, err := json.Marshal(signedData)
+	if err != nil {
+		log.Log.Error("pbft data marshal to json failed.")
+		return
+	}
+
+	msg.Family = MessageFamily
+	msg.Version = MessageVersion
+	msg.Type = signedData.Type
+	msg.Payload = msgPayload
+	return
+}
+
+//all pbft messages are sent to every member, the message is handled locally too because the network
+//will not deliver it to self. the state is saved before sending, so a restarted replica never contradicts it.
+func (p *pbftService) broadcast(data PBFTData) {
+	signedData, err := p.signData(data)
+	if err != nil {
+		log.Log.Error("sign pbft data failed: ", err.Error())
+		return
+	}
+
+	msg, err := p.buildMessage(signedData)
+	if err != nil {
+		return
+	}
+
+	if p.saveSafetyState() != nil {
+		return
+	}
+
+	go p.network.Broadcast(msg)
+
+	if handle, exists := p.consensusProcessor[data.Type]; exists {
+		handle(signedData)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/metadata/seal"
+)
+
+type messageType struct {
+	PrePrepare enum.Element
+	Prepare    enum.Element
+	Commit     enum.Element
+	Checkpoint enum.Element
+	ViewChange enum.Element
+	NewView    enum.Element
+}
+
+var messageTypes messageType
+
+//the content of all pbft messages, the meaning of the fields depends on the type:
+//pre-prepare carries the customer data in payload, checkpoint carries the state digest,
+//view change carries its stable checkpoint in sequence and digest, and the proofs in payload.
+type PBFTData struct {
+	Type       string
+	ViewNumber uint64
+	Sequence   uint64
+	Digest     []byte
+	Payload    []byte
+}
+
+type SignedPBFTData struct {
+	PBFTData
+	Seal seal.Entity
+}
+
+//a pre-prepare and the matching prepares from 2f distinct backups
+type PreparedCertificate struct {
+	PrePrepare SignedPBFTData
+	Prepares   []SignedPBFTData
+}
+
+type ViewChangeProof struct {
+	Checkpoints []SignedPBFTData
+	Prepared    []PreparedCertificate
+}
+
+type NewViewProof struct {
+	ViewChanges []SignedPBFTData
+	PrePrepares []SignedPBFTData
+}
+
+type sequenceLog struct {
+	view       uint64
+	prePrepare *SignedPBFTData
+	prepares   map[string]SignedPBFTData
+	commits    map[string]SignedPBFTData
+	committed  bool
+
+	//the certificate survives the view change, it is what the view change message reports
+	certificate *PreparedCertificate
+}
+
+func newSequenceLog(view uint64) *sequenceLog {
+	return &sequenceLog{
+		view:     view,
+		prepares: map[string]SignedPBFTData{},
+		commits:  map[string]SignedPBFTData{},
+	}
+}
+
+func (l *sequenceLog) isPrepared() bool {
+	return l.certificate != nil && l.certificate.PrePrepare.ViewNumber == l.view
+}
@@ -0,0 +1,322 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"time"
+)
+
+func (p *pbftService) highWaterMark() uint64 {
+	return p.lowWaterMark + 2*p.config.CheckpointInterval
+}
+
+func (p *pbftService) inWatermarks(sequence uint64) bool {
+	return sequence > p.lowWaterMark && sequence <= p.highWaterMark()
+}
+
+//the log of an older view is replaced once a message of a newer view comes, messages of older views are dropped.
+func (p *pbftService) getLog(sequence uint64, view uint64) (l *sequenceLog) {
+	l, exists := p.logs[sequence]
+	if exists && l.view > view {
+		return nil
+	}
+
+	if !exists || l.view < view {
+		newLog := newSequenceLog(view)
+		if exists {
+			newLog.certificate = l.certificate
+			newLog.committed = l.committed
+		}
+
+		l = newLog
+		p.logs[sequence] = l
+	}
+
+	return
+}
+
+func (p *pbftService) isNormalCaseMessage(data SignedPBFTData) bool {
+	if p.viewChanging || data.ViewNumber != p.currentView {
+		return false
+	}
+
+	return p.inWatermarks(data.Sequence)
+}
+
+func (p *pbftService) verifyCustomerData(data []byte) (passed bool) {
+	if len(data) == 0 {
+		return true
+	}
+
+	customerData, err := p.externalProcessor.CustomerDataFromConsensus(data)
+	if err != nil {
+		log.Log.Error("get customer data interface from message failed.")
+		return
+	}
+
+	passed, err = customerData.Verify()
+	if !passed {
+		log.Log.Error("customer data verify failed: ", err)
+	}
+	return
+}
+
+//customer data is built on the last executed data, so the primary keeps only one sequence in flight.
+func (p *pbftService) scheduleProposal() {
+	if p.viewChanging || p.proposing || !p.isSelfPrimary(p.currentView) {
+		return
+	}
+
+	if p.assignedSequence > p.lastExecuted {
+		return
+	}
+
+	p.proposing = true
+	viewNumber := p.currentView
+	go func() {
+		time.Sleep(p.config.ConsensusInterval)
+
+		p.stateLock.Lock()
+		defer p.stateLock.Unlock()
+
+		p.proposing = false
+		if p.isStopped() || p.viewChanging || p.currentView != viewNumber {
+			return
+		}
+
+		p.propose()
+	}()
+}
+
+func (p *pbftService) propose() {
+	sequence := p.lastExecuted + 1
+	if p.assignedSequence >= sequence {
+		return
+	}
+
+	//wait for the checkpoint to be stable, the proposal will be scheduled again after that
+	if !p.inWatermarks(sequence) {
+		return
+	}
+
+	customerData, err := p.externalProcessor.CustomerDataToConsensus()
+	if err != nil {
+		log.Log.Error("get customer data failed.")
+		return
+	}
+
+	payload, err := customerData.Bytes()
+	if err != nil {
+		log.Log.Error("customer data builder returns an error: ", err)
+		return
+	}
+
+	//the sequence is assigned before the pre-prepare is saved and sent, it's never assigned again in this view
+	p.assignedSequence = sequence
+	p.broadcast(PBFTData{
+		Type:       messageTypes.PrePrepare.String(),
+		ViewNumber: p.currentView,
+		Sequence:   sequence,
+		Digest:     p.digestOf(payload),
+		Payload:    payload,
+	})
+}
+
+func (p *pbftService) gotPrePrepare(data SignedPBFTData) (_ *message.Message) {
+	if !p.isNormalCaseMessage(data) {
+		return
+	}
+
+	if !p.isPrimary(data.ViewNumber, data.Seal.SignerPublicKey) {
+		log.Log.Error("pre-prepare is not from the primary @view ", data.ViewNumber)
+		return
+	}
+
+	p.acceptPrePrepare(data)
+	return
+}
+
+//accept a pre-prepare of the current view, from the primary or re-issued by the new view message.
+func (p *pbftService) acceptPrePrepare(data SignedPBFTData) {
+	if !bytes.Equal(data.Digest, p.digestOf(data.Payload)) {
+		log.Log.Error("pre-prepare digest not match its payload")
+		return
+	}
+
+	l := p.getLog(data.Sequence, data.ViewNumber)
+	if l == nil {
+		return
+	}
+
+	if l.prePrepare != nil {
+		if !bytes.Equal(l.prePrepare.Digest, data.Digest) {
+			log.Log.Error("conflicting pre-prepare @sequence ", data.Sequence)
+		}
+		return
+	}
+
+	//an executed sequence is re-issued by the new view only for the others to catch up
+	if data.Sequence > p.lastExecuted && !p.verifyCustomerData(data.Payload) {
+		return
+	}
+
+	l.prePrepare = &data
+	if data.Sequence > p.assignedSequence {
+		p.assignedSequence = data.Sequence
+	}
+
+	if !p.isSelfPrimary(data.ViewNumber) {
+		p.broadcast(PBFTData{
+			Type:       messageTypes.Prepare.String(),
+			ViewNumber: data.ViewNumber,
+			Sequence:   data.Sequence,
+			Digest:     data.Digest,
+		})
+	}
+
+	p.checkPrepared(l)
+}
+
+func (p *pbftService) gotPrepare(data SignedPBFTData) (_ *message.Message) {
+	if !p.isNormalCaseMessage(data) {
+		return
+	}
+
+	//the pre-prepare is the primary's prepare
+	if p.isPrimary(data.ViewNumber, data.Seal.SignerPublicKey) {
+		return
+	}
+
+	l := p.getLog(data.Sequence, data.ViewNumber)
+	if l == nil {
+		return
+	}
+
+	l.prepares[signerKey(data)] = data
+	p.checkPrepared(l)
+	return
+}
+
+func (p *pbftService) matchingMessages(messages map[string]SignedPBFTData, digest []byte) (matched []SignedPBFTData) {
+	for _, m := range messages {
+		if bytes.Equal(m.Digest, digest) {
+			matched = append(matched, m)
+		}
+	}
+
+	return
+}
+
+func (p *pbftService) checkPrepared(l *sequenceLog) {
+	if l.prePrepare == nil || l.isPrepared() {
+		return
+	}
+
+	prepares := p.matchingMessages(l.prepares, l.prePrepare.Digest)
+	if len(prepares) < p.quorum()-1 {
+		return
+	}
+
+	l.certificate = &PreparedCertificate{
+		PrePrepare: *l.prePrepare,
+		Prepares:   prepares,
+	}
+
+	p.broadcast(PBFTData{
+		Type:       messageTypes.Commit.String(),
+		ViewNumber: l.view,
+		Sequence:   l.prePrepare.Sequence,
+		Digest:     l.prePrepare.Digest,
+	})
+}
+
+func (p *pbftService) gotCommit(data SignedPBFTData) (_ *message.Message) {
+	if !p.isNormalCaseMessage(data) {
+		return
+	}
+
+	l := p.getLog(data.Sequence, data.ViewNumber)
+	if l == nil {
+		return
+	}
+
+	l.commits[signerKey(data)] = data
+	p.checkCommitted(l)
+	return
+}
+
+func (p *pbftService) checkCommitted(l *sequenceLog) {
+	if l.committed || !l.isPrepared() {
+		return
+	}
+
+	commits := p.matchingMessages(l.commits, l.prePrepare.Digest)
+	if len(commits) < p.quorum() {
+		return
+	}
+
+	l.committed = true
+	p.execute()
+}
+
+//execute the committed sequences in order, the state digest chains the digests of all executed sequences.
+func (p *pbftService) execute() {
+	executed := false
+	for {
+		l, exists := p.logs[p.lastExecuted+1]
+		if !exists || !l.committed || l.prePrepare == nil {
+			break
+		}
+
+		sequence := p.lastExecuted + 1
+		customerData := l.prePrepare.Payload
+		if len(customerData) > 0 {
+			log.Log.Println("pbft commit @sequence ", sequence, " @view ", l.view)
+			if p.externalProcessor != nil {
+				p.externalProcessor.EventProcessor(consensus.Event.Success, customerData, nil)
+			}
+		}
+
+		digestSource := append([]byte{}, p.stateDigest...)
+		p.stateDigest = p.digestOf(append(digestSource, l.prePrepare.Digest...))
+		p.lastExecuted = sequence
+		executed = true
+
+		if sequence%p.config.CheckpointInterval == 0 {
+			p.makeCheckpoint(sequence)
+		}
+	}
+
+	if !executed {
+		return
+	}
+
+	_ = p.saveSafetyState()
+	p.viewTimer.Reset(p.config.ConsensusTimeout)
+	p.scheduleProposal()
+}
+
+func (p *pbftService) registerNormalCaseProcessor() {
+	p.consensusProcessor[messageTypes.PrePrepare.String()] = p.gotPrePrepare
+	p.consensusProcessor[messageTypes.Prepare.String()] = p.gotPrepare
+	p.consensusProcessor[messageTypes.Commit.String()] = p.gotCommit
+}
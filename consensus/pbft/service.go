@@ -0,0 +1,265 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/network"
+	"sync"
+	"time"
+)
+
+type consensusProcessor func(data SignedPBFTData) (reply *message.Message)
+
+type pbftInformation struct {
+	Network            network.StaticInformation
+	Members            []string
+	ConsensusInterval  time.Duration
+	ConsensusTimeout   time.Duration
+	CheckpointInterval uint64
+	CurrentView        uint64
+	LastExecuted       uint64
+	StableCheckpoint   uint64
+}
+
+type pbftService struct {
+	config  Config
+	network network.IService
+
+	currentState enum.Element
+	stateLock    sync.Mutex
+
+	currentView  uint64
+	viewChanging bool
+	viewChangeTo uint64
+
+	lowWaterMark     uint64
+	stableDigest     []byte
+	stableProof      []SignedPBFTData
+	lastExecuted     uint64
+	assignedSequence uint64
+	stateDigest      []byte
+	proposing        bool
+
+	logs        map[uint64]*sequenceLog
+	checkpoints map[uint64]map[string]SignedPBFTData
+	viewChanges map[uint64]map[string]SignedPBFTData
+
+	viewTimer  *time.Timer
+	stopSignal chan struct{}
+
+	consensusProcessor map[string]consensusProcessor
+	externalProcessor  consensus.ExternalProcessor
+}
+
+var PBFT pbftService
+
+func (p *pbftService) isStopped() bool {
+	return p.currentState.String() == consensus.States.Stopped.String()
+}
+
+func (p *pbftService) startViewChangeMonitor() {
+	log.Log.Println("start pbft view change monitor : ", p.config.ConsensusTimeout)
+
+	for {
+		select {
+		case <-p.viewTimer.C:
+			p.viewTimeout()
+
+		case <-p.stopSignal:
+			log.Log.Println("pbft view change monitor stopped")
+			return
+		}
+	}
+}
+
+//consensus starts once a quorum of members is online, a primary that is not online will be replaced by view change.
+func (p *pbftService) initService() {
+	onlineCheck := time.NewTimer(p.config.MemberOnlineCheckInterval)
+	for {
+		select {
+		case <-onlineCheck.C:
+		case <-p.stopSignal:
+			return
+		}
+
+		if p.isQuorumOnline() {
+			log.Log.Println("consensus quorum online now!")
+			break
+		}
+
+		onlineCheck.Reset(p.config.MemberOnlineCheckInterval)
+	}
+
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.isStopped() {
+		return
+	}
+
+	p.currentState = consensus.States.Running
+	p.viewTimer.Reset(p.config.ConsensusTimeout)
+	go p.startViewChangeMonitor()
+
+	p.scheduleProposal()
+}
+
+func (p *pbftService) Feed(msg message.Message) (reply *message.Message) {
+	if msg.Family != MessageFamily {
+		return
+	}
+
+	data := SignedPBFTData{}
+	err := json.Unmarshal(msg.Payload, &data)
+	if err != nil {
+		log.Log.Error("invalid pbft data")
+		return
+	}
+
+	if data.Type != msg.Type {
+		log.Log.Error("pbft data type not match the message type")
+		return
+	}
+
+	if !p.verifySignedData(data) {
+		log.Log.Error("invalid pbft message signature")
+		return
+	}
+
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.isStopped() {
+		return
+	}
+
+	if handle, exists := p.consensusProcessor[msg.Type]; exists {
+		reply = handle(data)
+	}
+	return
+}
+
+func (p *pbftService) Start(cfg interface{}) (err error) {
+	config, ok := cfg.(Config)
+	if !ok {
+		err = errors.New("invalid config")
+		return
+	}
+
+	if config.CheckpointInterval == 0 {
+		config.CheckpointInterval = defaultCheckpointInterval
+	}
+
+	p.config = config
+	p.currentState = consensus.States.Init
+	p.loadSafetyState()
+	p.stopSignal = make(chan struct{})
+
+	go p.initService()
+	return
+}
+
+//stop the timers and goroutines of consensus, the in-flight message will be finished before stop.
+func (p *pbftService) Stop() (err error) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.isStopped() || p.stopSignal == nil {
+		return
+	}
+
+	p.currentState = consensus.States.Stopped
+	p.viewTimer.Stop()
+	close(p.stopSignal)
+
+	err = p.saveSafetyState()
+	log.Log.Println("pbft consensus stopped @view ", p.currentView)
+	return
+}
+
+func (p *pbftService) RegisterExternalProcessor(processor consensus.ExternalProcessor) {
+	p.externalProcessor = processor
+}
+
+func (p *pbftService) GetMessageFamily() (family string) {
+	return MessageFamily
+}
+
+func (p *pbftService) GetExternalProcessor() (processor consensus.ExternalProcessor) {
+	return p.externalProcessor
+}
+
+func (p *pbftService) GetConsensusCustomerData(msg message.Message) (data []byte, err error) {
+	pbftData := SignedPBFTData{}
+	err = json.Unmarshal(msg.Payload, &pbftData)
+	if err != nil {
+		return
+	}
+
+	if pbftData.Type != messageTypes.PrePrepare.String() {
+		err = errors.New("no customer data in pbft message: " + pbftData.Type)
+		return
+	}
+
+	data = pbftData.Payload
+	return
+}
+
+func (p *pbftService) Load(networkService network.IService, processor consensus.ExternalProcessor) {
+	enum.Build(&messageTypes, 0, "pbft-")
+
+	p.viewTimer = time.NewTimer(p.config.ConsensusTimeout)
+	p.viewTimer.Stop()
+
+	p.network = networkService
+
+	p.logs = map[uint64]*sequenceLog{}
+	p.checkpoints = map[uint64]map[string]SignedPBFTData{}
+	p.viewChanges = map[uint64]map[string]SignedPBFTData{}
+	p.consensusProcessor = map[string]consensusProcessor{}
+
+	p.externalProcessor = processor
+
+	p.registerNormalCaseProcessor()
+	p.registerCheckpointProcessor()
+	p.registerViewChangeProcessor()
+}
+
+func (p *pbftService) StaticInformation() interface{} {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	info := pbftInformation{}
+
+	info.Network = p.network.StaticInformation()
+	info.Members = p.allMembersKey()
+	info.ConsensusInterval = p.config.ConsensusInterval
+	info.ConsensusTimeout = p.config.ConsensusTimeout
+	info.CheckpointInterval = p.config.CheckpointInterval
+	info.CurrentView = p.currentView
+	info.LastExecuted = p.lastExecuted
+	info.StableCheckpoint = p.lowWaterMark
+
+	return info
+}
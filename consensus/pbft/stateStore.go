@@ -0,0 +1,128 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"github.com/SealSC/SealABC/log"
+)
+
+const safetyStateKey = "pbftSafetyState"
+
+//the log of a sequence a replica must not forget: the pre-prepare it accepted and the certificate it prepared
+type SequenceState struct {
+	Sequence    uint64
+	View        uint64
+	PrePrepare  *SignedPBFTData
+	Certificate *PreparedCertificate
+	Committed   bool
+}
+
+//the values a replica must not forget after a restart, otherwise it may come back at an old view and prepare
+//a value conflicting with the one it prepared, or the primary may assign a sequence again.
+type SafetyState struct {
+	CurrentView  uint64
+	ViewChanging bool
+	ViewChangeTo uint64
+
+	LowWaterMark     uint64
+	StableDigest     []byte
+	StableProof      []SignedPBFTData
+	LastExecuted     uint64
+	AssignedSequence uint64
+	StateDigest      []byte
+
+	Sequences []SequenceState
+}
+
+func (p *pbftService) saveSafetyState() (err error) {
+	if p.config.StateStore == nil {
+		return
+	}
+
+	state := SafetyState{
+		CurrentView:  p.currentView,
+		ViewChanging: p.viewChanging,
+		ViewChangeTo: p.viewChangeTo,
+
+		LowWaterMark:     p.lowWaterMark,
+		StableDigest:     p.stableDigest,
+		StableProof:      p.stableProof,
+		LastExecuted:     p.lastExecuted,
+		AssignedSequence: p.assignedSequence,
+		StateDigest:      p.stateDigest,
+	}
+
+	for sequence, l := range p.logs {
+		if l.prePrepare == nil && l.certificate == nil {
+			continue
+		}
+
+		state.Sequences = append(state.Sequences, SequenceState{
+			Sequence:    sequence,
+			View:        l.view,
+			PrePrepare:  l.prePrepare,
+			Certificate: l.certificate,
+			Committed:   l.committed,
+		})
+	}
+
+	err = p.config.StateStore.Save(safetyStateKey, state)
+	if err != nil {
+		log.Log.Error("save pbft safety state failed: ", err.Error())
+	}
+	return
+}
+
+func (p *pbftService) loadSafetyState() {
+	if p.config.StateStore == nil {
+		return
+	}
+
+	state := SafetyState{}
+	exists, err := p.config.StateStore.Load(safetyStateKey, &state)
+	if err != nil {
+		log.Log.Error("load pbft safety state failed: ", err.Error())
+		return
+	}
+
+	if !exists {
+		return
+	}
+
+	p.currentView = state.CurrentView
+	p.viewChanging = state.ViewChanging
+	p.viewChangeTo = state.ViewChangeTo
+
+	p.lowWaterMark = state.LowWaterMark
+	p.stableDigest = state.StableDigest
+	p.stableProof = state.StableProof
+	p.lastExecuted = state.LastExecuted
+	p.assignedSequence = state.AssignedSequence
+	p.stateDigest = state.StateDigest
+
+	p.logs = map[uint64]*sequenceLog{}
+	for _, s := range state.Sequences {
+		l := newSequenceLog(s.View)
+		l.prePrepare = s.PrePrepare
+		l.certificate = s.Certificate
+		l.committed = s.Committed
+		p.logs[s.Sequence] = l
+	}
+
+	log.Log.Println("pbft safety state recovered @view ", p.currentView, " @sequence ", p.lastExecuted)
+}
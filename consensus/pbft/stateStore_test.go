@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/storage/db/dbDrivers/levelDB"
+)
+
+func newTestStateStore(t *testing.T) consensus.IStateStore {
+	log.SetUpLogger(log.Config{})
+	driver, err := levelDB.NewDriver(levelDB.Config{DBFilePath: filepath.Join(t.TempDir(), "pbft")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return consensus.NewKVStateStore(driver)
+}
+
+//a restarted replica comes back at its view and sequence, and keeps the pre-prepare and the certificate it prepared
+func TestSafetyStateRecovered(t *testing.T) {
+	store := newTestStateStore(t)
+
+	prePrepare := SignedPBFTData{}
+	prePrepare.ViewNumber = 3
+	prePrepare.Sequence = 8
+	prePrepare.Digest = []byte("prepared")
+
+	accepted := SignedPBFTData{}
+	accepted.ViewNumber = 3
+	accepted.Sequence = 9
+	accepted.Digest = []byte("accepted")
+
+	p := &pbftService{config: Config{StateStore: store}}
+	p.currentView = 3
+	p.lastExecuted = 7
+	p.assignedSequence = 9
+	p.logs = map[uint64]*sequenceLog{
+		8: newSequenceLog(3),
+		9: newSequenceLog(3),
+	}
+	p.logs[8].prePrepare = &prePrepare
+	p.logs[8].certificate = &PreparedCertificate{PrePrepare: prePrepare}
+	p.logs[9].prePrepare = &accepted
+
+	if err := p.saveSafetyState(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := &pbftService{config: Config{StateStore: store}}
+	restarted.logs = map[uint64]*sequenceLog{}
+	restarted.loadSafetyState()
+
+	if restarted.currentView != 3 || restarted.lastExecuted != 7 || restarted.assignedSequence != 9 {
+		t.Fatal("view or sequence not recovered")
+	}
+
+	prepared := restarted.getLog(8, 3)
+	if !prepared.isPrepared() || !bytes.Equal(prepared.certificate.PrePrepare.Digest, prePrepare.Digest) {
+		t.Fatal("prepared certificate not recovered")
+	}
+
+	//a conflicting pre-prepare of the same view is refused by the accepted one
+	l := restarted.getLog(9, 3)
+	if l.prePrepare == nil || !bytes.Equal(l.prePrepare.Digest, accepted.Digest) {
+		t.Fatal("accepted pre-prepare not recovered")
+	}
+}
@@ -0,0 +1,380 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pbft
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+func (p *pbftService) viewTimeout() {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	if p.isStopped() {
+		return
+	}
+
+	//the view change itself timed out, try the next view
+	newView := p.currentView + 1
+	if p.viewChanging {
+		newView = p.viewChangeTo + 1
+	}
+
+	p.startViewChange(newView)
+}
+
+func (p *pbftService) preparedCertificates() (certificates []PreparedCertificate) {
+	for sequence, l := range p.logs {
+		if sequence <= p.lowWaterMark || l.certificate == nil {
+			continue
+		}
+
+		certificates = append(certificates, *l.certificate)
+	}
+
+	return
+}
+
+func (p *pbftService) startViewChange(newView uint64) {
+	p.viewChanging = true
+	p.viewChangeTo = newView
+	p.viewTimer.Reset(p.config.ConsensusTimeout)
+
+	log.Log.Println("pbft view change to ", newView)
+
+	proof, err := json.Marshal(ViewChangeProof{
+		Checkpoints: p.stableProof,
+		Prepared:    p.preparedCertificates(),
+	})
+	if err != nil {
+		log.Log.Error("view change proof marshal to json failed.")
+		return
+	}
+
+	p.broadcast(PBFTData{
+		Type:       messageTypes.ViewChange.String(),
+		ViewNumber: newView,
+		Sequence:   p.lowWaterMark,
+		Digest:     p.stableDigest,
+		Payload:    proof,
+	})
+}
+
+func (p *pbftService) verifyPreparedCertificate(certificate PreparedCertificate, stableSequence uint64, newView uint64) (passed bool) {
+	prePrepare := certificate.PrePrepare
+	if prePrepare.Type != messageTypes.PrePrepare.String() ||
+		prePrepare.ViewNumber >= newView ||
+		prePrepare.Sequence <= stableSequence {
+		return
+	}
+
+	if !p.isPrimary(prePrepare.ViewNumber, prePrepare.Seal.SignerPublicKey) ||
+		!bytes.Equal(prePrepare.Digest, p.digestOf(prePrepare.Payload)) ||
+		!p.verifySignedData(prePrepare) {
+		return
+	}
+
+	signers := map[string]bool{}
+	for _, prepare := range certificate.Prepares {
+		if prepare.Type != messageTypes.Prepare.String() ||
+			prepare.ViewNumber != prePrepare.ViewNumber ||
+			prepare.Sequence != prePrepare.Sequence ||
+			!bytes.Equal(prepare.Digest, prePrepare.Digest) {
+			return
+		}
+
+		if p.isPrimary(prepare.ViewNumber, prepare.Seal.SignerPublicKey) || !p.verifySignedData(prepare) {
+			return
+		}
+
+		signers[signerKey(prepare)] = true
+	}
+
+	return len(signers) >= p.quorum()-1
+}
+
+func (p *pbftService) viewChangeProof(data SignedPBFTData) (proof ViewChangeProof, passed bool) {
+	if data.Type != messageTypes.ViewChange.String() {
+		return
+	}
+
+	err := json.Unmarshal(data.Payload, &proof)
+	if err != nil {
+		log.Log.Error("invalid view change proof")
+		return
+	}
+
+	if !p.verifyCheckpointProof(data.Sequence, data.Digest, proof.Checkpoints) {
+		log.Log.Error("invalid checkpoint proof in view change")
+		return
+	}
+
+	for _, certificate := range proof.Prepared {
+		if !p.verifyPreparedCertificate(certificate, data.Sequence, data.ViewNumber) {
+			log.Log.Error("invalid prepared certificate in view change")
+			return
+		}
+	}
+
+	passed = true
+	return
+}
+
+func (p *pbftService) gotViewChange(data SignedPBFTData) (_ *message.Message) {
+	newView := data.ViewNumber
+	if newView <= p.currentView {
+		return
+	}
+
+	if _, passed := p.viewChangeProof(data); !passed {
+		return
+	}
+
+	viewChanges, exists := p.viewChanges[newView]
+	if !exists {
+		viewChanges = map[string]SignedPBFTData{}
+		p.viewChanges[newView] = viewChanges
+	}
+	viewChanges[signerKey(data)] = data
+
+	//join the view change once f+1 members asked for it, at least one of them is honest
+	if (!p.viewChanging || newView > p.viewChangeTo) && len(viewChanges) > p.faultTolerance() {
+		p.startViewChange(newView)
+	}
+
+	if p.viewChanging && p.viewChangeTo == newView && p.isSelfPrimary(newView) && len(viewChanges) >= p.quorum() {
+		p.sendNewView(newView, viewChanges)
+	}
+	return
+}
+
+//compute the pre-prepares of the new view from the view changes: the stable checkpoint is the highest one,
+//every sequence after it is re-issued with the digest prepared in the highest view, or as a null request.
+func (p *pbftService) newViewPrePrepares(newView uint64, viewChanges []SignedPBFTData) (stable SignedPBFTData, prePrepares []PBFTData) {
+	prepared := map[uint64]SignedPBFTData{}
+	maxSequence := uint64(0)
+	for _, vc := range viewChanges {
+		if vc.Sequence >= stable.Sequence {
+			stable = vc
+		}
+
+		proof := ViewChangeProof{}
+		_ = json.Unmarshal(vc.Payload, &proof)
+		for _, certificate := range proof.Prepared {
+			prePrepare := certificate.PrePrepare
+			if existing, exists := prepared[prePrepare.Sequence]; !exists || existing.ViewNumber < prePrepare.ViewNumber {
+				prepared[prePrepare.Sequence] = prePrepare
+			}
+
+			if prePrepare.Sequence > maxSequence {
+				maxSequence = prePrepare.Sequence
+			}
+		}
+	}
+
+	for sequence := stable.Sequence + 1; sequence <= maxSequence; sequence++ {
+		prePrepare := PBFTData{
+			Type:       messageTypes.PrePrepare.String(),
+			ViewNumber: newView,
+			Sequence:   sequence,
+			Digest:     p.digestOf(nil),
+		}
+
+		if preparedData, exists := prepared[sequence]; exists {
+			prePrepare.Digest = preparedData.Digest
+			prePrepare.Payload = preparedData.Payload
+		}
+
+		prePrepares = append(prePrepares, prePrepare)
+	}
+
+	return
+}
+
+func (p *pbftService) sendNewView(newView uint64, viewChanges map[string]SignedPBFTData) {
+	proof := NewViewProof{}
+	for _, vc := range viewChanges {
+		proof.ViewChanges = append(proof.ViewChanges, vc)
+	}
+
+	_, prePrepares := p.newViewPrePrepares(newView, proof.ViewChanges)
+	for _, prePrepare := range prePrepares {
+		signedPrePrepare, err := p.signData(prePrepare)
+		if err != nil {
+			log.Log.Error("sign pre-prepare for new view failed: ", err.Error())
+			return
+		}
+
+		proof.PrePrepares = append(proof.PrePrepares, signedPrePrepare)
+	}
+
+	proofBytes, err := json.Marshal(proof)
+	if err != nil {
+		log.Log.Error("new view proof marshal to json failed.")
+		return
+	}
+
+	p.broadcast(PBFTData{
+		Type:       messageTypes.NewView.String(),
+		ViewNumber: newView,
+		Payload:    proofBytes,
+	})
+}
+
+func (p *pbftService) verifyNewView(data SignedPBFTData, proof NewViewProof) (stable SignedPBFTData, passed bool) {
+	signers := map[string]bool{}
+	for _, vc := range proof.ViewChanges {
+		if vc.ViewNumber != data.ViewNumber || !p.verifySignedData(vc) {
+			return
+		}
+
+		if _, valid := p.viewChangeProof(vc); !valid {
+			return
+		}
+
+		signers[signerKey(vc)] = true
+	}
+
+	if len(signers) < p.quorum() {
+		log.Log.Error("not enough view changes in new view")
+		return
+	}
+
+	stable, expected := p.newViewPrePrepares(data.ViewNumber, proof.ViewChanges)
+	if len(expected) != len(proof.PrePrepares) {
+		log.Log.Error("new view pre-prepares not match the view changes")
+		return
+	}
+
+	for i, prePrepare := range proof.PrePrepares {
+		if prePrepare.Type != expected[i].Type ||
+			prePrepare.ViewNumber != expected[i].ViewNumber ||
+			prePrepare.Sequence != expected[i].Sequence ||
+			!bytes.Equal(prePrepare.Digest, expected[i].Digest) {
+			log.Log.Error("new view pre-prepares not match the view changes")
+			return
+		}
+
+		if !bytes.Equal(prePrepare.Seal.SignerPublicKey, data.Seal.SignerPublicKey) || !p.verifySignedData(prePrepare) {
+			return
+		}
+	}
+
+	passed = true
+	return
+}
+
+func (p *pbftService) gotNewView(data SignedPBFTData) (_ *message.Message) {
+	newView := data.ViewNumber
+	if newView <= p.currentView {
+		return
+	}
+
+	if !p.isPrimary(newView, data.Seal.SignerPublicKey) {
+		log.Log.Error("new view is not from the primary @view ", newView)
+		return
+	}
+
+	proof := NewViewProof{}
+	err := json.Unmarshal(data.Payload, &proof)
+	if err != nil {
+		log.Log.Error("invalid new view proof")
+		return
+	}
+
+	stable, passed := p.verifyNewView(data, proof)
+	if !passed {
+		log.Log.Error("not a valid new view.")
+		return
+	}
+
+	p.enterNewView(newView, stable, proof.PrePrepares)
+	return
+}
+
+//the pre-prepares accepted in the old views but not executed yet
+func (p *pbftService) pendingPrePrepares(newView uint64) (pending map[uint64]SignedPBFTData) {
+	pending = map[uint64]SignedPBFTData{}
+	for sequence, l := range p.logs {
+		if sequence <= p.lastExecuted || l.committed || l.prePrepare == nil || l.view >= newView {
+			continue
+		}
+
+		pending[sequence] = *l.prePrepare
+	}
+
+	return
+}
+
+//the pending pre-prepares not re-issued by the new view will never be executed, their customer data is
+//returned to the external processor as failed.
+func (p *pbftService) cancelDroppedPrePrepares(pending map[uint64]SignedPBFTData) {
+	for sequence, prePrepare := range pending {
+		l := p.logs[sequence]
+		if l != nil && l.prePrepare != nil && bytes.Equal(l.prePrepare.Digest, prePrepare.Digest) {
+			continue
+		}
+
+		if len(prePrepare.Payload) == 0 || p.externalProcessor == nil {
+			continue
+		}
+
+		log.Log.Warn("pbft pre-prepare @sequence ", sequence, " dropped by the new view")
+		p.externalProcessor.EventProcessor(consensus.Event.Failed, prePrepare.Payload, nil)
+	}
+}
+
+func (p *pbftService) enterNewView(newView uint64, stable SignedPBFTData, prePrepares []SignedPBFTData) {
+	p.currentView = newView
+	p.viewChanging = false
+	p.viewTimer.Reset(p.config.ConsensusTimeout)
+
+	for v := range p.viewChanges {
+		if v <= newView {
+			delete(p.viewChanges, v)
+		}
+	}
+
+	log.Log.Println("pbft enter new view ", newView)
+
+	if stable.Sequence > p.lowWaterMark {
+		stableProof := ViewChangeProof{}
+		_ = json.Unmarshal(stable.Payload, &stableProof)
+		p.stabilizeCheckpoint(stable.Sequence, stable.Digest, stableProof.Checkpoints)
+	}
+
+	pending := p.pendingPrePrepares(newView)
+	for _, prePrepare := range prePrepares {
+		if p.inWatermarks(prePrepare.Sequence) {
+			p.acceptPrePrepare(prePrepare)
+		}
+	}
+	p.cancelDroppedPrePrepares(pending)
+
+	_ = p.saveSafetyState()
+	p.scheduleProposal()
+}
+
+func (p *pbftService) registerViewChangeProcessor() {
+	p.consensusProcessor[messageTypes.ViewChange.String()] = p.gotViewChange
+	p.consensusProcessor[messageTypes.NewView.String()] = p.gotNewView
+}
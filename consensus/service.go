@@ -42,13 +42,28 @@ type ICustomerData interface {
 var States state
 var Event event
 
+//the quorum of the BFT consensus including the proposer, any two quorums intersect in at least one honest member.
+//the governance of the members uses the same quorum, so they always agree on the threshold.
+func QuorumOf(memberCount int) int {
+	return memberCount - memberCount/3
+}
+
+//the finality proof of a success event proves the customer data was decided by the members, it's empty if the
+//consensus doesn't build one. a pipelined consensus builds new customer data on the customer data proposed but
+//not decided yet by NewDataBasedOnConsensus, the pending data is ordered from the oldest.
 type ExternalProcessor interface {
-	EventProcessor(event enum.Element, customerData []byte)
-	NewDataBasedOnConsensus(data ICustomerData) (newData ICustomerData, err error)
+	EventProcessor(event enum.Element, customerData []byte, finalityProof []byte)
+	NewDataBasedOnConsensus(pending [][]byte) (newData ICustomerData, err error)
 	CustomerDataToConsensus() (data ICustomerData, err error)
 	CustomerDataFromConsensus(data []byte) (customData ICustomerData, err error)
 }
 
+//verify a finality proof built by the consensus with the members at the height of the decided data,
+//and returns the customer data decided by it
+type IFinalityVerifier interface {
+	VerifyFinality(proof []byte, height uint64) (customerData []byte, err error)
+}
+
 type IConsensusService interface {
 	Load(networkService network.IService, processor ExternalProcessor)
 	Start(cfg interface{}) (err error)
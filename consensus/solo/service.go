@@ -0,0 +1,193 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package solo
+
+import (
+	"errors"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/network"
+	"sync"
+	"time"
+)
+
+const MessageFamily = "solo-consensus"
+
+type soloInformation struct {
+	Network           network.StaticInformation
+	ConsensusInterval time.Duration
+	ConsensusRound    uint64
+}
+
+//solo consensus lets a single node decide every customer data by itself, it is for development and test only.
+type soloService struct {
+	config  Config
+	network network.IService
+
+	currentState enum.Element
+	stateLock    sync.Mutex
+
+	consensusRound uint64
+	stopSignal     chan struct{}
+
+	externalProcessor consensus.ExternalProcessor
+}
+
+var Solo soloService
+
+func (s *soloService) isStopped() bool {
+	return s.currentState.String() == consensus.States.Stopped.String()
+}
+
+func (s *soloService) verifiedCustomerData() (data []byte, err error) {
+	customerData, err := s.externalProcessor.CustomerDataToConsensus()
+	if err != nil {
+		return
+	}
+
+	data, err = customerData.Bytes()
+	if err != nil {
+		return
+	}
+
+	//verify the data as a replica does, so the customer data goes through the same path as the real consensus
+	verifiedData, err := s.externalProcessor.CustomerDataFromConsensus(data)
+	if err != nil {
+		return
+	}
+
+	passed, err := verifiedData.Verify()
+	if !passed && err == nil {
+		err = errors.New("customer data verify failed")
+	}
+	return
+}
+
+func (s *soloService) newRound() {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if s.isStopped() || s.externalProcessor == nil {
+		return
+	}
+
+	data, err := s.verifiedCustomerData()
+	if err != nil {
+		log.Log.Error("solo consensus failed: ", err.Error())
+		s.externalProcessor.EventProcessor(consensus.Event.Failed, data, nil)
+		return
+	}
+
+	s.consensusRound += 1
+	s.externalProcessor.EventProcessor(consensus.Event.Success, data, nil)
+}
+
+func (s *soloService) startConsensus() {
+	log.Log.Println("start solo consensus : ", s.config.ConsensusInterval)
+
+	ticker := time.NewTicker(s.config.ConsensusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.newRound()
+
+		case <-s.stopSignal:
+			log.Log.Println("solo consensus stopped")
+			return
+		}
+	}
+}
+
+//solo consensus exchanges no message
+func (s *soloService) Feed(msg message.Message) (reply *message.Message) {
+	return
+}
+
+func (s *soloService) Start(cfg interface{}) (err error) {
+	config, ok := cfg.(Config)
+	if !ok {
+		err = errors.New("invalid config")
+		return
+	}
+
+	if config.ConsensusInterval <= 0 {
+		err = errors.New("invalid consensus interval")
+		return
+	}
+
+	s.config = config
+	s.currentState = consensus.States.Running
+	s.stopSignal = make(chan struct{})
+
+	go s.startConsensus()
+	return
+}
+
+func (s *soloService) Stop() (err error) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if s.isStopped() || s.stopSignal == nil {
+		return
+	}
+
+	s.currentState = consensus.States.Stopped
+	close(s.stopSignal)
+	return
+}
+
+func (s *soloService) RegisterExternalProcessor(processor consensus.ExternalProcessor) {
+	s.externalProcessor = processor
+}
+
+func (s *soloService) GetMessageFamily() (family string) {
+	return MessageFamily
+}
+
+func (s *soloService) GetExternalProcessor() (processor consensus.ExternalProcessor) {
+	return s.externalProcessor
+}
+
+func (s *soloService) GetConsensusCustomerData(msg message.Message) (data []byte, err error) {
+	err = errors.New("solo consensus has no consensus message")
+	return
+}
+
+func (s *soloService) Load(networkService network.IService, processor consensus.ExternalProcessor) {
+	s.network = networkService
+	s.externalProcessor = processor
+}
+
+func (s *soloService) StaticInformation() interface{} {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	info := soloInformation{}
+
+	if s.network != nil {
+		info.Network = s.network.StaticInformation()
+	}
+	info.ConsensusInterval = s.config.ConsensusInterval
+	info.ConsensusRound = s.consensusRound
+
+	return info
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package consensus
+
+import (
+	"encoding/json"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+//the store of the states a consensus must not forget after a restart, otherwise it may vote against the values
+//it voted before. every consensus service keeps its state under its own key, so the services could share one store.
+type IStateStore interface {
+	Save(key string, state interface{}) (err error)
+	Load(key string, state interface{}) (exists bool, err error)
+}
+
+type kvStateStore struct {
+	driver kvDatabase.IDriver
+}
+
+//the whole state is stored under one key, so a single put keeps the state consistent.
+func (k *kvStateStore) Save(key string, state interface{}) (err error) {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	err = k.driver.Put(kvDatabase.KVItem{
+		Key:  []byte(key),
+		Data: stateBytes,
+	})
+	return
+}
+
+func (k *kvStateStore) Load(key string, state interface{}) (exists bool, err error) {
+	kv, err := k.driver.Get([]byte(key))
+	if err != nil || !kv.Exists {
+		return
+	}
+
+	err = json.Unmarshal(kv.Data, state)
+	if err != nil {
+		return
+	}
+
+	exists = true
+	return
+}
+
+func NewKVStateStore(driver kvDatabase.IDriver) IStateStore {
+	return &kvStateStore{
+		driver: driver,
+	}
+}
@@ -0,0 +1,414 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bls12381
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+const algorithmName = "BLS12381"
+
+const PrivateKeySize = 32
+const PublicKeySize = 192
+const SignatureSize = 96
+
+//signatures are points on G1 and public keys are points on G2, so the signatures of the same data
+//can be aggregated into one G1 point which keeps the same size whatever the signer count is.
+type keyPair struct {
+	PrivateKey []byte
+	PublicKey  []byte
+}
+
+//the domain separation tags of the minimal-signature-size ciphersuite with proof of possession
+const signatureDST = "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+const possessionDST = "BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+
+//the base field modulus of BLS12-381
+var fieldModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+//expand_message_xmd of RFC 9380 with SHA-256
+func expandMessageXMD(msg []byte, dst []byte, outLen int) (uniform []byte, err error) {
+	ell := (outLen + sha256.Size - 1) / sha256.Size
+	if ell > 255 || outLen > 65535 || len(dst) > 255 {
+		err = errors.New("invalid expand length or DST")
+		return
+	}
+
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(outLen >> 8), byte(outLen), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	bi := make([]byte, sha256.Size)
+	for i := 1; i <= ell; i++ {
+		h.Reset()
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		h.Write(bi)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+
+		uniform = append(uniform, bi...)
+	}
+
+	uniform = uniform[:outLen]
+	return
+}
+
+//hash_to_curve of RFC 9380 with the suite BLS12381G1_XMD:SHA-256_SSWU_RO_, the cofactor clearing of MapToCurve
+//is a group homomorphism, so the sum of two mapped points is same as the cleared sum of them.
+func hashToG1(data []byte, dst string) (point *bls12381.PointG1, err error) {
+	//two field elements of 64 bytes each, reduced to the 48 bytes big endian encoding
+	uniform, err := expandMessageXMD(data, []byte(dst), 128)
+	if err != nil {
+		return
+	}
+
+	g1 := bls12381.NewG1()
+	point = g1.Zero()
+	for i := 0; i < 2; i++ {
+		fieldElement := make([]byte, 48)
+		new(big.Int).Mod(new(big.Int).SetBytes(uniform[i*64:(i+1)*64]), fieldModulus).FillBytes(fieldElement)
+
+		mapped, mapErr := g1.MapToCurve(fieldElement)
+		if mapErr != nil {
+			err = mapErr
+			return
+		}
+
+		g1.Add(point, point, mapped)
+	}
+
+	point = g1.Affine(point)
+	return
+}
+
+func publicKeyFromBytes(key []byte) (pk *bls12381.PointG2, err error) {
+	g2 := bls12381.NewG2()
+	pk, err = g2.FromBytes(key)
+	if err != nil {
+		return
+	}
+
+	if g2.IsZero(pk) || !g2.InCorrectSubgroup(pk) {
+		err = errors.New("invalid public key")
+	}
+	return
+}
+
+func signatureFromBytes(signature []byte) (sig *bls12381.PointG1, err error) {
+	g1 := bls12381.NewG1()
+	sig, err = g1.FromBytes(signature)
+	if err != nil {
+		return
+	}
+
+	if !g1.InCorrectSubgroup(sig) {
+		err = errors.New("invalid signature")
+	}
+	return
+}
+
+func verifyPoints(data []byte, dst string, sig *bls12381.PointG1, pk *bls12381.PointG2) (passed bool, err error) {
+	hashPoint, err := hashToG1(data, dst)
+	if err != nil {
+		return
+	}
+
+	//e(sig, g2) == e(H(data), pk)
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(sig, engine.G2.One())
+	engine.AddPairInv(hashPoint, pk)
+
+	passed = engine.Check()
+	return
+}
+
+func (k keyPair) Type() string {
+	return algorithmName
+}
+
+func (k keyPair) Verify(data []byte, signature []byte) (passed bool, err error) {
+	pk, err := publicKeyFromBytes(k.PublicKey)
+	if err != nil {
+		return
+	}
+
+	sig, err := signatureFromBytes(signature)
+	if err != nil {
+		return
+	}
+
+	return verifyPoints(data, signatureDST, sig, pk)
+}
+
+func (k keyPair) Sign(data []byte) (signature []byte, err error) {
+	return k.signWithDST(data, signatureDST)
+}
+
+func (k keyPair) signWithDST(data []byte, dst string) (signature []byte, err error) {
+	if len(k.PrivateKey) != PrivateKeySize {
+		err = errors.New("no private key")
+		return
+	}
+
+	hashPoint, err := hashToG1(data, dst)
+	if err != nil {
+		return
+	}
+
+	g1 := bls12381.NewG1()
+	sig := g1.New()
+	g1.MulScalar(sig, hashPoint, new(big.Int).SetBytes(k.PrivateKey))
+
+	signature = g1.ToBytes(sig)
+	return
+}
+
+func (k keyPair) KeyPairData() (keyData []byte) {
+	keyData, _ = json.Marshal(k)
+	return
+}
+
+func (k keyPair) RawKeyPair() (kp interface{}) {
+	return signerCommon.KeyPair{
+		PrivateKey: append([]byte{}, k.PrivateKey...),
+		PublicKey:  append([]byte{}, k.PublicKey...),
+	}
+}
+
+func (k keyPair) PublicKeyBytes() (key []byte) {
+	return append([]byte{}, k.PublicKey...)
+}
+
+func (k keyPair) PrivateKeyBytes() (key []byte) {
+	return append([]byte{}, k.PrivateKey...)
+}
+
+func (k keyPair) PublicKeyString() (key string) {
+	return hex.EncodeToString(k.PublicKey)
+}
+
+func (k keyPair) PrivateKeyString() (key string) {
+	return hex.EncodeToString(k.PrivateKey)
+}
+
+func (k keyPair) PublicKeyCompare(key interface{}) (equal bool) {
+	keyBytes, ok := key.([]byte)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(k.PublicKey, keyBytes)
+}
+
+func (k *keyPair) ToAddress() string {
+	return k.PublicKeyString()
+}
+
+func (k *keyPair) ToAddressBytes() []byte {
+	return k.PublicKeyBytes()
+}
+
+//the private key is a scalar of the group order, a raw key bigger than the order will be reduced.
+func newKeyPair(scalar *big.Int) (kp *keyPair, err error) {
+	g2 := bls12381.NewG2()
+	scalar = new(big.Int).Mod(scalar, g2.Q())
+	if scalar.Sign() == 0 {
+		err = errors.New("invalid private key")
+		return
+	}
+
+	pub := g2.New()
+	g2.MulScalar(pub, g2.One(), scalar)
+
+	priv := make([]byte, PrivateKeySize)
+	scalar.FillBytes(priv)
+
+	kp = &keyPair{
+		PrivateKey: priv,
+		PublicKey:  g2.ToBytes(pub),
+	}
+	return
+}
+
+//aggregate the signatures of the same data into one signature
+func AggregateSignatures(signatures [][]byte) (aggregated []byte, err error) {
+	if len(signatures) == 0 {
+		err = errors.New("no signature to aggregate")
+		return
+	}
+
+	g1 := bls12381.NewG1()
+	sum := g1.Zero()
+	for _, s := range signatures {
+		sig, sigErr := signatureFromBytes(s)
+		if sigErr != nil {
+			err = sigErr
+			return
+		}
+
+		g1.Add(sum, sum, sig)
+	}
+
+	aggregated = g1.ToBytes(sum)
+	return
+}
+
+//verify an aggregated signature of the same data signed by all the given public keys
+func VerifyAggregated(data []byte, aggregated []byte, publicKeys [][]byte) (passed bool, err error) {
+	if len(publicKeys) == 0 {
+		err = errors.New("no public key")
+		return
+	}
+
+	g2 := bls12381.NewG2()
+	sum := g2.Zero()
+	for _, k := range publicKeys {
+		pk, pkErr := publicKeyFromBytes(k)
+		if pkErr != nil {
+			err = pkErr
+			return
+		}
+
+		g2.Add(sum, sum, pk)
+	}
+
+	sig, err := signatureFromBytes(aggregated)
+	if err != nil {
+		return
+	}
+
+	return verifyPoints(data, signatureDST, sig, sum)
+}
+
+//the proof of possession is the signature of a public key by its own private key under a separated DST, the keys
+//aggregated by VerifyAggregated must have their proofs verified, otherwise a rogue key could forge the aggregation.
+func ProvePossession(signer signerCommon.ISigner) (proof []byte, err error) {
+	kp, ok := signer.(*keyPair)
+	if !ok {
+		err = errors.New("not a " + algorithmName + " signer")
+		return
+	}
+
+	return kp.signWithDST(kp.PublicKey, possessionDST)
+}
+
+func VerifyPossession(publicKey []byte, proof []byte) (passed bool, err error) {
+	pk, err := publicKeyFromBytes(publicKey)
+	if err != nil {
+		return
+	}
+
+	sig, err := signatureFromBytes(proof)
+	if err != nil {
+		return
+	}
+
+	return verifyPoints(publicKey, possessionDST, sig, pk)
+}
+
+type keyGenerator struct{}
+
+func (keyGenerator) Type() string {
+	return algorithmName
+}
+
+func (keyGenerator) NewSigner(_ interface{}) (s signerCommon.ISigner, err error) {
+	scalar, err := rand.Int(rand.Reader, bls12381.NewG2().Q())
+	if err != nil {
+		return
+	}
+
+	return newKeyPair(scalar)
+}
+
+func (k *keyGenerator) FromRawPrivateKey(key interface{}) (s signerCommon.ISigner, err error) {
+	keyBytes, ok := key.([]byte)
+	if !ok {
+		err = errors.New("only support bytes type key")
+		return
+	}
+	if len(keyBytes) != PrivateKeySize {
+		err = errors.New("invalid key size")
+		return
+	}
+
+	return newKeyPair(new(big.Int).SetBytes(keyBytes))
+}
+
+func (k *keyGenerator) FromRawPublicKey(key interface{}) (s signerCommon.ISigner, err error) {
+	keyBytes, ok := key.([]byte)
+	if !ok {
+		err = errors.New("only support bytes type key")
+		return
+	}
+
+	if _, err = publicKeyFromBytes(keyBytes); err != nil {
+		return
+	}
+
+	s = &keyPair{
+		PublicKey: append([]byte{}, keyBytes...),
+	}
+	return
+}
+
+func (k *keyGenerator) FromKeyPairData(kpData []byte) (signer signerCommon.ISigner, err error) {
+	newSigner := keyPair{}
+	err = json.Unmarshal(kpData, &newSigner)
+	if err != nil {
+		return
+	}
+
+	signer = &newSigner
+	return
+}
+
+func (k *keyGenerator) FromRawKeyPair(keys interface{}) (s signerCommon.ISigner, err error) {
+	newSigner := keyPair{}
+
+	kp, ok := keys.(signerCommon.KeyPair)
+	if !ok {
+		err = errors.New("invalid key pair")
+		return
+	}
+
+	newSigner.PrivateKey = append([]byte{}, kp.PrivateKey.([]byte)...)
+	newSigner.PublicKey = append([]byte{}, kp.PublicKey.([]byte)...)
+
+	s = &newSigner
+	return
+}
+
+var SignerGenerator = &keyGenerator{}
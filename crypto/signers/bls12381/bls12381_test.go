@@ -0,0 +1,160 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package bls12381
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+//RFC 9380 K.1, expand_message_xmd(SHA-256)
+func TestExpandMessageXMD(t *testing.T) {
+	uniform, err := expandMessageXMD([]byte(""), []byte("QUUX-V01-CS02-with-expander-SHA256-128"), 0x20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := mustDecode(t, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235")
+	if !bytes.Equal(uniform, expected) {
+		t.Fatal("unexpected uniform bytes: ", hex.EncodeToString(uniform))
+	}
+}
+
+//RFC 9380 J.9.1, BLS12381G1_XMD:SHA-256_SSWU_RO_
+func TestHashToG1(t *testing.T) {
+	point, err := hashToG1([]byte(""), "QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := mustDecode(t, "052926add2207b76ca4fa57a8734416c8dc95e24501772c814278700eed6d1e4e8cf62d9c09db0fac349612b759e79a1"+
+		"08ba738453bfed09cb546dbb0783dbb3a5f1f566ed67bb6be0e8c67e2e81a4cc68ee29813bb7994998f3eae0c9c6a265")
+	if encoded := bls12381.NewG1().ToBytes(point); !bytes.Equal(encoded, expected) {
+		t.Fatal("unexpected point: ", hex.EncodeToString(encoded))
+	}
+}
+
+func newTestSigners(t *testing.T, count int) (signers []*keyPair) {
+	for i := 0; i < count; i++ {
+		s, err := SignerGenerator.NewSigner(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, s.(*keyPair))
+	}
+	return
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := newTestSigners(t, 1)[0]
+	data := []byte("data to sign")
+
+	sig, err := s.Sign(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed, _ := s.Verify(data, sig); !passed {
+		t.Fatal("valid signature not verified")
+	}
+
+	if passed, _ := s.Verify([]byte("other data"), sig); passed {
+		t.Fatal("signature verified on other data")
+	}
+
+	pub, err := SignerGenerator.FromRawPublicKey(s.PublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed, _ := pub.Verify(data, sig); !passed {
+		t.Fatal("signature not verified by the public key")
+	}
+}
+
+func TestVerifyAggregated(t *testing.T) {
+	signers := newTestSigners(t, 4)
+	data := []byte("aggregated data")
+
+	var sigs [][]byte
+	var keys [][]byte
+	for _, s := range signers {
+		sig, err := s.Sign(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sigs = append(sigs, sig)
+		keys = append(keys, s.PublicKeyBytes())
+	}
+
+	aggregated, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed, _ := VerifyAggregated(data, aggregated, keys); !passed {
+		t.Fatal("aggregated signature not verified")
+	}
+
+	if passed, _ := VerifyAggregated(data, aggregated, keys[1:]); passed {
+		t.Fatal("aggregated signature verified without a signer")
+	}
+
+	if passed, _ := VerifyAggregated([]byte("other data"), aggregated, keys); passed {
+		t.Fatal("aggregated signature verified on other data")
+	}
+}
+
+func TestPossession(t *testing.T) {
+	signers := newTestSigners(t, 2)
+
+	proof, err := ProvePossession(signers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed, _ := VerifyPossession(signers[0].PublicKeyBytes(), proof); !passed {
+		t.Fatal("valid proof of possession not verified")
+	}
+
+	if passed, _ := VerifyPossession(signers[1].PublicKeyBytes(), proof); passed {
+		t.Fatal("proof of possession verified for another key")
+	}
+
+	//a proof is not a signature of the public key, the DSTs are separated
+	sig, err := signers[0].Sign(signers[0].PublicKeyBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if passed, _ := VerifyPossession(signers[0].PublicKeyBytes(), sig); passed {
+		t.Fatal("signature accepted as a proof of possession")
+	}
+}
@@ -18,6 +18,7 @@
 package signers
 
 import (
+	"github.com/SealSC/SealABC/crypto/signers/bls12381"
 	"github.com/SealSC/SealABC/crypto/signers/ecdsa/secp256k1"
 	"github.com/SealSC/SealABC/crypto/signers/ed25519"
 	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
@@ -28,6 +29,7 @@ var signerGenerators = map[string]ISignerGenerator{
 	ed25519.SignerGenerator.Type():   ed25519.SignerGenerator,
 	secp256k1.SignerGenerator.Type(): secp256k1.SignerGenerator,
 	sm2.SignerGenerator.Type():       sm2.SignerGenerator,
+	bls12381.SignerGenerator.Type():  bls12381.SignerGenerator,
 }
 
 type ISignerGenerator interface {
@@ -67,6 +67,7 @@ func (t *Tree) Calculate() (root []byte, err error) {
 	}
 
 	root = tree.MerkleRoot()
+	t.tree = tree
 
 	return
 }
@@ -92,3 +93,62 @@ func (t Tree) VerifyHash(hash []byte) (passed bool, err error) {
 	}
 	return t.tree.VerifyContent(newContent)
 }
+
+//a step of the audit path, the hash of the sibling node and whether the sibling is on the left
+type ProofStep struct {
+	Hash []byte
+	Left bool
+}
+
+//the inclusion proof of a leaf, the steps are ordered from the leaf to the root
+type Proof struct {
+	Index int
+	Leaf  []byte
+	Path  []ProofStep
+}
+
+//build the audit path of the leaf at the index in adding order, the tree must be calculated.
+func (t Tree) Proof(index int) (proof Proof, err error) {
+	if t.tree == nil {
+		return proof, errors.New("not calculated")
+	}
+
+	if index < 0 || index >= len(t.list) {
+		return proof, errors.New("index out of range")
+	}
+
+	current := t.tree.Leafs[index]
+	proof.Index = index
+	proof.Leaf = current.Hash
+
+	//the last node of an odd level is paired with itself, so the parent is found by the node, not by the hash
+	for parent := current.Parent; parent != nil; parent = parent.Parent {
+		if parent.Left == current {
+			proof.Path = append(proof.Path, ProofStep{Hash: parent.Right.Hash, Left: false})
+		} else {
+			proof.Path = append(proof.Path, ProofStep{Hash: parent.Left.Hash, Left: true})
+		}
+
+		current = parent
+	}
+
+	return
+}
+
+//fold the audit path from the leaf and compare with the root, it needs nothing but the proof and the root.
+func VerifyProof(proof Proof, root []byte) (passed bool) {
+	if len(proof.Leaf) == 0 || len(root) == 0 {
+		return false
+	}
+
+	current := proof.Leaf
+	for _, step := range proof.Path {
+		if step.Left {
+			current = sha3.Sha256.Sum(append(append([]byte{}, step.Hash...), current...))
+		} else {
+			current = sha3.Sha256.Sum(append(append([]byte{}, current...), step.Hash...))
+		}
+	}
+
+	return bytes.Equal(current, root)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package merkleTree
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+)
+
+func newTestTree(t *testing.T, count int) (tree Tree, root []byte) {
+	for i := 0; i < count; i++ {
+		tree.AddHash(sha3.Sha256.Sum([]byte("leaf-" + strconv.Itoa(i))))
+	}
+
+	root, err := tree.Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestProofOfEveryLeaf(t *testing.T) {
+	sha3.Load()
+
+	//the odd counts pair the last node of a level with itself
+	for count := 1; count <= 9; count++ {
+		tree, root := newTestTree(t, count)
+		for i := 0; i < count; i++ {
+			proof, err := tree.Proof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !VerifyProof(proof, root) {
+				t.Fatal("proof of leaf ", i, " in ", count, " leaves not verified")
+			}
+		}
+	}
+}
+
+func TestProofTampered(t *testing.T) {
+	sha3.Load()
+	tree, root := newTestTree(t, 5)
+
+	proof, err := tree.Proof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherLeaf := proof
+	otherLeaf.Leaf = sha3.Sha256.Sum([]byte("other"))
+	if VerifyProof(otherLeaf, root) {
+		t.Fatal("proof of another leaf verified")
+	}
+
+	flipped := proof
+	flipped.Path = append([]ProofStep{}, proof.Path...)
+	flipped.Path[0].Left = !flipped.Path[0].Left
+	if VerifyProof(flipped, root) {
+		t.Fatal("proof with a flipped step verified")
+	}
+
+	short := proof
+	short.Path = proof.Path[:len(proof.Path)-1]
+	if VerifyProof(short, root) {
+		t.Fatal("proof without the last step verified")
+	}
+
+	_, otherRoot := newTestTree(t, 6)
+	if VerifyProof(proof, otherRoot) {
+		t.Fatal("proof verified by another root")
+	}
+
+	if VerifyProof(proof, nil) || VerifyProof(Proof{}, root) {
+		t.Fatal("empty proof or root verified")
+	}
+}
+
+func TestProofErrors(t *testing.T) {
+	sha3.Load()
+
+	notCalculated := Tree{}
+	notCalculated.AddHash(sha3.Sha256.Sum([]byte("leaf")))
+	if _, err := notCalculated.Proof(0); err == nil {
+		t.Fatal("proof built on a tree not calculated")
+	}
+
+	tree, _ := newTestTree(t, 3)
+	if _, err := tree.Proof(3); err == nil {
+		t.Fatal("proof built for an index out of range")
+	}
+
+	if _, err := tree.Proof(-1); err == nil {
+		t.Fatal("proof built for a negative index")
+	}
+}
@@ -17,8 +17,15 @@
 
 package engine
 
-import "github.com/SealSC/SealABC/engine/engineStartup"
+import (
+	"context"
+	"github.com/SealSC/SealABC/engine/engineStartup"
+)
 
-func Startup(cfg engineStartup.Config) {
-	_ = engineStartup.Start(cfg)
+func Startup(cfg engineStartup.Config) (err error) {
+	return engineStartup.Start(cfg)
+}
+
+func Shutdown(ctx context.Context) (err error) {
+	return engineStartup.Shutdown(ctx)
 }
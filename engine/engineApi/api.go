@@ -18,9 +18,14 @@
 package engineApi
 
 import (
+	"context"
 	"github.com/SealSC/SealABC/engine/engineApi/httpJSON"
 )
 
 func Start(cfg Config) {
 	httpJSON.Start(cfg.HttpJSON)
 }
+
+func Stop(ctx context.Context) (err error) {
+	return httpJSON.Stop(ctx)
+}
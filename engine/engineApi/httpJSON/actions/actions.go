@@ -30,6 +30,7 @@ func Load(cfg http.Config) []http.IRequestHandler {
 
 	actionList = []http.IRequestHandler{
 		ListServices,
+		ListRejectedPeers,
 	}
 
 	return actionList
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package actions
+
+import (
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/http"
+	"github.com/SealSC/SealABC/service"
+	"github.com/gin-gonic/gin"
+)
+
+type listRejectedPeers struct {
+	path string
+}
+
+var ListRejectedPeers = &listRejectedPeers{
+	path: "/list/rejected/peers",
+}
+
+func (c *listRejectedPeers) Handle(ctx *gin.Context) {
+	res := http.NewResponse(ctx)
+	res.OK(network.RejectedPeers())
+}
+
+func (c *listRejectedPeers) RouteRegister(router gin.IRouter) {
+	router.GET(serverConfig.BasePath+c.path, c.Handle)
+}
+
+func (c *listRejectedPeers) BasicInformation() (info http.HandlerBasicInformation) {
+
+	info.Description = "this method will list the last join attempts rejected by the peer admission."
+	info.Path = serverConfig.BasePath + c.path
+	info.Method = service.ApiProtocolMethod.HttpGet.String()
+
+	return
+}
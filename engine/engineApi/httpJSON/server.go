@@ -18,12 +18,15 @@
 package httpJSON
 
 import (
+	"context"
 	"github.com/SealSC/SealABC/engine/engineApi/httpJSON/actions"
 	"github.com/SealSC/SealABC/network/http"
 )
 
+var httpServer http.Server
+
 func Start(cfg http.Config) {
-	httpServer := http.Server{
+	httpServer = http.Server{
 		Config: &cfg,
 	}
 
@@ -32,3 +35,7 @@ func Start(cfg http.Config) {
 
 	httpServer.Start()
 }
+
+func Stop(ctx context.Context) (err error) {
+	return httpServer.Shutdown(ctx)
+}
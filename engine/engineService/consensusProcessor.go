@@ -56,13 +56,24 @@ func (r requestList) Bytes() (data []byte, err error) {
 	return
 }
 
-func (e *consensusProcessor) EventProcessor(event enum.Element, customerData []byte) {
-	if event.String() == consensus.Event.Failed.String() {
-		//todo: handle consensus failed
-		log.Log.Error("consensus failed")
-		return
+//the requests of a failed consensus round are cancelled by their services, so the services could return them to the pools.
+func (e *consensusProcessor) cancelRequests(reqList requestList) {
+	for _, req := range reqList.Requests {
+		srvReq := serviceRequest.Entity{}
+		err := json.Unmarshal(req, &srvReq)
+		if err != nil {
+			log.Log.Error("deserialize consensus data failed: ", err.Error())
+			continue
+		}
+
+		err = cancelServiceRequest(srvReq)
+		if err != nil {
+			log.Log.Error("cancel request failed: ", err.Error())
+		}
 	}
+}
 
+func (e *consensusProcessor) EventProcessor(event enum.Element, customerData []byte, finalityProof []byte) {
 	reqList := requestList{}
 
 	err := json.Unmarshal(customerData, &reqList)
@@ -71,6 +82,12 @@ func (e *consensusProcessor) EventProcessor(event enum.Element, customerData []b
 		return
 	}
 
+	if event.String() == consensus.Event.Failed.String() {
+		log.Log.Warn("consensus failed, cancel ", len(reqList.Requests), " requests")
+		e.cancelRequests(reqList)
+		return
+	}
+
 	for _, req := range reqList.Requests {
 		srvReq := serviceRequest.Entity{}
 		err = json.Unmarshal(req, &srvReq)
@@ -79,6 +96,7 @@ func (e *consensusProcessor) EventProcessor(event enum.Element, customerData []b
 			continue
 		}
 
+		srvReq.FinalityProof = finalityProof
 		_, err = executeServiceRequest(srvReq)
 		//todo: handle the result of pre-execute
 
@@ -92,6 +110,27 @@ func (e *consensusProcessor) EventProcessor(event enum.Element, customerData []b
 	return
 }
 
+//the service requests in the customer data decided by the consensus
+func ServiceRequestsFromConsensus(customerData []byte) (reqList []serviceRequest.Entity, err error) {
+	consensusReqList := requestList{}
+	err = json.Unmarshal(customerData, &consensusReqList)
+	if err != nil {
+		return
+	}
+
+	for _, req := range consensusReqList.Requests {
+		srvReq := serviceRequest.Entity{}
+		err = json.Unmarshal(req, &srvReq)
+		if err != nil {
+			return
+		}
+
+		reqList = append(reqList, srvReq)
+	}
+
+	return
+}
+
 func (e *consensusProcessor) CustomerDataToConsensus() (data consensus.ICustomerData, _ error) {
 	allValidRequests := getAllRequestsNeedConsensus()
 	data = &requestList{
@@ -112,8 +151,31 @@ func (e *consensusProcessor) CustomerDataFromConsensus(data []byte) (customerDat
 	return
 }
 
-func (e *consensusProcessor) NewDataBasedOnConsensus(data consensus.ICustomerData) (newData consensus.ICustomerData, err error) {
-	//todo: prepare for parallel service
+func (e *consensusProcessor) NewDataBasedOnConsensus(pending [][]byte) (newData consensus.ICustomerData, err error) {
+	pendingRequests := map[string][][]byte{}
+	for _, data := range pending {
+		reqList := requestList{}
+		err = json.Unmarshal(data, &reqList)
+		if err != nil {
+			log.Log.Error("deserialize pending consensus data failed: ", err.Error())
+			return
+		}
+
+		for _, req := range reqList.Requests {
+			srvReq := serviceRequest.Entity{}
+			err = json.Unmarshal(req, &srvReq)
+			if err != nil {
+				log.Log.Error("deserialize pending request failed: ", err.Error())
+				return
+			}
+
+			pendingRequests[srvReq.RequestService] = append(pendingRequests[srvReq.RequestService], req)
+		}
+	}
+
+	newData = &requestList{
+		getAllRequestsBasedOn(pendingRequests),
+	}
 	return
 }
 
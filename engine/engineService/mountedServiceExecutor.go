@@ -18,7 +18,9 @@
 package engineService
 
 import (
+	"context"
 	"errors"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/serviceRequest"
 	"github.com/SealSC/SealABC/service"
 	"sync"
@@ -26,6 +28,7 @@ import (
 
 var serviceMap = map[string]service.IService{}
 var serviceLock sync.RWMutex
+var servicesStopped = false
 
 func Mount(s service.IService) (err error) {
 	serviceLock.Lock()
@@ -41,6 +44,11 @@ func Mount(s service.IService) (err error) {
 }
 
 func getService(name string) (s service.IService, err error) {
+	if servicesStopped {
+		err = errors.New("services stopped")
+		return
+	}
+
 	if _, exists := serviceMap[name]; !exists {
 		err = errors.New("service named " + name + " not exists")
 		return
@@ -74,10 +82,26 @@ func executeServiceRequest(req serviceRequest.Entity) (result []byte, err error)
 	return s.Execute(req)
 }
 
+func cancelServiceRequest(req serviceRequest.Entity) (err error) {
+	serviceLock.Lock()
+	defer serviceLock.Unlock()
+
+	s, err := getService(req.RequestService)
+	if err != nil {
+		return
+	}
+
+	return s.Cancel(req)
+}
+
 func getAllRequestsNeedConsensus() (actions [][]byte) {
 	serviceLock.Lock()
 	defer serviceLock.Unlock()
 
+	if servicesStopped {
+		return
+	}
+
 	for _, s := range serviceMap {
 		act, cnt := s.RequestsForConsensus()
 		if cnt == 0 {
@@ -88,3 +112,49 @@ func getAllRequestsNeedConsensus() (actions [][]byte) {
 
 	return
 }
+
+//the pending requests are grouped by their services, a service without pending requests builds its requests as usual.
+func getAllRequestsBasedOn(pending map[string][][]byte) (actions [][]byte) {
+	serviceLock.Lock()
+	defer serviceLock.Unlock()
+
+	if servicesStopped {
+		return
+	}
+
+	for name, s := range serviceMap {
+		var act [][]byte
+		var cnt uint32
+
+		servicePending := pending[name]
+		if len(servicePending) == 0 {
+			act, cnt = s.RequestsForConsensus()
+		} else if pipelined, ok := s.(service.IPipelinedService); ok {
+			act, cnt = pipelined.RequestsBasedOn(servicePending)
+		}
+
+		if cnt == 0 {
+			continue
+		}
+		actions = append(actions, act...)
+	}
+
+	return
+}
+
+//stop all mounted services, the executing request will be finished first because it holds the service lock.
+func StopServices(ctx context.Context) (err error) {
+	serviceLock.Lock()
+	defer serviceLock.Unlock()
+
+	servicesStopped = true
+	for name, s := range serviceMap {
+		stopErr := s.Stop(ctx)
+		if stopErr != nil {
+			log.Log.Error("stop service ", name, " failed: ", stopErr.Error())
+			err = stopErr
+		}
+	}
+
+	return
+}
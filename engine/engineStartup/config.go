@@ -29,6 +29,7 @@ type Config struct {
 
 	ConsensusNetwork  network.Config
 	ConsensusDisabled bool
+	ConsensusType     string
 
 	Consensus interface{}
 }
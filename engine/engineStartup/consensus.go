@@ -18,10 +18,43 @@
 package engineStartup
 
 import (
+	"errors"
 	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/consensus/hotStuff"
+	"github.com/SealSC/SealABC/consensus/pbft"
+	"github.com/SealSC/SealABC/consensus/solo"
 	"github.com/SealSC/SealABC/network"
 )
 
+const (
+	BasicHotStuff   = "basic-hot-stuff"
+	ChainedHotStuff = "chained-hot-stuff"
+	PBFT            = "pbft"
+	Solo            = "solo"
+)
+
+//basic hot-stuff is used if the consensus type is not set
+func consensusService() (service consensus.IConsensusService, err error) {
+	switch config.ConsensusType {
+	case "", BasicHotStuff:
+		service = &hotStuff.Basic
+
+	case ChainedHotStuff:
+		service = &hotStuff.Chained
+
+	case PBFT:
+		service = &pbft.PBFT
+
+	case Solo:
+		service = &solo.Solo
+
+	default:
+		err = errors.New("unsupported consensus type: " + config.ConsensusType)
+	}
+
+	return
+}
+
 func startConsensus(service consensus.IConsensusService, ns network.IService, processor consensus.ExternalProcessor) (err error) {
 	//load service to consensus driver
 	service = consensus.Load(service, ns, processor)
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package engineStartup
+
+import (
+	"context"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/engine/engineApi"
+	"github.com/SealSC/SealABC/engine/engineService"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/storage/db"
+)
+
+var consensusNetwork network.IService
+
+func Shutdown(ctx context.Context) (err error) {
+	//stop consensus first, no more new rounds after this
+	if !config.ConsensusDisabled {
+		stopErr := consensus.Driver.Stop()
+		if stopErr != nil {
+			log.Log.Error("stop consensus failed: ", stopErr.Error())
+			err = stopErr
+		}
+	}
+
+	//stop mounted services, it will wait for the executing request
+	stopErr := engineService.StopServices(ctx)
+	if stopErr != nil {
+		err = stopErr
+	}
+
+	//stop consensus network
+	if consensusNetwork != nil {
+		consensusNetwork.Stop()
+	}
+
+	//stop engine api
+	stopErr = engineApi.Stop(ctx)
+	if stopErr != nil {
+		log.Log.Error("stop engine api failed: ", stopErr.Error())
+		err = stopErr
+	}
+
+	//close storage last
+	db.CloseAll()
+
+	log.Log.Println("engine shutdown")
+	return
+}
@@ -18,7 +18,6 @@
 package engineStartup
 
 import (
-	"github.com/SealSC/SealABC/consensus/hotStuff"
 	"github.com/SealSC/SealABC/engine/engineApi"
 	"github.com/SealSC/SealABC/engine/engineService"
 	"github.com/SealSC/SealABC/log"
@@ -38,21 +37,26 @@ func Start(cfg Config) (err error) {
 	loadStorage()
 
 	//start consensus network
-	consensusNetwork, _ := startConsensusNetwork()
+	consensusNetwork, _ = startConsensusNetwork()
 
 	//start system service
 	startSystemService()
 
 	engineApi.Start(config.Api)
 
+	service, err := consensusService()
+	if err != nil {
+		return
+	}
+
 	//start consensus
 	if !config.ConsensusDisabled {
-		err = startConsensus(&hotStuff.Basic, consensusNetwork, &engineService.ConsensusProcessor)
+		err = startConsensus(service, consensusNetwork, &engineService.ConsensusProcessor)
 		if err != nil {
 			return
 		}
 	}
 
-	engineService.SetConsensusInformation(&hotStuff.Basic)
+	engineService.SetConsensusInformation(service)
 	return
 }
@@ -7,13 +7,14 @@ require (
 	github.com/btcsuite/btcd v0.22.0-beta
 	github.com/cbergoon/merkletree v0.2.0
 	github.com/d5c5ceb0/sm_crypto_golang v0.0.0-20180712040946-248d0b0bf119
-	github.com/ethereum/go-ethereum v1.10.13 // indirect
+	github.com/ethereum/go-ethereum v1.10.13
 	github.com/gin-gonic/gin v1.7.0
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/golang/snappy v0.0.4
 	github.com/sirupsen/logrus v1.7.0
 	github.com/syndtr/goleveldb v1.0.1-0.20210819022825-2ae1ddf74ef7
 	github.com/urfave/cli/v2 v2.3.0
 	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b
 	golang.org/x/sys v0.0.0-20211209171907-798191bca915 // indirect
-	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1 // indirect
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
 )
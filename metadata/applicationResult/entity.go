@@ -19,8 +19,15 @@ package applicationResult
 
 import "github.com/SealSC/SealABC/metadata/seal"
 
-type Entity struct {
+//an application specific event happened when executing a request, such as a scheduled change
+type Event struct {
+	Name string
 	Data interface{}
+}
+
+type Entity struct {
+	Data   interface{}
+	Events []Event
 
 	Seal *seal.Entity
 }
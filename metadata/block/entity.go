@@ -22,7 +22,25 @@ import (
 	"github.com/SealSC/SealABC/metadata/seal"
 )
 
+//version 1 headers are signed without the roots, version 2 headers commit the state, results and snapshot roots
+const (
+	LegacyVersion  = "1"
+	CurrentVersion = "2"
+)
+
 type Header struct {
+	Version          string
+	Height           uint64
+	PrevBlock        []byte
+	TransactionsRoot []byte
+	StateRoot        []byte
+	ResultsRoot      []byte
+	SnapshotRoot     []byte
+	Timestamp        uint64
+}
+
+//the header layout of the version 1 blocks, keeps the hash of the blocks built before the roots
+type legacyHeader struct {
 	Version          string
 	Height           uint64
 	PrevBlock        []byte
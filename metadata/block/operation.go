@@ -18,19 +18,48 @@
 package block
 
 import (
+	"errors"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
 	"github.com/SealSC/SealABC/crypto"
 )
 
+func (e *Entity) headerBytes() (headerBytes []byte, err error) {
+	h := e.EntityData.Header
+	switch h.Version {
+	case LegacyVersion:
+		return structSerializer.ToMFBytes(legacyHeader{
+			Version:          h.Version,
+			Height:           h.Height,
+			PrevBlock:        h.PrevBlock,
+			TransactionsRoot: h.TransactionsRoot,
+			Timestamp:        h.Timestamp,
+		})
+
+	case CurrentVersion:
+		return structSerializer.ToMFBytes(h)
+
+	default:
+		err = errors.New("unsupported block version: " + h.Version)
+		return
+	}
+}
+
 func (e *Entity) Sign(tools crypto.Tools, privateKey []byte) (err error) {
-	blockHeaderBytes, _ := structSerializer.ToMFBytes(e.EntityData.Header)
+	blockHeaderBytes, err := e.headerBytes()
+	if err != nil {
+		return
+	}
+
 	err = e.Seal.Sign(blockHeaderBytes, tools, privateKey)
 	return
 }
 
 func (e *Entity) Verify(tools crypto.Tools) (passed bool, err error) {
 	passed = false
-	blockHeaderBytes, _ := structSerializer.ToMFBytes(e.EntityData.Header)
+	blockHeaderBytes, err := e.headerBytes()
+	if err != nil {
+		return
+	}
 
 	passed, err = e.Seal.Verify(blockHeaderBytes, tools.HashCalculator)
 	if err != nil {
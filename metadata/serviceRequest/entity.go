@@ -31,4 +31,7 @@ type Entity struct {
 	EntityData
 
 	CustomerSeal seal.Entity
+
+	//set by the engine when the request is decided, if the consensus builds a finality proof
+	FinalityProof []byte
 }
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package network
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+//a peer failed the authentication or the admission is refused for a while, the one failed the authentication is
+//blacklisted by its ip:port, and the one refused by the admission is blacklisted by its proven node id.
+const blacklistDuration = 10 * time.Minute
+const rejectedPeersKept = 256
+
+//the admission decides the peers allowed to link by the node id proved by the secure handshake
+type IAdmission interface {
+	Allowed(peerID string) bool
+}
+
+//the allowlist admits the node ids from the config and the keys from a registry on chain,
+//such as the members of the validator set.
+type Allowlist struct {
+	ids      map[string]bool
+	registry func() (keys [][]byte, err error)
+}
+
+func NewAllowlist(ids []string, registry func() (keys [][]byte, err error)) *Allowlist {
+	a := &Allowlist{
+		ids:      map[string]bool{},
+		registry: registry,
+	}
+
+	for _, id := range ids {
+		a.ids[strings.ToLower(id)] = true
+	}
+
+	return a
+}
+
+func (a *Allowlist) Allowed(peerID string) bool {
+	peerID = strings.ToLower(peerID)
+	if a.ids[peerID] {
+		return true
+	}
+
+	if a.registry == nil {
+		return false
+	}
+
+	keys, err := a.registry()
+	if err != nil {
+		return false
+	}
+
+	for _, k := range keys {
+		if hex.EncodeToString(k) == peerID {
+			return true
+		}
+	}
+
+	return false
+}
+
+type RejectedPeer struct {
+	Local   string
+	Address string
+	PeerID  string
+	Reason  string
+	Time    int64
+}
+
+var rejectedPeers struct {
+	list []RejectedPeer
+	lock sync.Mutex
+}
+
+func recordRejectedPeer(peer RejectedPeer) {
+	rejectedPeers.lock.Lock()
+	defer rejectedPeers.lock.Unlock()
+
+	peer.Time = time.Now().Unix()
+	rejectedPeers.list = append(rejectedPeers.list, peer)
+	if len(rejectedPeers.list) > rejectedPeersKept {
+		rejectedPeers.list = rejectedPeers.list[len(rejectedPeers.list)-rejectedPeersKept:]
+	}
+}
+
+//the last rejected join attempts of all the networks, the latest last
+func RejectedPeers() (list []RejectedPeer) {
+	rejectedPeers.lock.Lock()
+	defer rejectedPeers.lock.Unlock()
+
+	return append(list, rejectedPeers.list...)
+}
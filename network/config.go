@@ -17,6 +17,8 @@
 
 package network
 
+import "github.com/SealSC/SealABC/crypto/signers/signerCommon"
+
 type Config struct {
 	ID string
 
@@ -27,6 +29,18 @@ type Config struct {
 
 	P2PSeeds []string
 
+	//the nodes of different networks refuse to peer, such as the nodes built from different genesis
+	NetworkID string
+
+	//the links are authenticated by the node key and encrypted if the signer is set, the ID must be its public key
+	Signer signerCommon.ISigner
+
+	//only the peers admitted are linked, it needs the signer to prove the ids of the peers
+	Admission IAdmission
+
+	//the limits of the messages received from every peer
+	LinkLimits LinkLimits
+
 	Topology ITopology
 	Router   IRouter
 }
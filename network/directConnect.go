@@ -39,6 +39,11 @@ type ITopology interface {
 	RemoveLink(link ILink)
 }
 
+//a topology broadcasts by itself if it implements this, such as by gossip, otherwise the router sends to all the nodes
+type IBroadcastTopology interface {
+	Broadcast(msg Message) (err error)
+}
+
 type directConnect struct {
 	LocalNode LinkNode
 }
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/dataStructure/mfb"
+	"github.com/golang/snappy"
+)
+
+//the data types in the preferred order, the peers negotiate one of them when joining, and json is the fallback.
+//a link always accepts all of them, so the messages sent before the negotiation are never lost.
+var SupportedDataTypes = []byte{
+	BINARY_SNAPPY_TYPE,
+	BINARY_TYPE,
+	JSON_SNAPPY_TYPE,
+	JSON_TYPE,
+}
+
+func IsSupportedDataType(dataType byte) bool {
+	for _, t := range SupportedDataTypes {
+		if t == dataType {
+			return true
+		}
+	}
+
+	return false
+}
+
+//the first local preferred data type offered by the peer
+func NegotiateDataType(offered []byte) (dataType byte) {
+	for _, t := range SupportedDataTypes {
+		for _, o := range offered {
+			if o == t {
+				return t
+			}
+		}
+	}
+
+	return JSON_TYPE
+}
+
+func isCompressedType(dataType byte) bool {
+	return dataType == JSON_SNAPPY_TYPE || dataType == BINARY_SNAPPY_TYPE
+}
+
+func isBinaryType(dataType byte) bool {
+	return dataType == BINARY_TYPE || dataType == BINARY_SNAPPY_TYPE
+}
+
+//the binary form lists every field of a message flat, the embedded message and the from node are flattened
+//here explicitly, so the form never depends on how a serializer walks the embedded structs.
+const binaryMessageFields = 11
+
+func binaryMessageBytes(fields [][]byte) []byte {
+	data := mfb.MarkedFlatBytes{}
+	data.FromByteSlice(fields)
+	return data
+}
+
+func toBinaryMessage(m Message) []byte {
+	return binaryMessageBytes([][]byte{
+		[]byte(m.Family),
+		[]byte(m.Version),
+		[]byte(m.Type),
+		m.Payload,
+		m.Hash,
+		m.Signature,
+		[]byte(m.From.ID),
+		[]byte(m.From.Protocol),
+		[]byte(m.From.ServeAddress),
+		m.From.CustomerData,
+		[]byte(m.From.NetworkID),
+	})
+}
+
+//the fields are copied out of the data, so the data could be reused
+func fromBinaryMessage(data []byte, m *Message) (err error) {
+	fields, err := mfb.MarkedFlatBytes(data).ToByteSlice()
+	if err != nil {
+		return
+	}
+
+	//the fields must cover the whole data exactly, an empty, short or padded message is malformed
+	if len(fields) != binaryMessageFields || !bytes.Equal(binaryMessageBytes(fields), data) {
+		err = errors.New("malformed binary message")
+		return
+	}
+
+	for i, f := range fields {
+		if len(f) == 0 {
+			fields[i] = nil
+		}
+	}
+
+	*m = Message{}
+	m.Family = string(fields[0])
+	m.Version = string(fields[1])
+	m.Type = string(fields[2])
+	m.Payload = fields[3]
+	m.Hash = fields[4]
+	m.Signature = fields[5]
+	m.From.ID = string(fields[6])
+	m.From.Protocol = string(fields[7])
+	m.From.ServeAddress = string(fields[8])
+	m.From.CustomerData = fields[9]
+	m.From.NetworkID = string(fields[10])
+	return
+}
+
+func encodeMessage(m Message, dataType byte) (data []byte, err error) {
+	if !IsSupportedDataType(dataType) {
+		err = errors.New("unsupported data type")
+		return
+	}
+
+	if isBinaryType(dataType) {
+		data = toBinaryMessage(m)
+	} else {
+		data, err = json.Marshal(m)
+		if err != nil {
+			return
+		}
+	}
+
+	if isCompressedType(dataType) {
+		data = snappy.Encode(nil, data)
+	}
+
+	return
+}
+
+func decodeMessage(data []byte, dataType byte, m *Message) (err error) {
+	if !IsSupportedDataType(dataType) {
+		err = errors.New("unsupported data type")
+		return
+	}
+
+	if isCompressedType(dataType) {
+		size, lenErr := snappy.DecodedLen(data)
+		if lenErr != nil {
+			err = lenErr
+			return
+		}
+
+		if size > MAX_MESSAGE_LEN {
+			err = errors.New("message too large")
+			return
+		}
+
+		data, err = snappy.Decode(nil, data)
+		if err != nil {
+			return
+		}
+	}
+
+	if isBinaryType(dataType) {
+		err = fromBinaryMessage(data, m)
+	} else {
+		err = json.Unmarshal(data, m)
+	}
+
+	return
+}
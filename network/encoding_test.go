@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/message"
+)
+
+func newTestMessage() (m Message) {
+	m.Message = message.Message{
+		Family:    "testFamily",
+		Version:   "1",
+		Type:      "testType",
+		Payload:   []byte("payload"),
+		Hash:      []byte("hash"),
+		Signature: []byte("signature"),
+	}
+
+	m.From = Node{
+		ID:           "node id",
+		Protocol:     "tcp",
+		ServeAddress: "127.0.0.1:8080",
+		CustomerData: []byte("customer data"),
+		NetworkID:    "network id",
+	}
+	return
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	log.SetUpLogger(log.Config{})
+
+	//the empty fields must survive the round trip too
+	partial := newTestMessage()
+	partial.Payload = nil
+	partial.From.ID = ""
+
+	for _, dataType := range SupportedDataTypes {
+		for _, m := range []Message{newTestMessage(), partial} {
+			raw, err := m.ToEncodedRawMessage(dataType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prefix := MessagePrefix{}
+			if err = prefix.FromBytes(raw[:MESSAGE_PREFIX_LEN]); err != nil {
+				t.Fatal(err)
+			}
+
+			if prefix.DataType != dataType || int(prefix.Size) != len(raw)-MESSAGE_PREFIX_LEN {
+				t.Fatal("wrong prefix of data type ", dataType)
+			}
+
+			decoded := Message{}
+			if err = decoded.FromEncodedMessage(dataType, raw[MESSAGE_PREFIX_LEN:]); err != nil {
+				t.Fatal("decode data type ", dataType, " failed: ", err)
+			}
+
+			if !reflect.DeepEqual(m, decoded) {
+				t.Fatal("message changed by data type ", dataType, ": ", decoded)
+			}
+		}
+	}
+}
+
+func TestMalformedMessageRejected(t *testing.T) {
+	log.SetUpLogger(log.Config{})
+
+	for _, dataType := range SupportedDataTypes {
+		data, err := encodeMessage(newTestMessage(), dataType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		malformed := map[string][]byte{
+			"empty":  {},
+			"short":  data[:len(data)/2],
+			"padded": append(append([]byte{}, data...), 0, 0, 0, 1, 0),
+		}
+
+		for name, d := range malformed {
+			m := Message{}
+			if m.FromEncodedMessage(dataType, d) == nil {
+				t.Fatal(name, " message of data type ", dataType, " decoded")
+			}
+		}
+	}
+}
+
+//the binary decoded message must not refer to the receiving buffer
+func TestBinaryMessageCopied(t *testing.T) {
+	data, err := encodeMessage(newTestMessage(), BINARY_TYPE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Message{}
+	if err = decodeMessage(data, BINARY_TYPE, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range data {
+		data[i] = 0
+	}
+
+	if !reflect.DeepEqual(m, newTestMessage()) {
+		t.Fatal("decoded message changed with the buffer")
+	}
+}
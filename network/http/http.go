@@ -18,6 +18,7 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"github.com/SealSC/SealABC/log"
 	"github.com/gin-gonic/gin"
@@ -26,6 +27,8 @@ import (
 
 type Server struct {
 	Config *Config
+
+	httpServer *http.Server
 }
 
 func (s *Server) Start() (err error) {
@@ -52,9 +55,14 @@ func (s *Server) Start() (err error) {
 	})
 	s.setRouters(router, *s.Config)
 
+	s.httpServer = &http.Server{
+		Addr:    s.Config.Address,
+		Handler: router,
+	}
+
 	go func() {
-		runSrvErr := router.Run(s.Config.Address)
-		if runSrvErr != nil {
+		runSrvErr := s.httpServer.ListenAndServe()
+		if runSrvErr != nil && runSrvErr != http.ErrServerClosed {
 			log.Log.Warn("start http server failed: ", runSrvErr.Error())
 		}
 	}()
@@ -62,6 +70,15 @@ func (s *Server) Start() (err error) {
 	return
 }
 
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	if s.httpServer == nil {
+		return
+	}
+
+	err = s.httpServer.Shutdown(ctx)
+	return
+}
+
 func (s *Server) setRouters(router *gin.Engine, cfg Config) {
 	for _, v := range cfg.RequestHandler {
 		v.RouteRegister(router)
@@ -23,10 +23,8 @@ import (
 	"io"
 	"net"
 	"sync"
-)
-
-const (
-	max_buffer_size = 10 * 1024 * 1024
+	"sync/atomic"
+	"time"
 )
 
 type ILink interface {
@@ -35,41 +33,194 @@ type ILink interface {
 	SendData(data []byte) (n int, err error)
 	SendMessage(msg Message) (n int, err error)
 	RemoteAddr() net.Addr
+	PeerID() string
+	SetDataType(dataType byte)
+	DataType() byte
+	Deliver(msg Message, via ILink)
 	Close()
 }
 
 type Link struct {
-	Connection          net.Conn
-	Reader              *bufio.Reader
-	Writer              *bufio.Writer
-	RawMessageProcessor RawMessageProcessor
-	LinkClosed          LinkClosed
-	ConnectOut          bool
+	Connection               net.Conn
+	Reader                   *bufio.Reader
+	Writer                   *bufio.Writer
+	ReceivedMessageProcessor ReceivedMessageProcessor
+	LinkClosed               LinkClosed
+	ConnectOut               bool
+	Limits                   LinkLimits
 
 	senderLock sync.Mutex
+	closed     int32
+	peerID     string
+	dataType   byte
+
+	queues      [PRIORITY_COUNT]chan receivedMessage
+	stopped     chan bool
+	msgLimiter  *rateLimiter
+	byteLimiter *rateLimiter
+	throttled   int
 }
 
 func (l *Link) RemoteAddr() net.Addr {
 	return l.Connection.RemoteAddr()
 }
 
+//the node id proved by the secure handshake, it's empty on a plain link
+func (l *Link) PeerID() string {
+	return l.peerID
+}
+
+//the data type negotiated with the peer, the messages sent by the link are encoded by it
+func (l *Link) SetDataType(dataType byte) {
+	l.senderLock.Lock()
+	defer l.senderLock.Unlock()
+
+	l.dataType = dataType
+}
+
+func (l *Link) DataType() byte {
+	l.senderLock.Lock()
+	defer l.senderLock.Unlock()
+
+	return l.dataType
+}
+
 func (l *Link) Start() {
 
 	l.Reader = bufio.NewReader(l.Connection)
 	l.Writer = bufio.NewWriter(l.Connection)
 
+	queueSize := l.Limits.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultLinkQueueSize
+	}
+
+	for i := range l.queues {
+		l.queues[i] = make(chan receivedMessage, queueSize)
+	}
+
+	l.stopped = make(chan bool)
+	l.msgLimiter = newRateLimiter(l.Limits.MessagesPerSecond)
+	l.byteLimiter = newRateLimiter(l.Limits.BytesPerSecond)
+
+	workers := l.Limits.Workers
+	if workers <= 0 {
+		workers = defaultLinkWorkers
+	}
+
+	for i := 0; i < workers; i++ {
+		go l.processMessages()
+	}
+
 	go l.StartReceiving()
 }
 
+//the message of the highest priority queued, ok is false if the link is stopped
+func (l *Link) nextMessage() (received receivedMessage, ok bool) {
+	for _, q := range l.queues {
+		select {
+		case received = <-q:
+			return received, true
+		default:
+		}
+	}
+
+	select {
+	case received = <-l.queues[PRIORITY_HIGH]:
+	case received = <-l.queues[PRIORITY_NORMAL]:
+	case received = <-l.queues[PRIORITY_LOW]:
+	case <-l.stopped:
+		return received, false
+	}
+
+	return received, true
+}
+
+func (l *Link) processMessages() {
+	for {
+		received, ok := l.nextMessage()
+		if !ok {
+			return
+		}
+
+		l.ReceivedMessageProcessor(received.msg, received.link)
+	}
+}
+
+//wait if the peer sends faster than the limits, false if it's throttled too many times in a row
+func (l *Link) throttle(size int) bool {
+	wait := l.msgLimiter.take(1)
+	if byteWait := l.byteLimiter.take(size); byteWait > wait {
+		wait = byteWait
+	}
+
+	if wait == 0 {
+		l.throttled = 0
+		return true
+	}
+
+	l.throttled += 1
+	if l.Limits.DisconnectAfter > 0 && l.throttled >= l.Limits.DisconnectAfter {
+		return false
+	}
+
+	time.Sleep(wait)
+	return true
+}
+
+//the reader waits for a full queue, except the low priority messages are dropped
+func (l *Link) enqueue(msg Message) {
+	priority := queuePriority(msg)
+	received := receivedMessage{
+		msg:  msg,
+		link: l,
+	}
+
+	if priority == PRIORITY_LOW {
+		select {
+		case l.queues[priority] <- received:
+		default:
+			log.Log.Warn("drop the message of type ", msg.Type, " from ", l.RemoteAddr().String(), " for the full queue")
+		}
+		return
+	}
+
+	select {
+	case l.queues[priority] <- received:
+	case <-l.stopped:
+	}
+}
+
+//queue a message relayed by the peer, it's processed as received from the via link in the queues of this link.
+//the message is dropped if the queue is full, the deliverer is a message processor and can't wait for the queue.
+//the relayed message is inside a message already counted by the rate limits of this link.
+func (l *Link) Deliver(msg Message, via ILink) {
+	if l.stopped == nil {
+		return
+	}
+
+	select {
+	case <-l.stopped:
+		return
+	default:
+	}
+
+	select {
+	case l.queues[queuePriority(msg)] <- receivedMessage{msg: msg, link: via}:
+	default:
+		log.Log.Warn("drop the message of type ", msg.Type, " relayed by ", l.RemoteAddr().String(), " for the full queue")
+	}
+}
+
 func (l *Link) StartReceiving() {
 	defer func() {
+		close(l.stopped)
 		l.Close()
 		l.LinkClosed(l)
 	}()
 
 	for {
 		msgPrefix := make([]byte, MESSAGE_PREFIX_LEN, MESSAGE_PREFIX_LEN)
-		data := make([]byte, max_buffer_size, max_buffer_size)
 
 		n, err := io.ReadFull(l.Reader, msgPrefix) //l.Reader.Read(msgPrefix[:])
 		if n != MESSAGE_PREFIX_LEN {
@@ -77,7 +228,7 @@ func (l *Link) StartReceiving() {
 		}
 
 		if err != nil {
-			if err == io.EOF {
+			if err == io.EOF || l.isClosed() {
 				log.Log.Println("disconnect remote: ", err)
 				break
 			}
@@ -94,22 +245,25 @@ func (l *Link) StartReceiving() {
 			continue
 		}
 
-		if prefix.Size > MAX_MESSAGE_LEN {
+		if prefix.Size < 0 || prefix.Size > MAX_MESSAGE_LEN {
 			_, _ = l.Reader.Discard(int(prefix.Size))
 			continue
 		}
 
-		n, err = io.ReadFull(l.Reader, data[:prefix.Size])
+		data := getBuffer(int(prefix.Size))
+		n, err = io.ReadFull(l.Reader, data)
 		//n, err := l.Reader.Read(data[:prefix.Size])
 
 		if int32(n) != prefix.Size {
 			log.Log.Println("error message: need ", prefix.Size, "bytes bug got ", n, " bytes")
 			//log.Log.Println("error ", err.Error())
+			putBuffer(data)
 			return
 		}
 
 		if err != nil {
-			if err == io.EOF {
+			putBuffer(data)
+			if err == io.EOF || l.isClosed() {
 				log.Log.Println("disconnect remote: ", err)
 				break
 			}
@@ -117,12 +271,25 @@ func (l *Link) StartReceiving() {
 			continue
 		}
 
-		go l.RawMessageProcessor(data[:prefix.Size], l)
+		if !l.throttle(MESSAGE_PREFIX_LEN + n) {
+			log.Log.Warn("disconnect ", l.RemoteAddr().String(), " for exceeding the rate limits")
+			putBuffer(data)
+			return
+		}
+
+		msg := Message{}
+		err = msg.FromEncodedMessage(prefix.DataType, data)
+		putBuffer(data)
+		if err != nil {
+			continue
+		}
+
+		l.enqueue(msg)
 	}
 }
 
 func (l *Link) SendMessage(msg Message) (n int, err error) {
-	data, err := msg.ToRawMessage()
+	data, err := msg.ToEncodedRawMessage(l.DataType())
 	if err != nil {
 		return
 	}
@@ -155,6 +322,12 @@ func (l *Link) SendData(data []byte) (n int, err error) {
 	return
 }
 
+//the receiver checks it to tell a local close from a network error, so it's accessed atomically
+func (l *Link) isClosed() bool {
+	return atomic.LoadInt32(&l.closed) == 1
+}
+
 func (l *Link) Close() {
+	atomic.StoreInt32(&l.closed, 1)
 	l.Connection.Close()
 }
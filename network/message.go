@@ -42,7 +42,10 @@ const (
 
 	MAX_MESSAGE_LEN = 8 * 1024 * 1024 //raw message max size will be (8 MB + MESSAGE_PREFIX_LEN) bytes.
 
-	JSON_TYPE = 0x00
+	JSON_TYPE          = 0x00
+	BINARY_TYPE        = 0x01
+	JSON_SNAPPY_TYPE   = 0x02
+	BINARY_SNAPPY_TYPE = 0x03
 )
 
 type Message struct {
@@ -51,21 +54,25 @@ type Message struct {
 }
 
 func (m Message) ToRawMessage() (rawMsg []byte, err error) {
-	msgJson, err := json.Marshal(m)
+	return m.ToEncodedRawMessage(JSON_TYPE)
+}
+
+func (m Message) ToEncodedRawMessage(dataType byte) (rawMsg []byte, err error) {
+	msgData, err := encodeMessage(m, dataType)
 	if err != nil {
-		log.Log.Println("marshal message faild: ", err)
+		log.Log.Println("encode message faild: ", err)
 		return
 	}
 
-	msgSize := len(msgJson)
+	msgSize := len(msgData)
 
 	rawMsg = append([]byte(MAGIC_WORD))
-	rawMsg = append(rawMsg, JSON_TYPE)
+	rawMsg = append(rawMsg, dataType)
 
 	msgSizeBytes := make([]byte, SIZE_LEN, SIZE_LEN)
 	binary.BigEndian.PutUint32(msgSizeBytes, uint32(msgSize))
 	rawMsg = append(rawMsg, msgSizeBytes...)
-	rawMsg = append(rawMsg, msgJson...)
+	rawMsg = append(rawMsg, msgData...)
 
 	return
 }
@@ -80,6 +87,18 @@ func (m *Message) FromRawMessage(msgData []byte) (err error) {
 	return
 }
 
+func (m *Message) FromEncodedMessage(dataType byte, msgData []byte) (err error) {
+	if dataType == JSON_TYPE {
+		return m.FromRawMessage(msgData)
+	}
+
+	err = decodeMessage(msgData, dataType, m)
+	if err != nil {
+		log.Log.Println("decode message of type ", dataType, " failed: ", err)
+	}
+	return
+}
+
 type MessagePrefix struct {
 	Size     int32
 	DataType byte
@@ -97,8 +116,8 @@ func (m *MessagePrefix) FromBytes(prefix []byte) (err error) {
 	dataType := prefix[off]
 	off += SIZE_DATA_TYPE
 
-	if dataType != JSON_TYPE {
-		err = errors.New("only support json for now")
+	if !IsSupportedDataType(dataType) {
+		err = errors.New("unsupported data type")
 		return
 	}
 
@@ -31,6 +31,7 @@ type IService interface {
 	Self() (node Node)
 
 	Create(cfg Config) (err error)
+	Stop()
 	ConnectTo(Node) (err error)
 
 	Join(seeds []Node, cfg *Config) (err error)
@@ -98,6 +99,16 @@ func (s *Service) Create(cfg Config) (err error) {
 	return
 }
 
+func (s *Service) Stop() {
+	if !s.started {
+		return
+	}
+
+	s.router.LeaveTopology()
+	s.router.Stop()
+	s.started = false
+}
+
 func (s *Service) Join(seeds []Node, serviceCfg *Config) (err error) {
 	if !s.started && serviceCfg != nil {
 		err = s.Create(*serviceCfg)
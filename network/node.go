@@ -22,6 +22,7 @@ type Node struct {
 	Protocol     string
 	ServeAddress string
 	CustomerData []byte
+	NetworkID    string
 }
 
 type LinkNode struct {
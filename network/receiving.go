@@ -0,0 +1,228 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+//the messages received from a link are processed by priority, the consensus before the sync before the gossip
+const (
+	PRIORITY_HIGH   = 0
+	PRIORITY_NORMAL = 1
+	PRIORITY_LOW    = 2
+
+	PRIORITY_COUNT = 3
+)
+
+const (
+	defaultLinkWorkers     = 1
+	defaultLinkQueueSize   = 256
+	defaultRouterQueueSize = 1024
+
+	minBufferSize = 4 * 1024
+)
+
+//the limits of the messages received from every peer, the zero values are the defaults and no rate limit.
+//a peer sending faster than the rates is throttled, and disconnected if it's throttled for too many messages in a row.
+type LinkLimits struct {
+	Workers           int
+	QueueSize         int
+	MessagesPerSecond int
+	BytesPerSecond    int
+	DisconnectAfter   int
+}
+
+var messagePriorities = struct {
+	families map[string]int
+	types    map[string]int
+	lock     sync.RWMutex
+}{
+	families: map[string]int{},
+	types:    map[string]int{},
+}
+
+//set the priority of a message family, or of a type of the family if the type is not empty.
+//the messages not set are in the normal priority.
+func SetMessagePriority(family string, msgType string, priority int) {
+	messagePriorities.lock.Lock()
+	defer messagePriorities.lock.Unlock()
+
+	if msgType == "" {
+		messagePriorities.families[family] = priority
+	} else {
+		messagePriorities.types[family+"/"+msgType] = priority
+	}
+}
+
+func messagePriority(msg Message) int {
+	messagePriorities.lock.RLock()
+	defer messagePriorities.lock.RUnlock()
+
+	if priority, exists := messagePriorities.types[msg.Family+"/"+msg.Type]; exists {
+		return priority
+	}
+
+	if priority, exists := messagePriorities.families[msg.Family]; exists {
+		return priority
+	}
+
+	return PRIORITY_NORMAL
+}
+
+//the priority of the queue a message goes to, the invalid priorities are the normal priority
+func queuePriority(msg Message) int {
+	priority := messagePriority(msg)
+	if priority < PRIORITY_HIGH || priority > PRIORITY_LOW {
+		priority = PRIORITY_NORMAL
+	}
+
+	return priority
+}
+
+//the messages of all the links are queued to the router by priority, and one router worker dispatches them in
+//priority order, so the high priority messages of a link never wait for the low priority ones of the others,
+//and the message processors are still called one by one.
+type receivedMessage struct {
+	msg  Message
+	link ILink
+}
+
+func (r *Router) startDispatching() {
+	for i := range r.received {
+		r.received[i] = make(chan receivedMessage, defaultRouterQueueSize)
+	}
+
+	r.dispatchStopped = make(chan bool)
+	go r.dispatchMessages()
+}
+
+//the message of the highest priority queued, ok is false if the router is stopped
+func (r *Router) nextReceivedMessage() (received receivedMessage, ok bool) {
+	for _, q := range r.received {
+		select {
+		case received = <-q:
+			return received, true
+		default:
+		}
+	}
+
+	select {
+	case received = <-r.received[PRIORITY_HIGH]:
+	case received = <-r.received[PRIORITY_NORMAL]:
+	case received = <-r.received[PRIORITY_LOW]:
+	case <-r.dispatchStopped:
+		return received, false
+	}
+
+	return received, true
+}
+
+func (r *Router) dispatchMessages() {
+	for {
+		received, ok := r.nextReceivedMessage()
+		if !ok {
+			return
+		}
+
+		r.processReceivedMessage(received.msg, received.link)
+	}
+}
+
+//the receiving buffers are pooled by the power of 2 sizes from minBufferSize to the max message size
+var bufferPools = newBufferPools()
+
+func newBufferPools() (pools []*sync.Pool) {
+	for size := minBufferSize; size < MAX_MESSAGE_LEN*2; size *= 2 {
+		bufferSize := size
+		pools = append(pools, &sync.Pool{
+			New: func() interface{} {
+				return make([]byte, bufferSize, bufferSize)
+			},
+		})
+	}
+
+	return
+}
+
+func bufferPoolIndex(size int) (index int) {
+	for bufferSize := minBufferSize; bufferSize < size; bufferSize *= 2 {
+		index += 1
+	}
+
+	return
+}
+
+func getBuffer(size int) []byte {
+	index := bufferPoolIndex(size)
+	if index >= len(bufferPools) {
+		return make([]byte, size, size)
+	}
+
+	return bufferPools[index].Get().([]byte)[:size]
+}
+
+func putBuffer(buf []byte) {
+	index := bufferPoolIndex(cap(buf))
+	if index >= len(bufferPools) || minBufferSize<<uint(index) != cap(buf) {
+		return
+	}
+
+	bufferPools[index].Put(buf[:cap(buf)])
+}
+
+//a token bucket refilled every second, the tokens could go negative, then the receiver waits for the debt
+type rateLimiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+//take the tokens and return how long to wait for them
+func (r *rateLimiter) take(n int) (wait time.Duration) {
+	if r == nil {
+		return
+	}
+
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.rate {
+		r.tokens = r.rate
+	}
+	r.last = now
+
+	r.tokens -= float64(n)
+	if r.tokens >= 0 {
+		return
+	}
+
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
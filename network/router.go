@@ -18,13 +18,16 @@
 package network
 
 import (
+	"errors"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/message"
 	"net"
 	"sync"
+	"time"
 )
 
-type RawMessageProcessor func(data []byte, link ILink)
+type ReceivedMessageProcessor func(msg Message, link ILink)
 type MessageProcessor func(msg Message) (reply *Message)
 type LinkClosed func(link ILink)
 
@@ -34,6 +37,7 @@ type IRouter interface {
 	TopologyName() string
 
 	Start(cfg Config) (err error)
+	Stop()
 	Listen(listener net.Listener)
 	ConnectTo(node Node) (linkedNode LinkNode, err error)
 
@@ -43,7 +47,7 @@ type IRouter interface {
 	LeaveTopology()
 	GetAllLinkedNode() (nodes []Node)
 
-	RawMessageProcessor(data []byte, link ILink)
+	ReceivedMessageProcessor(newMsg Message, link ILink)
 	RegisterMessageProcessor(msgFamily string, processor MessageProcessor)
 
 	SendTo(node Node, msg Message) (n int, err error)
@@ -55,7 +59,19 @@ type Router struct {
 	MessageProcessorMap map[string]MessageProcessor
 	LocalNode           LinkNode
 
-	rawProcessorLock sync.Mutex
+	received        [PRIORITY_COUNT]chan receivedMessage
+	dispatchStopped chan bool
+
+	listener  net.Listener
+	links     map[ILink]bool
+	linksLock sync.Mutex
+	signer    signerCommon.ISigner
+
+	linkLimits LinkLimits
+
+	admission     IAdmission
+	blacklist     map[string]time.Time
+	blacklistLock sync.Mutex
 }
 
 func (r *Router) Self() Node {
@@ -68,6 +84,7 @@ func (r *Router) TopologyName() string {
 
 func (r *Router) Start(cfg Config) (err error) {
 	r.MessageProcessorMap = map[string]MessageProcessor{}
+	r.links = map[ILink]bool{}
 	if cfg.Topology != nil {
 		r.Topology = cfg.Topology
 	} else {
@@ -75,34 +92,85 @@ func (r *Router) Start(cfg Config) (err error) {
 	}
 
 	r.Topology.MountTo(r)
+	r.startDispatching()
 
 	localNode := LinkNode{}
 	localNode.Protocol = cfg.ServiceProtocol
 	localNode.ServeAddress = cfg.ServiceAddress
+	localNode.NetworkID = cfg.NetworkID
 	if cfg.ID == "" {
 		localNode.ID = r.Topology.BuildNodeID(localNode.Node)
 	} else {
 		localNode.ID = cfg.ID
 	}
 
+	if cfg.Signer != nil {
+		if localNode.ID != cfg.Signer.PublicKeyString() {
+			err = errors.New("the node id is not the public key of the signer")
+			return
+		}
+
+		r.signer = cfg.Signer
+	}
+
+	if r.signer != nil {
+		r.blacklist = map[string]time.Time{}
+	}
+
+	if cfg.Admission != nil {
+		if r.signer == nil {
+			err = errors.New("the admission needs the secure link")
+			return
+		}
+
+		r.admission = cfg.Admission
+	}
+
+	r.linkLimits = cfg.LinkLimits
 	r.LocalNode = localNode
 	r.Topology.SetLocalNode(localNode)
 
 	log.Log.Println("[ I am ]: ", localNode.ID)
 
-	var listener net.Listener
 	if !cfg.ClientOnly {
-		listener, err = net.Listen(cfg.ServiceProtocol, cfg.ServiceAddress)
+		r.listener, err = net.Listen(cfg.ServiceProtocol, cfg.ServiceAddress)
 		if err != nil {
 			return
 		}
 
-		go r.Listen(listener)
+		go r.Listen(r.listener)
 	}
 
 	return
 }
 
+//stop listening and close all the links
+func (r *Router) Stop() {
+	if r.listener != nil {
+		_ = r.listener.Close()
+	}
+
+	close(r.dispatchStopped)
+
+	r.linksLock.Lock()
+	var allLinks []ILink
+	for l := range r.links {
+		allLinks = append(allLinks, l)
+	}
+	r.linksLock.Unlock()
+
+	for _, l := range allLinks {
+		l.Close()
+	}
+}
+
+func (r *Router) addLink(link ILink) {
+	r.linksLock.Lock()
+	defer r.linksLock.Unlock()
+
+	r.links[link] = true
+}
+
 func (r *Router) Listen(listener net.Listener) {
 	for {
 		conn, err := listener.Accept()
@@ -111,41 +179,172 @@ func (r *Router) Listen(listener net.Listener) {
 			break
 		}
 
-		newLink := Link{
-			Connection:          conn,
-			ConnectOut:          false,
-			RawMessageProcessor: r.RawMessageProcessor,
-			LinkClosed:          r.LinkClosed,
+		if r.signer == nil {
+			r.acceptLink(conn, "")
+			continue
 		}
 
-		r.Topology.AddLink(&newLink)
-		newLink.Start()
+		if r.isBlacklisted(conn.RemoteAddr().String()) {
+			_ = conn.Close()
+			continue
+		}
+
+		//a slow handshake must not block the others
+		go func(conn net.Conn) {
+			sc, hsErr := r.secureLink(conn, conn.RemoteAddr().String(), false)
+			if hsErr != nil {
+				return
+			}
+
+			r.acceptLink(sc, sc.peerID)
+		}(conn)
 	}
 }
 
+//run the secure handshake and the admission, the connection is closed if any fails.
+//only the peer failed the authentication or refused by the admission is blacklisted, a peer lost by a timeout,
+//an EOF or a reset is not.
+func (r *Router) secureLink(conn net.Conn, address string, initiator bool) (sc *secureConn, err error) {
+	blacklistKey := ""
+	sc, err = secureHandshake(conn, r.signer, initiator)
+	if err != nil {
+		if _, isAuthErr := err.(secureAuthError); isAuthErr {
+			blacklistKey = address
+		}
+	} else if r.isBlacklisted(peerBlacklistKey(sc.peerID)) {
+		err = errors.New("peer is blacklisted")
+	} else if r.admission != nil && !r.admission.Allowed(sc.peerID) {
+		err = errors.New("peer is not admitted")
+		blacklistKey = peerBlacklistKey(sc.peerID)
+	}
+
+	if err == nil {
+		return
+	}
+
+	log.Log.Warn("secure link with ", address, " failed: ", err.Error())
+	_ = conn.Close()
+
+	rejected := RejectedPeer{
+		Local:   r.LocalNode.ServeAddress,
+		Address: address,
+		Reason:  err.Error(),
+	}
+
+	if sc != nil {
+		rejected.PeerID = sc.peerID
+	}
+
+	if blacklistKey != "" {
+		r.addToBlacklist(blacklistKey)
+	}
+
+	recordRejectedPeer(rejected)
+	return
+}
+
+//the peers are blacklisted by the ip:port or by the proven node id
+func peerBlacklistKey(peerID string) string {
+	return "id:" + peerID
+}
+
+func (r *Router) addToBlacklist(key string) {
+	if r.blacklist == nil {
+		return
+	}
+
+	r.blacklistLock.Lock()
+	defer r.blacklistLock.Unlock()
+
+	r.blacklist[key] = time.Now().Add(blacklistDuration)
+}
+
+func (r *Router) isBlacklisted(key string) bool {
+	if r.blacklist == nil {
+		return false
+	}
+
+	r.blacklistLock.Lock()
+	defer r.blacklistLock.Unlock()
+
+	until, exists := r.blacklist[key]
+	if !exists {
+		return false
+	}
+
+	if time.Now().After(until) {
+		delete(r.blacklist, key)
+		return false
+	}
+
+	return true
+}
+
+func (r *Router) acceptLink(conn net.Conn, peerID string) {
+	newLink := Link{
+		Connection:               conn,
+		ConnectOut:               false,
+		ReceivedMessageProcessor: r.ReceivedMessageProcessor,
+		LinkClosed:               r.LinkClosed,
+		Limits:                   r.linkLimits,
+		peerID:                   peerID,
+	}
+
+	r.addLink(&newLink)
+	r.Topology.AddLink(&newLink)
+	newLink.Start()
+}
+
 func (r *Router) ConnectTo(node Node) (linkedNode LinkNode, err error) {
+	if r.isBlacklisted(node.ServeAddress) {
+		err = errors.New("node is blacklisted")
+		return
+	}
+
 	conn, err := net.Dial(node.Protocol, node.ServeAddress)
 	if err != nil {
 		log.Log.Println("got an error: ", err)
 		return
 	}
 
+	peerID := ""
+	if r.signer != nil {
+		sc, hsErr := r.secureLink(conn, node.ServeAddress, true)
+		if hsErr != nil {
+			err = hsErr
+			return
+		}
+
+		conn = sc
+		peerID = sc.peerID
+	}
+
 	link := Link{
-		Connection:          conn,
-		ConnectOut:          true,
-		RawMessageProcessor: r.RawMessageProcessor,
-		LinkClosed:          r.LinkClosed,
+		Connection:               conn,
+		ConnectOut:               true,
+		ReceivedMessageProcessor: r.ReceivedMessageProcessor,
+		LinkClosed:               r.LinkClosed,
+		Limits:                   r.linkLimits,
+		peerID:                   peerID,
 	}
+	r.addLink(&link)
 	link.Start()
 
 	linkedNode = NewNetworkNodeFromLink(&link)
 	linkedNode.ServeAddress = node.ServeAddress
-	linkedNode.ID = r.Topology.BuildNodeID(linkedNode.Node)
+	linkedNode.ID = peerID
+	if peerID == "" {
+		linkedNode.ID = r.Topology.BuildNodeID(linkedNode.Node)
+	}
 
 	return
 }
 
 func (r *Router) LinkClosed(link ILink) {
+	r.linksLock.Lock()
+	delete(r.links, link)
+	r.linksLock.Unlock()
+
 	r.Topology.RemoveLink(link)
 	return
 }
@@ -187,13 +386,40 @@ func (r *Router) RegisterMessageProcessor(msgFamily string, processor MessagePro
 	r.MessageProcessorMap[msgFamily] = processor
 }
 
-func (r *Router) RawMessageProcessor(data []byte, link ILink) {
-	r.rawProcessorLock.Lock()
-	defer r.rawProcessorLock.Unlock()
+//queue a message received from a link by its priority, the router dispatches it, see dispatchMessages.
+func (r *Router) ReceivedMessageProcessor(newMsg Message, link ILink) {
+	priority := queuePriority(newMsg)
+	received := receivedMessage{
+		msg:  newMsg,
+		link: link,
+	}
 
-	newMsg := Message{}
-	err := newMsg.FromRawMessage(data)
-	if err != nil {
+	if priority == PRIORITY_LOW {
+		select {
+		case r.received[priority] <- received:
+		default:
+			log.Log.Warn("drop the message of type ", newMsg.Type, " from ", link.RemoteAddr().String(), " for the full router queue")
+		}
+		return
+	}
+
+	select {
+	case r.received[priority] <- received:
+	case <-r.dispatchStopped:
+	}
+}
+
+func (r *Router) processReceivedMessage(newMsg Message, link ILink) {
+	//every message carries the sender, a link to another network is closed before it joins the topology
+	if newMsg.From.NetworkID != r.LocalNode.NetworkID {
+		log.Log.Warn("refuse the node of network [", newMsg.From.NetworkID, "] from ", link.RemoteAddr().String())
+		link.Close()
+		return
+	}
+
+	//a peer proved its node id can't send as another node
+	if peerID := link.PeerID(); peerID != "" && newMsg.From.ID != peerID {
+		log.Log.Warn("drop the message of node ", newMsg.From.ID, " from the link of node ", peerID)
 		return
 	}
 
@@ -212,19 +438,12 @@ func (r *Router) RawMessageProcessor(data []byte, link ILink) {
 	}
 
 	replyMsg.From = r.LocalNode.Node
-	rawMsg, err := replyMsg.ToRawMessage()
-	if err != nil {
-		return
-	}
-
-	n, err := link.SendData(rawMsg)
+	n, err := link.SendMessage(*replyMsg)
 
 	if err != nil {
 		log.Log.Println("reply message failed. ", err)
-		log.Log.Printf("\r\nshould sent %d real sent %d \r\n", len(rawMsg), n)
+		log.Log.Printf("\r\nreal sent %d \r\n", n)
 	}
-
-	rawMsg = nil
 }
 
 func (r *Router) SendTo(node Node, msg Message) (n int, err error) {
@@ -233,15 +452,11 @@ func (r *Router) SendTo(node Node, msg Message) (n int, err error) {
 		return
 	}
 
+	//the link encodes the message by the data type negotiated, a routed link forwards it by the topology
 	msg.From = r.LocalNode.Node
-	rawMsg, err := msg.ToRawMessage()
+	n, err = link.SendMessage(msg)
 	if err != nil {
-		return
-	}
-
-	n, err = link.SendData(rawMsg)
-	if err != nil {
-		log.Log.Error("send data failed: data length ", len(rawMsg), " error: ", err.Error())
+		log.Log.Error("send message failed: ", err.Error())
 	}
 
 	return
@@ -253,6 +468,10 @@ func (r *Router) Broadcast(msg message.Message) (err error) {
 		From:    r.LocalNode.Node,
 	}
 
+	if bt, ok := r.Topology.(IBroadcastTopology); ok {
+		return bt.Broadcast(networkMsg)
+	}
+
 	targets := r.Topology.GetAllNodes()
 	for _, t := range targets {
 		n := t.Node
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"github.com/SealSC/SealABC/log"
+)
+
+//a signer whose signatures are never valid
+type badSigner struct {
+	signerCommon.ISigner
+}
+
+func (s badSigner) Sign(data []byte) (signature []byte, err error) {
+	return make([]byte, 64), nil
+}
+
+func newTestSecureRouter(t *testing.T, admitted []string) *Router {
+	log.SetUpLogger(log.Config{})
+	return &Router{
+		signer:    newTestSigner(t),
+		admission: NewAllowlist(admitted, nil),
+		blacklist: map[string]time.Time{},
+	}
+}
+
+//the router accepts a link from a peer of the signer, the peer is closed at once if it's not signing
+func acceptTestPeer(t *testing.T, r *Router, peerSigner signerCommon.ISigner) (address string, err error) {
+	initiatorConn, responderConn := newTestConnPair(t)
+	address = responderConn.RemoteAddr().String()
+
+	if peerSigner == nil {
+		_ = initiatorConn.Close()
+	} else {
+		handshakeOn(initiatorConn, peerSigner, true)
+	}
+
+	_, err = r.secureLink(responderConn, address, false)
+	return
+}
+
+func TestBlacklistByPeerIDOnAdmissionRefused(t *testing.T) {
+	r := newTestSecureRouter(t, nil)
+	peer := newTestSigner(t)
+
+	address, err := acceptTestPeer(t, r, peer)
+	if err == nil {
+		t.Fatal("peer not admitted is linked")
+	}
+
+	if !r.isBlacklisted(peerBlacklistKey(peer.PublicKeyString())) {
+		t.Fatal("peer refused by the admission is not blacklisted by its id")
+	}
+
+	if r.isBlacklisted(address) {
+		t.Fatal("peer refused by the admission is blacklisted by its address")
+	}
+}
+
+func TestBlacklistByAddressOnAuthFailure(t *testing.T) {
+	peer := newTestSigner(t)
+	r := newTestSecureRouter(t, []string{peer.PublicKeyString()})
+
+	address, err := acceptTestPeer(t, r, badSigner{peer})
+	if err == nil {
+		t.Fatal("peer with an invalid signature is linked")
+	}
+
+	if !r.isBlacklisted(address) {
+		t.Fatal("peer failed the authentication is not blacklisted by its address")
+	}
+
+	if r.isBlacklisted(peerBlacklistKey(peer.PublicKeyString())) {
+		t.Fatal("the id of a peer not proven is blacklisted")
+	}
+}
+
+func TestNoBlacklistOnTransportFailure(t *testing.T) {
+	r := newTestSecureRouter(t, nil)
+
+	address, err := acceptTestPeer(t, r, nil)
+	if err == nil {
+		t.Fatal("closed peer is linked")
+	}
+
+	if r.isBlacklisted(address) || len(r.blacklist) != 0 {
+		t.Fatal("peer lost by the transport is blacklisted")
+	}
+}
+
+func TestAdmittedPeerLinked(t *testing.T) {
+	peer := newTestSigner(t)
+	r := newTestSecureRouter(t, []string{peer.PublicKeyString()})
+
+	if _, err := acceptTestPeer(t, r, peer); err != nil {
+		t.Fatal("admitted peer not linked: ", err)
+	}
+
+	if len(r.blacklist) != 0 {
+		t.Fatal("admitted peer is blacklisted")
+	}
+}
+
+func TestRouterDispatchByPriority(t *testing.T) {
+	log.SetUpLogger(log.Config{})
+	SetMessagePriority("testHigh", "", PRIORITY_HIGH)
+	SetMessagePriority("testLow", "", PRIORITY_LOW)
+
+	r := &Router{}
+	for i := range r.received {
+		r.received[i] = make(chan receivedMessage, 4)
+	}
+	r.dispatchStopped = make(chan bool)
+
+	//queued from different links, the low first
+	for _, family := range []string{"testLow", "testNormal", "testHigh"} {
+		msg := Message{}
+		msg.Family = family
+		r.ReceivedMessageProcessor(msg, &Link{})
+	}
+
+	for _, family := range []string{"testHigh", "testNormal", "testLow"} {
+		received, ok := r.nextReceivedMessage()
+		if !ok || received.msg.Family != family {
+			t.Fatal("dispatched ", received.msg.Family, " before ", family)
+		}
+	}
+
+	close(r.dispatchStopped)
+	if _, ok := r.nextReceivedMessage(); ok {
+		t.Fatal("message dispatched by a stopped router")
+	}
+}
+
+func TestLinkDeliverRelayed(t *testing.T) {
+	log.SetUpLogger(log.Config{})
+	conn, _ := newTestConnPair(t)
+
+	l := &Link{Connection: conn}
+	for i := range l.queues {
+		l.queues[i] = make(chan receivedMessage, 1)
+	}
+	l.stopped = make(chan bool)
+
+	via := &Link{}
+	relayed := Message{}
+	relayed.Type = "relayed"
+	l.Deliver(relayed, via)
+
+	//the queue is full, the deliverer never waits
+	l.Deliver(Message{}, via)
+
+	received, ok := l.nextMessage()
+	if !ok || received.msg.Type != "relayed" || received.link != via {
+		t.Fatal("relayed message not queued for the via link")
+	}
+
+	select {
+	case <-l.queues[PRIORITY_NORMAL]:
+		t.Fatal("message queued over the full queue")
+	default:
+	}
+
+	close(l.stopped)
+	l.Deliver(relayed, via)
+	if len(l.queues[PRIORITY_NORMAL]) != 0 {
+		t.Fatal("message queued to a stopped link")
+	}
+}
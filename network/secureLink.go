@@ -0,0 +1,293 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package network
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/SealSC/SealABC/crypto/signers"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+//the secure handshake authenticates both peers by their node keys and encrypts the link:
+//1. both sides send an ephemeral x25519 public key.
+//2. a key for each direction is derived from the shared secret and the transcript of the ephemeral keys.
+//3. both sides send the node public key and its signature of the transcript over the encrypted link, so a peer
+//   proves it holds the private key behind its node id, and the proof is bound to this link only.
+//every later frame is sealed with a counter nonce, so it can't be read, changed, replayed or reordered.
+const secureLinkPrologue = "SealABC-secure-link-v1"
+const secureHandshakeTimeout = 10 * time.Second
+
+//the max plain text of a frame, a larger write is split
+const secureFrameMax = 64 * 1024
+const secureFrameLenSize = 4
+
+const (
+	roleInitiator = "initiator"
+	roleResponder = "responder"
+)
+
+//the peer failed to prove its node key or to seal its frames, the failures of the transport are not this error
+type secureAuthError struct {
+	reason string
+}
+
+func (e secureAuthError) Error() string {
+	return e.reason
+}
+
+type secureAuth struct {
+	SignerAlgorithm string
+	PublicKey       []byte
+	Signature       []byte
+}
+
+type secureConn struct {
+	net.Conn
+
+	sendCipher  cipher.AEAD
+	recvCipher  cipher.AEAD
+	sendCounter uint64
+	recvCounter uint64
+	pending     []byte
+	peerID      string
+
+	sendLock sync.Mutex
+	recvLock sync.Mutex
+}
+
+func counterNonce(counter uint64) (nonce []byte) {
+	nonce = make([]byte, chacha20poly1305.NonceSize, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], counter)
+	return
+}
+
+func (s *secureConn) Write(p []byte) (n int, err error) {
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	for len(p) > 0 {
+		size := len(p)
+		if size > secureFrameMax {
+			size = secureFrameMax
+		}
+
+		sealed := s.sendCipher.Seal(nil, counterNonce(s.sendCounter), p[:size], nil)
+		s.sendCounter += 1
+
+		frame := make([]byte, secureFrameLenSize, secureFrameLenSize+len(sealed))
+		binary.BigEndian.PutUint32(frame, uint32(len(sealed)))
+		frame = append(frame, sealed...)
+
+		_, err = s.Conn.Write(frame)
+		if err != nil {
+			return
+		}
+
+		n += size
+		p = p[size:]
+	}
+
+	return
+}
+
+func (s *secureConn) readFrame() (plain []byte, err error) {
+	lenBytes := make([]byte, secureFrameLenSize, secureFrameLenSize)
+	_, err = io.ReadFull(s.Conn, lenBytes)
+	if err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(lenBytes)
+	if size > secureFrameMax+uint32(s.recvCipher.Overhead()) {
+		err = secureAuthError{"secure frame too large"}
+		return
+	}
+
+	sealed := make([]byte, size, size)
+	_, err = io.ReadFull(s.Conn, sealed)
+	if err != nil {
+		return
+	}
+
+	plain, err = s.recvCipher.Open(nil, counterNonce(s.recvCounter), sealed, nil)
+	if err != nil {
+		err = secureAuthError{"secure frame is broken"}
+		return
+	}
+
+	s.recvCounter += 1
+	return
+}
+
+func (s *secureConn) Read(p []byte) (n int, err error) {
+	s.recvLock.Lock()
+	defer s.recvLock.Unlock()
+
+	for len(s.pending) == 0 {
+		s.pending, err = s.readFrame()
+		if err != nil {
+			return
+		}
+	}
+
+	n = copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return
+}
+
+//derive the key of a direction, the info binds the key to the direction
+func deriveSecureKey(shared []byte, transcript []byte, info string) (aead cipher.AEAD, err error) {
+	key := make([]byte, chacha20poly1305.KeySize, chacha20poly1305.KeySize)
+	_, err = io.ReadFull(hkdf.New(sha256.New, shared, transcript, []byte(info)), key)
+	if err != nil {
+		return
+	}
+
+	return chacha20poly1305.New(key)
+}
+
+//the signed data of a peer, the role keeps a peer from reflecting the signature of the other side
+func secureAuthData(transcript []byte, role string) []byte {
+	data := sha256.Sum256(append(append([]byte{}, transcript...), []byte(role)...))
+	return data[:]
+}
+
+func verifySecureAuth(auth secureAuth, data []byte) (peerID string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = secureAuthError{"invalid peer signature"}
+		}
+	}()
+
+	signerGen := signers.SignerGeneratorByAlgorithmType(auth.SignerAlgorithm)
+	if signerGen == nil {
+		err = secureAuthError{"unsupported peer signature algorithm: " + auth.SignerAlgorithm}
+		return
+	}
+
+	peer, keyErr := signerGen.FromRawPublicKey(auth.PublicKey)
+	if keyErr != nil {
+		err = secureAuthError{"invalid peer public key: " + keyErr.Error()}
+		return
+	}
+
+	passed, err := peer.Verify(data, auth.Signature)
+	if err != nil || !passed {
+		err = secureAuthError{"invalid peer signature"}
+		return
+	}
+
+	peerID = peer.PublicKeyString()
+	return
+}
+
+//run the handshake on a new connection, the initiator is the side connected out
+func secureHandshake(conn net.Conn, signer signerCommon.ISigner, initiator bool) (sc *secureConn, err error) {
+	_ = conn.SetDeadline(time.Now().Add(secureHandshakeTimeout))
+	defer func() {
+		_ = conn.SetDeadline(time.Time{})
+	}()
+
+	ephemeral := make([]byte, curve25519.ScalarSize, curve25519.ScalarSize)
+	if _, err = rand.Read(ephemeral); err != nil {
+		return
+	}
+
+	localPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return
+	}
+
+	if _, err = conn.Write(localPub); err != nil {
+		return
+	}
+
+	remotePub := make([]byte, curve25519.PointSize, curve25519.PointSize)
+	if _, err = io.ReadFull(conn, remotePub); err != nil {
+		return
+	}
+
+	shared, err := curve25519.X25519(ephemeral, remotePub)
+	if err != nil {
+		return
+	}
+
+	localRole, remoteRole := roleInitiator, roleResponder
+	initiatorPub, responderPub := localPub, remotePub
+	if !initiator {
+		localRole, remoteRole = roleResponder, roleInitiator
+		initiatorPub, responderPub = remotePub, localPub
+	}
+
+	transcript := sha256.Sum256(append(append([]byte(secureLinkPrologue), initiatorPub...), responderPub...))
+
+	sc = &secureConn{Conn: conn}
+	initiatorCipher, err := deriveSecureKey(shared, transcript[:], roleInitiator)
+	if err != nil {
+		return
+	}
+
+	responderCipher, err := deriveSecureKey(shared, transcript[:], roleResponder)
+	if err != nil {
+		return
+	}
+
+	sc.sendCipher, sc.recvCipher = initiatorCipher, responderCipher
+	if !initiator {
+		sc.sendCipher, sc.recvCipher = responderCipher, initiatorCipher
+	}
+
+	sig, err := signer.Sign(secureAuthData(transcript[:], localRole))
+	if err != nil {
+		return
+	}
+
+	authBytes, _ := json.Marshal(secureAuth{
+		SignerAlgorithm: signer.Type(),
+		PublicKey:       signer.PublicKeyBytes(),
+		Signature:       sig,
+	})
+
+	if _, err = sc.Write(authBytes); err != nil {
+		return
+	}
+
+	remoteAuthBytes, err := sc.readFrame()
+	if err != nil {
+		return
+	}
+
+	remoteAuth := secureAuth{}
+	if json.Unmarshal(remoteAuthBytes, &remoteAuth) != nil {
+		err = secureAuthError{"invalid peer auth"}
+		return
+	}
+
+	sc.peerID, err = verifySecureAuth(remoteAuth, secureAuthData(transcript[:], remoteRole))
+	return
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto/signers/ed25519"
+	"github.com/SealSC/SealABC/crypto/signers/signerCommon"
+)
+
+func newTestSigner(t *testing.T) signerCommon.ISigner {
+	signer, err := ed25519.SignerGenerator.NewSigner(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+type handshakeResult struct {
+	conn *secureConn
+	err  error
+}
+
+func handshakeOn(conn net.Conn, signer signerCommon.ISigner, initiator bool) chan handshakeResult {
+	result := make(chan handshakeResult, 1)
+	go func() {
+		sc, err := secureHandshake(conn, signer, initiator)
+		result <- handshakeResult{conn: sc, err: err}
+	}()
+	return result
+}
+
+//a pipe blocks the writes of both sides, the handshake needs the buffers of a real connection
+func newTestConnPair(t *testing.T) (initiatorConn net.Conn, responderConn net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	initiatorConn, err = net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responderConn, err = listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = initiatorConn.Close()
+		_ = responderConn.Close()
+	})
+	return
+}
+
+func newTestSecurePair(t *testing.T, initiatorSigner signerCommon.ISigner, responderSigner signerCommon.ISigner) (initiator *secureConn, responder *secureConn) {
+	initiatorConn, responderConn := newTestConnPair(t)
+	initiatorResult := handshakeOn(initiatorConn, initiatorSigner, true)
+	responderResult := handshakeOn(responderConn, responderSigner, false)
+
+	i, r := <-initiatorResult, <-responderResult
+	if i.err != nil || r.err != nil {
+		t.Fatal("handshake failed: ", i.err, r.err)
+	}
+	return i.conn, r.conn
+}
+
+func TestSecureHandshake(t *testing.T) {
+	initiatorSigner, responderSigner := newTestSigner(t), newTestSigner(t)
+	initiator, responder := newTestSecurePair(t, initiatorSigner, responderSigner)
+
+	if initiator.peerID != responderSigner.PublicKeyString() || responder.peerID != initiatorSigner.PublicKeyString() {
+		t.Fatal("peer id is not the node key of the peer")
+	}
+
+	//larger than a frame, so it's split
+	data := bytes.Repeat([]byte("secure link data "), secureFrameMax/8)
+	go func() {
+		_, _ = initiator.Write(data)
+	}()
+
+	received := make([]byte, len(data))
+	if _, err := io.ReadFull(responder, received); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, received) {
+		t.Fatal("data changed by the secure link")
+	}
+
+	go func() {
+		_, _ = responder.Write([]byte("reply"))
+	}()
+
+	reply := make([]byte, 5)
+	if _, err := io.ReadFull(initiator, reply); err != nil || string(reply) != "reply" {
+		t.Fatal("reply not received: ", err)
+	}
+}
+
+//write a frame sealed with the counter to the raw connection
+func writeRawFrame(t *testing.T, sc *secureConn, counter uint64, plain []byte, tamper bool) {
+	sealed := sc.sendCipher.Seal(nil, counterNonce(counter), plain, nil)
+	if tamper {
+		sealed[0] ^= 0x01
+	}
+
+	frame := make([]byte, secureFrameLenSize, secureFrameLenSize+len(sealed))
+	binary.BigEndian.PutUint32(frame, uint32(len(sealed)))
+	go func() {
+		_, _ = sc.Conn.Write(append(frame, sealed...))
+	}()
+}
+
+func TestSecureFrameRejected(t *testing.T) {
+	cases := []struct {
+		name    string
+		counter func(sc *secureConn) uint64
+		tamper  bool
+	}{
+		{name: "changed", counter: func(sc *secureConn) uint64 { return sc.sendCounter }, tamper: true},
+		{name: "replayed", counter: func(sc *secureConn) uint64 { return sc.sendCounter - 1 }},
+		{name: "reordered", counter: func(sc *secureConn) uint64 { return sc.sendCounter + 1 }},
+	}
+
+	for _, c := range cases {
+		initiator, responder := newTestSecurePair(t, newTestSigner(t), newTestSigner(t))
+		writeRawFrame(t, initiator, c.counter(initiator), []byte("frame"), c.tamper)
+
+		if _, err := responder.Read(make([]byte, 16)); err == nil {
+			t.Fatal(c.name, " frame accepted")
+		}
+	}
+}
+
+func TestSecureAuth(t *testing.T) {
+	signer := newTestSigner(t)
+	transcript := []byte("transcript")
+
+	sig, err := signer.Sign(secureAuthData(transcript, roleInitiator))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := secureAuth{SignerAlgorithm: signer.Type(), PublicKey: signer.PublicKeyBytes(), Signature: sig}
+	peerID, err := verifySecureAuth(auth, secureAuthData(transcript, roleInitiator))
+	if err != nil || peerID != signer.PublicKeyString() {
+		t.Fatal("auth of the peer not verified: ", err)
+	}
+
+	//the signature of the other side reflected back
+	if _, err = verifySecureAuth(auth, secureAuthData(transcript, roleResponder)); err == nil {
+		t.Fatal("reflected signature verified")
+	}
+
+	if _, err = verifySecureAuth(auth, secureAuthData([]byte("other link"), roleInitiator)); err == nil {
+		t.Fatal("signature of another link verified")
+	}
+
+	otherKey := auth
+	otherKey.PublicKey = newTestSigner(t).PublicKeyBytes()
+	if _, err = verifySecureAuth(otherKey, secureAuthData(transcript, roleInitiator)); err == nil {
+		t.Fatal("signature verified by another node key")
+	}
+
+	unknown := auth
+	unknown.SignerAlgorithm = "unknown"
+	if _, err = verifySecureAuth(unknown, secureAuthData(transcript, roleInitiator)); err == nil {
+		t.Fatal("signature of an unknown algorithm verified")
+	}
+}
@@ -18,6 +18,7 @@
 package payload
 
 type Join struct {
-	TargetID string
-	SourceID string
+	TargetID  string
+	SourceID  string
+	DataTypes []byte
 }
@@ -18,6 +18,7 @@
 package payload
 
 type JoinReply struct {
-	PrevID string
-	RealID string
+	PrevID   string
+	RealID   string
+	DataType byte
 }
@@ -35,9 +35,11 @@ func (j *joinMessageProcessor) Process(msg network.Message, t *Topology, link ne
 		return
 	}
 
+	//the peer not offering any data type only knows json
 	joinReply := payload.JoinReply{
-		PrevID: join.TargetID,
-		RealID: t.LocalNode.ID,
+		PrevID:   join.TargetID,
+		RealID:   t.LocalNode.ID,
+		DataType: network.NegotiateDataType(join.DataTypes),
 	}
 	replyPayload, _ := json.Marshal(joinReply)
 
@@ -57,7 +59,11 @@ func (j *joinMessageProcessor) Process(msg network.Message, t *Topology, link ne
 	_, err = target.Link.SendData(rawReply)
 	if err != nil {
 		log.Log.Warn("send join reply failed: ", err.Error())
+		return
 	}
+
+	//the reply is in json, the peer switches after getting it
+	target.Link.SetDataType(joinReply.DataType)
 	return
 }
 
@@ -82,10 +88,14 @@ func (j *joinReplyMessageProcessor) Process(msg network.Message, t *Topology, li
 	target.Node = msg.From
 	t.setJoinedNode(target)
 
-	//doPing(link)
+	if network.IsSupportedDataType(joinReply.DataType) {
+		link.SetDataType(joinReply.DataType)
+	}
+
+	//doPing(link, t)
 
 	//get neighbors
-	getNeighbors(target)
+	getNeighbors(target, t)
 	return
 }
 
@@ -26,9 +26,10 @@ import (
 	"sync"
 )
 
-func getNeighbors(seed network.LinkNode) {
+func getNeighbors(seed network.LinkNode, t *Topology) {
 	log.Log.Println("get neighbors from: ", seed.ID)
 	msg := message.NewMessage(message.Types.GetNeighbors, []byte{})
+	msg.From = t.LocalNode.Node
 	_, err := seed.Link.SendMessage(msg)
 	if err != nil {
 		log.Log.Error("send get neighbors message failed: ", err.Error())
@@ -47,6 +48,7 @@ func (j *getNeighborsMessageProcessor) Process(msg network.Message, t *Topology,
 
 	replyPayload, _ := json.Marshal(neighbors)
 	replyMsg := message.NewMessage(message.Types.GetNeighborsReply, replyPayload)
+	replyMsg.From = t.LocalNode.Node
 
 	_, err = link.SendMessage(replyMsg)
 
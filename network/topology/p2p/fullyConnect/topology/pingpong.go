@@ -25,10 +25,11 @@ import (
 	"time"
 )
 
-func doPing(link network.ILink) {
+func doPing(link network.ILink, t *Topology) {
 	ping := payload.NewPing()
 	pingPayloadBytes, _ := json.Marshal(ping)
 	msg := message.NewMessage(message.Types.Ping, pingPayloadBytes)
+	msg.From = t.LocalNode.Node
 	link.SendMessage(msg)
 }
 
@@ -47,12 +48,13 @@ func (p *pingMessageProcessor) Process(msg network.Message, t *Topology, link ne
 
 	replayPayloadBytes, _ := json.Marshal(pong)
 	reply := message.NewMessage(message.Types.Pong, replayPayloadBytes)
+	reply.From = t.LocalNode.Node
 	link.SendMessage(reply)
 
 	go func() {
 		time.Sleep(time.Second * 10)
 
-		doPing(link)
+		doPing(link, t)
 	}()
 
 	return
@@ -42,6 +42,7 @@ func (t Topology) Name() string {
 
 func (t *Topology) MountTo(router network.IRouter) {
 	message.LoadMessageTypes()
+	network.SetMessagePriority(message.Family, "", network.PRIORITY_HIGH)
 
 	t.router = router
 
@@ -95,8 +96,9 @@ func (t *Topology) GetLocalNode() (node network.LinkNode) {
 func (t *Topology) Join(node network.LinkNode) (err error) {
 	t.preJoinNode[node.Link] = node
 	join := payload.Join{
-		TargetID: node.ID,
-		SourceID: t.LocalNode.ID,
+		TargetID:  node.ID,
+		SourceID:  t.LocalNode.ID,
+		DataTypes: network.SupportedDataTypes,
 	}
 
 	joinPayload, _ := json.Marshal(join)
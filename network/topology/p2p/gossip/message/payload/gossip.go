@@ -0,0 +1,27 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package payload
+
+//a gossip is forwarded by the nodes until the hops run out, the nodes drop the gossip already seen by the id.
+//a gossip to a target is routed to the target only, otherwise every node delivers it.
+type Gossip struct {
+	ID      string
+	Target  string
+	Hops    int
+	Message []byte
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package payload
+
+import "github.com/SealSC/SealABC/network"
+
+type Join struct {
+	TargetID  string
+	SourceID  string
+	DataTypes []byte
+}
+
+//a full node rejects the join and gives some peers to try
+type JoinReply struct {
+	PrevID   string
+	RealID   string
+	DataType byte
+	Rejected bool
+	Peers    []network.Node
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message/payload"
+)
+
+func newGossipID() string {
+	id := make([]byte, 16, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+//the origin sends a broadcast to all the peers, and the others forward it to the fanout peers
+func (t *Topology) Broadcast(msg network.Message) (err error) {
+	return t.startGossip(msg, "", t.sampleLinks(0))
+}
+
+//a message to a node not linked is routed by the gossip to the target
+func (t *Topology) sendRouted(target string, msg network.Message) (err error) {
+	return t.startGossip(msg, target, t.sampleLinks(t.cfg.Fanout))
+}
+
+func (t *Topology) startGossip(msg network.Message, target string, links []network.ILink) (err error) {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	gossip := payload.Gossip{
+		ID:      newGossipID(),
+		Target:  target,
+		Hops:    t.cfg.MaxHops,
+		Message: msgBytes,
+	}
+
+	t.markSeen(gossip.ID)
+	if len(links) == 0 {
+		return errors.New("no peer to gossip")
+	}
+
+	t.sendGossip(gossip, links)
+	return
+}
+
+func (t *Topology) sendGossip(gossip payload.Gossip, links []network.ILink) {
+	gossipPayload, _ := json.Marshal(gossip)
+	msg := message.NewMessage(message.Types.Gossip, gossipPayload)
+	msg.From = t.LocalNode.Node
+
+	for _, l := range links {
+		go func(link network.ILink) {
+			_, err := link.SendMessage(msg)
+			if err != nil {
+				log.Log.Warn("send gossip failed: ", err.Error())
+			}
+		}(l)
+	}
+}
+
+type gossipMessageProcessor struct{}
+
+//the message in the gossip is delivered by a routed link in the queues of the link it's received from, so the reply
+//is routed to the origin, and the relayed messages are bounded by the queues of the relaying peer.
+//a relayed message is not authenticated by the link, the applications verify the signatures of their messages,
+//and the topology messages are never relayed, a peer can't join or answer the topology as another node.
+func (g *gossipMessageProcessor) Process(msg network.Message, t *Topology, link network.ILink) (err error) {
+	gossip := payload.Gossip{}
+	err = payload.FromMessage(msg, &gossip)
+	if err != nil {
+		log.Log.Warn("invalid gossip message: ", err.Error())
+		return
+	}
+
+	if t.markSeen(gossip.ID) {
+		return
+	}
+
+	inner := network.Message{}
+	err = json.Unmarshal(gossip.Message, &inner)
+	if err != nil {
+		log.Log.Warn("invalid message in the gossip: ", err.Error())
+		return
+	}
+
+	if inner.Family == message.Family {
+		log.Log.Warn("refuse the topology message of type ", inner.Type, " relayed by ", link.RemoteAddr().String())
+		return
+	}
+
+	if gossip.Target == "" || gossip.Target == t.LocalNode.ID {
+		link.Deliver(inner, newRoutedLink(t, inner.From.ID))
+	}
+
+	if gossip.Target == t.LocalNode.ID {
+		return
+	}
+
+	gossip.Hops -= 1
+	if gossip.Hops <= 0 {
+		return
+	}
+
+	t.lock.RLock()
+	targetLink, linked := t.nodeID2Link[gossip.Target]
+	t.lock.RUnlock()
+
+	if gossip.Target != "" && linked {
+		t.sendGossip(gossip, []network.ILink{targetLink})
+		return
+	}
+
+	t.sendGossip(gossip, t.sampleLinks(t.cfg.Fanout, link))
+	return
+}
+
+var GossipMessageProcessor = &gossipMessageProcessor{}
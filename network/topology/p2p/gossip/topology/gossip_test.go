@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message/payload"
+)
+
+//a link recording the messages delivered by the gossip
+type deliveryLink struct {
+	network.ILink
+	delivered []network.Message
+	via       []network.ILink
+}
+
+func (d *deliveryLink) Deliver(msg network.Message, via network.ILink) {
+	d.delivered = append(d.delivered, msg)
+	d.via = append(d.via, via)
+}
+
+func (d *deliveryLink) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func newTestTopology() *Topology {
+	log.SetUpLogger(log.Config{})
+	message.LoadMessageTypes()
+
+	t := NewTopology(Config{})
+	t.LocalNode.ID = "local"
+	t.nodeID2Link = map[string]network.ILink{}
+	t.seen = map[string]bool{}
+	t.lastSeen = map[string]bool{}
+	return t
+}
+
+func newTestGossip(t *testing.T, id string, inner network.Message) network.Message {
+	innerBytes, err := json.Marshal(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gossipBytes, err := json.Marshal(payload.Gossip{
+		ID:      id,
+		Target:  "local",
+		Hops:    1,
+		Message: innerBytes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return message.NewMessage(message.Types.Gossip, gossipBytes)
+}
+
+func TestGossipDeliveredByLink(t *testing.T) {
+	topology := newTestTopology()
+	link := &deliveryLink{}
+
+	inner := network.Message{}
+	inner.Family = "application"
+	inner.From.ID = "origin"
+
+	_ = GossipMessageProcessor.Process(newTestGossip(t, "app", inner), topology, link)
+	if len(link.delivered) != 1 || link.delivered[0].Family != "application" {
+		t.Fatal("gossip message not delivered by the receiving link")
+	}
+
+	routed, ok := link.via[0].(*routedLink)
+	if !ok || routed.target != "origin" {
+		t.Fatal("gossip message not delivered as from a routed link to the origin")
+	}
+
+	//the same gossip is delivered once
+	_ = GossipMessageProcessor.Process(newTestGossip(t, "app", inner), topology, link)
+	if len(link.delivered) != 1 {
+		t.Fatal("seen gossip delivered again")
+	}
+}
+
+func TestGossipTopologyMessageRefused(t *testing.T) {
+	topology := newTestTopology()
+	link := &deliveryLink{}
+
+	join := message.NewMessage(message.Types.Join, nil)
+	join.From.ID = "origin"
+
+	_ = GossipMessageProcessor.Process(newTestGossip(t, "join", join), topology, link)
+	if len(link.delivered) != 0 {
+		t.Fatal("topology message delivered over a routed link")
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"encoding/json"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message/payload"
+)
+
+func (t *Topology) newJoinMessage(targetID string) (msg network.Message) {
+	join := payload.Join{
+		TargetID:  targetID,
+		SourceID:  t.LocalNode.ID,
+		DataTypes: network.SupportedDataTypes,
+	}
+
+	joinPayload, _ := json.Marshal(join)
+	msg = message.NewMessage(message.Types.Join, joinPayload)
+	msg.From = t.LocalNode.Node
+	return
+}
+
+type joinMessageProcessor struct{}
+
+func (j *joinMessageProcessor) Process(msg network.Message, t *Topology, link network.ILink) (err error) {
+	join := payload.Join{}
+	err = payload.FromMessage(msg, &join)
+	if err != nil {
+		log.Log.Println("not join protocol ")
+		return
+	}
+
+	target, exist := t.getPreJoinNode(link)
+	if !exist {
+		return
+	}
+
+	joinReply := payload.JoinReply{
+		PrevID:   join.TargetID,
+		RealID:   t.LocalNode.ID,
+		DataType: network.NegotiateDataType(join.DataTypes),
+	}
+
+	//a full node gives some peers to the joining node and closes the link after the reply
+	if t.isFull(msg.From.ID) {
+		joinReply.Rejected = true
+		joinReply.Peers = t.samplePeers(maxExchangedPeers)
+	} else {
+		target.Node = msg.From
+		t.setJoinedNode(target)
+	}
+
+	replyPayload, _ := json.Marshal(joinReply)
+	reply := message.NewMessage(message.Types.JoinReply, replyPayload)
+	reply.From = t.LocalNode.Node
+	_, err = link.SendMessage(reply)
+	if err != nil {
+		log.Log.Warn("send join reply failed: ", err.Error())
+		return
+	}
+
+	if joinReply.Rejected {
+		t.addKnownNodes([]network.Node{msg.From})
+		link.Close()
+		return
+	}
+
+	//the reply is in json, the peer switches after getting it
+	link.SetDataType(joinReply.DataType)
+	return
+}
+
+type joinReplyMessageProcessor struct{}
+
+func (j *joinReplyMessageProcessor) Process(msg network.Message, t *Topology, link network.ILink) (err error) {
+	joinReply := payload.JoinReply{}
+	err = payload.FromMessage(msg, &joinReply)
+	if err != nil {
+		log.Log.Println("not join-reply protocol ")
+		return
+	}
+
+	//the link may be closed by the full node already
+	if joinReply.Rejected {
+		log.Log.Println("join rejected by the full node: ", msg.From.ServeAddress)
+		t.removeKnownNode(msg.From.ID)
+		t.addKnownNodes(joinReply.Peers)
+		link.Close()
+		t.refreshPeers()
+		return
+	}
+
+	target, exist := t.getPreJoinNode(link)
+	if !exist {
+		return
+	}
+
+	target.Node = msg.From
+	t.setJoinedNode(target)
+
+	if network.IsSupportedDataType(joinReply.DataType) {
+		link.SetDataType(joinReply.DataType)
+	}
+
+	getPeers(link, t)
+	return
+}
+
+var JoinMessageProcessor = &joinMessageProcessor{}
+var JoinReplyMessageProcessor = &joinReplyMessageProcessor{}
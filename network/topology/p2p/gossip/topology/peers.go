@@ -0,0 +1,67 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"encoding/json"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message/payload"
+)
+
+func getPeers(link network.ILink, t *Topology) {
+	msg := message.NewMessage(message.Types.GetPeers, []byte{})
+	msg.From = t.LocalNode.Node
+	_, err := link.SendMessage(msg)
+	if err != nil {
+		log.Log.Error("send get peers message failed: ", err.Error())
+	}
+}
+
+type getPeersMessageProcessor struct{}
+
+func (g *getPeersMessageProcessor) Process(msg network.Message, t *Topology, link network.ILink) (err error) {
+	peers := payload.PeersPayload{
+		Peers: t.samplePeers(maxExchangedPeers),
+	}
+
+	replyPayload, _ := json.Marshal(peers)
+	replyMsg := message.NewMessage(message.Types.GetPeersReply, replyPayload)
+	replyMsg.From = t.LocalNode.Node
+
+	_, err = link.SendMessage(replyMsg)
+	return
+}
+
+type getPeersReplyMessageProcessor struct{}
+
+func (g *getPeersReplyMessageProcessor) Process(msg network.Message, t *Topology, _ network.ILink) (err error) {
+	peers := payload.PeersPayload{}
+	err = payload.FromMessage(msg, &peers)
+	if err != nil {
+		log.Log.Warn("invalid get peers reply message: ", err.Error())
+		return
+	}
+
+	t.addKnownNodes(peers.Peers)
+	return
+}
+
+var GetPeersMessageProcessor = &getPeersMessageProcessor{}
+var GetPeersReplyMessageProcessor = &getPeersReplyMessageProcessor{}
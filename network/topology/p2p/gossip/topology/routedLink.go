@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"errors"
+	"github.com/SealSC/SealABC/network"
+	"net"
+)
+
+type routedAddr struct {
+	id string
+}
+
+func (r routedAddr) Network() string {
+	return "gossip"
+}
+
+func (r routedAddr) String() string {
+	return r.id
+}
+
+//a routed link sends the messages to a node not linked by the gossip, or directly if the node is linked
+type routedLink struct {
+	topology *Topology
+	target   string
+}
+
+func newRoutedLink(t *Topology, target string) *routedLink {
+	return &routedLink{
+		topology: t,
+		target:   target,
+	}
+}
+
+func (r *routedLink) Start() {}
+
+func (r *routedLink) StartReceiving() {}
+
+func (r *routedLink) SendData(_ []byte) (n int, err error) {
+	err = errors.New("a routed link only sends messages")
+	return
+}
+
+func (r *routedLink) SendMessage(msg network.Message) (n int, err error) {
+	r.topology.lock.RLock()
+	link, linked := r.topology.nodeID2Link[r.target]
+	r.topology.lock.RUnlock()
+
+	if linked {
+		return link.SendMessage(msg)
+	}
+
+	err = r.topology.sendRouted(r.target, msg)
+	return
+}
+
+func (r *routedLink) RemoteAddr() net.Addr {
+	return routedAddr{id: r.target}
+}
+
+//the routed messages are not authenticated by the link
+func (r *routedLink) PeerID() string {
+	return ""
+}
+
+func (r *routedLink) SetDataType(_ byte) {}
+
+func (r *routedLink) DataType() byte {
+	return network.JSON_TYPE
+}
+
+//a routed link has no queue, the messages are relayed by the linked peers
+func (r *routedLink) Deliver(_ network.Message, _ network.ILink) {}
+
+func (r *routedLink) Close() {}
@@ -0,0 +1,467 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package topology
+
+import (
+	"errors"
+	"github.com/SealSC/SealABC/crypto/signers/ed25519"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/network/topology/p2p/gossip/message"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxPeers = 8
+	defaultFanout   = 4
+	defaultMaxHops  = 6
+
+	maxKnownPeers        = 1024
+	maxExchangedPeers    = 16
+	peerMaintainInterval = 10 * time.Second
+	seenGenerationSize   = 8192
+	acceptedPeersFactor  = 2
+)
+
+//every node links to a bounded number of peers, and finds more peers by exchanging them with the linked ones.
+//the validators are always linked directly when their addresses are known, and they are not counted in the bound.
+type Config struct {
+	MaxPeers   int
+	Fanout     int
+	MaxHops    int
+	Validators []string
+}
+
+type Topology struct {
+	LocalNode network.LinkNode
+
+	cfg                 Config
+	validators          map[string]bool
+	preJoinNode         map[network.ILink]network.LinkNode
+	joinedNode          map[network.ILink]network.LinkNode
+	nodeID2Link         map[string]network.ILink
+	knownNode           map[string]network.Node
+	seen                map[string]bool
+	lastSeen            map[string]bool
+	messageProcessorMap map[string]iMessageProcessor
+	router              network.IRouter
+	stop                chan bool
+	refresh             chan bool
+	lock                sync.RWMutex
+}
+
+func NewTopology(cfg Config) *Topology {
+	if cfg.MaxPeers <= 0 {
+		cfg.MaxPeers = defaultMaxPeers
+	}
+
+	if cfg.Fanout <= 0 {
+		cfg.Fanout = defaultFanout
+	}
+
+	if cfg.MaxHops <= 0 {
+		cfg.MaxHops = defaultMaxHops
+	}
+
+	t := &Topology{
+		cfg:        cfg,
+		validators: map[string]bool{},
+	}
+
+	for _, v := range cfg.Validators {
+		t.validators[v] = true
+	}
+
+	return t
+}
+
+func (t *Topology) Name() string {
+	return "gossip P2P"
+}
+
+func (t *Topology) MountTo(router network.IRouter) {
+	message.LoadMessageTypes()
+	network.SetMessagePriority(message.Family, "", network.PRIORITY_HIGH)
+	network.SetMessagePriority(message.Family, message.Types.Gossip.String(), network.PRIORITY_NORMAL)
+
+	t.router = router
+
+	t.preJoinNode = map[network.ILink]network.LinkNode{}
+	t.joinedNode = map[network.ILink]network.LinkNode{}
+	t.nodeID2Link = map[string]network.ILink{}
+	t.knownNode = map[string]network.Node{}
+	t.seen = map[string]bool{}
+	t.lastSeen = map[string]bool{}
+
+	t.messageProcessorMap = map[string]iMessageProcessor{
+		message.Types.Join.String():          JoinMessageProcessor,
+		message.Types.JoinReply.String():     JoinReplyMessageProcessor,
+		message.Types.GetPeers.String():      GetPeersMessageProcessor,
+		message.Types.GetPeersReply.String(): GetPeersReplyMessageProcessor,
+		message.Types.Gossip.String():        GossipMessageProcessor,
+	}
+
+	t.stop = make(chan bool)
+	t.refresh = make(chan bool, 1)
+	go t.maintainPeers()
+}
+
+func (t *Topology) BuildNodeID(_ network.Node) string {
+	s, _ := ed25519.SignerGenerator.NewSigner(nil)
+	return s.PublicKeyString()
+}
+
+func (t *Topology) InterestedMessage(msg network.Message) (interested bool) {
+	return msg.Family == message.Family
+}
+
+func (t *Topology) MessageProcessor(msg network.Message, link network.ILink) {
+	processor, exists := t.messageProcessorMap[msg.Type]
+	if !exists {
+		return
+	}
+
+	err := processor.Process(msg, t, link)
+
+	if err != nil {
+		log.Log.Println("got gossip p2p error: ", err)
+	}
+
+	return
+}
+
+func (t *Topology) SetLocalNode(node network.LinkNode) {
+	t.LocalNode = node
+}
+
+func (t *Topology) GetLocalNode() (node network.LinkNode) {
+	return t.LocalNode
+}
+
+func (t *Topology) Join(node network.LinkNode) (err error) {
+	t.lock.Lock()
+	t.preJoinNode[node.Link] = node
+	t.lock.Unlock()
+
+	_, err = node.Link.SendMessage(t.newJoinMessage(node.ID))
+	if err != nil {
+		log.Log.Warn("join to node failed: ", node.ServeAddress)
+	}
+	return
+}
+
+func (t *Topology) Leave() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+}
+
+func (t *Topology) isValidator(id string) bool {
+	return t.validators[id]
+}
+
+//the link of a joined node, the one still joining, or a routed link to a node known in the network
+func (t *Topology) GetLink(node network.Node) (link network.ILink, err error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	link, exists := t.nodeID2Link[node.ID]
+	if exists {
+		return
+	}
+
+	for l, n := range t.preJoinNode {
+		if n.ServeAddress == node.ServeAddress && n.Protocol == node.Protocol {
+			link = l
+			return
+		}
+	}
+
+	if _, known := t.knownNode[node.ID]; known || t.isValidator(node.ID) {
+		link = newRoutedLink(t, node.ID)
+		return
+	}
+
+	err = errors.New("no such link")
+	return
+}
+
+func (t *Topology) GetAllNodes() (all []network.LinkNode) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, n := range t.joinedNode {
+		if n.ID == t.LocalNode.ID {
+			continue
+		}
+
+		all = append(all, n)
+	}
+
+	return
+}
+
+func (t *Topology) AddLink(link network.ILink) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.preJoinNode[link] = network.NewNetworkNodeFromLink(link)
+}
+
+func (t *Topology) RemoveLink(link network.ILink) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	node, exist := t.joinedNode[link]
+	if exist {
+		delete(t.joinedNode, link)
+		if t.nodeID2Link[node.ID] == link {
+			delete(t.nodeID2Link, node.ID)
+		}
+	}
+	delete(t.preJoinNode, link)
+}
+
+func (t *Topology) getPreJoinNode(link network.ILink) (node network.LinkNode, exist bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	node, exist = t.preJoinNode[link]
+	return
+}
+
+func (t *Topology) setJoinedNode(node network.LinkNode) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.preJoinNode, node.Link)
+	if node.ID == t.LocalNode.ID {
+		return
+	}
+
+	t.joinedNode[node.Link] = node
+	t.nodeID2Link[node.ID] = node.Link
+	t.addKnownNode(node.Node)
+}
+
+func (t *Topology) isJoined(id string) (joined bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if id == t.LocalNode.ID {
+		return true
+	}
+	_, joined = t.nodeID2Link[id]
+	return
+}
+
+//the node is joined or joining
+func (t *Topology) isLinked(id string) bool {
+	if t.isJoined(id) {
+		return true
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, n := range t.preJoinNode {
+		if n.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+//the validators are not counted in the bound
+func (t *Topology) peerCount() (count int) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, n := range t.joinedNode {
+		if !t.isValidator(n.ID) {
+			count += 1
+		}
+	}
+
+	return
+}
+
+//a node links to the max peers by itself, and accepts more joins, so the new nodes could join the full nodes
+func (t *Topology) isFull(id string) bool {
+	return !t.isValidator(id) && t.peerCount() >= t.cfg.MaxPeers*acceptedPeersFactor
+}
+
+func (t *Topology) addKnownNode(node network.Node) {
+	if node.ID == "" || node.ID == t.LocalNode.ID || node.ServeAddress == "" {
+		return
+	}
+
+	if _, exists := t.knownNode[node.ID]; !exists && len(t.knownNode) >= maxKnownPeers {
+		return
+	}
+
+	t.knownNode[node.ID] = node
+}
+
+func (t *Topology) addKnownNodes(nodes []network.Node) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, n := range nodes {
+		t.addKnownNode(n)
+	}
+}
+
+func (t *Topology) removeKnownNode(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.knownNode, id)
+}
+
+//some known nodes in random order
+func (t *Topology) samplePeers(count int) (peers []network.Node) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, n := range t.knownNode {
+		peers = append(peers, n)
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+
+	if len(peers) > count {
+		peers = peers[:count]
+	}
+	return
+}
+
+//some joined nodes in random order, except the excluded links
+func (t *Topology) sampleLinks(count int, excluded ...network.ILink) (links []network.ILink) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for l := range t.joinedNode {
+		skip := false
+		for _, e := range excluded {
+			if l == e {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			links = append(links, l)
+		}
+	}
+
+	rand.Shuffle(len(links), func(i, j int) {
+		links[i], links[j] = links[j], links[i]
+	})
+
+	if count > 0 && len(links) > count {
+		links = links[:count]
+	}
+	return
+}
+
+//the id is seen in the last two generations, so the memory is bounded
+func (t *Topology) markSeen(id string) (seen bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.seen[id] || t.lastSeen[id] {
+		return true
+	}
+
+	if len(t.seen) >= seenGenerationSize {
+		t.lastSeen = t.seen
+		t.seen = map[string]bool{}
+	}
+
+	t.seen[id] = true
+	return false
+}
+
+//link to the known validators, fill the peers up to the bound and exchange the peers regularly
+func (t *Topology) maintainPeers() {
+	t.lock.RLock()
+	stop := t.stop
+	t.lock.RUnlock()
+
+	ticker := time.NewTicker(peerMaintainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-t.refresh:
+		}
+
+		missing := t.cfg.MaxPeers - t.peerCount()
+		for _, n := range t.samplePeers(maxKnownPeers) {
+			if t.isLinked(n.ID) {
+				continue
+			}
+
+			if !t.isValidator(n.ID) {
+				if missing <= 0 {
+					continue
+				}
+				missing -= 1
+			}
+
+			t.connectTo(n)
+		}
+
+		for _, l := range t.sampleLinks(1) {
+			getPeers(l, t)
+		}
+	}
+}
+
+//maintain the peers now, such as after a join is rejected
+func (t *Topology) refreshPeers() {
+	select {
+	case t.refresh <- true:
+	default:
+	}
+}
+
+func (t *Topology) connectTo(node network.Node) {
+	linkedNode, err := t.router.ConnectTo(node)
+	if err != nil {
+		log.Log.Warn("connect to peer ", node.ServeAddress, " failed: ", err.Error())
+		t.removeKnownNode(node.ID)
+		return
+	}
+
+	linkedNode.ID = node.ID
+	_ = t.Join(linkedNode)
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/applicationResult"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
@@ -42,6 +43,9 @@ type BasicAssetsApplication struct {
 
 	Ledger     *basicAssetsLedger.Ledger
 	SQLStorage *basicAssetsSQLStorage.Storage
+
+	stateRoot *chainStructure.StateRootRecorder
+	staged    *kvDatabase.StagedDriver
 }
 
 func (b *BasicAssetsApplication) Name() (name string) {
@@ -101,6 +105,16 @@ func (b *BasicAssetsApplication) Execute(
 		return
 	}
 
+	//replayed after a crash
+	if b.stateRoot.Applied(blk.Header.Height, actIndex) {
+		b.Ledger.RemoveTransactionFromPool(tx.HashString())
+		return
+	}
+
+	//the ledger writes in many steps, they are staged and saved in one atomic write
+	b.staged.Begin()
+	defer b.staged.Discard()
+
 	execResult, err := b.Ledger.ExecuteTransaction(tx)
 	if err != nil {
 		return
@@ -112,6 +126,12 @@ func (b *BasicAssetsApplication) Execute(
 		return
 	}
 
+	putList, delList := b.staged.Staged()
+	err = b.stateRoot.Commit(blk.Header.Height, actIndex, putList, delList)
+	if err != nil {
+		return
+	}
+
 	b.Ledger.RemoveTransactionFromPool(tx.HashString())
 
 	if b.SQLStorage != nil {
@@ -149,6 +169,31 @@ func (b *BasicAssetsApplication) Execute(
 	return
 }
 
+func (b *BasicAssetsApplication) Cancel(req blockchainRequest.Entity) (err error) {
+	return b.Ledger.ReturnTransactionToPool(req)
+}
+
+func (b *BasicAssetsApplication) StateRoot() (root []byte) {
+	return b.stateRoot.Root()
+}
+
+func (b *BasicAssetsApplication) RevertTo(height uint64) (err error) {
+	return b.stateRoot.RevertTo(height)
+}
+
+func (b *BasicAssetsApplication) PruneUndo(height uint64) (err error) {
+	return b.stateRoot.PruneUndo(height)
+}
+
+func (b *BasicAssetsApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	kvList, next = b.stateRoot.Snapshot(start, count)
+	return
+}
+
+func (b *BasicAssetsApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	return b.stateRoot.Restore(kvList, first)
+}
+
 func (b *BasicAssetsApplication) RequestsForBlock(_ block.Entity) (reqList []blockchainRequest.Entity, cnt uint32) {
 	return b.Ledger.GetTransactionsFromPool()
 }
@@ -185,7 +230,14 @@ func Load() {
 
 func NewApplicationInterface(kvDriver kvDatabase.IDriver, sqlDriver simpleSQLDatabase.IDriver) (app chainStructure.IBlockchainExternalApplication) {
 	bs := BasicAssetsApplication{}
-	bs.Ledger = basicAssetsLedger.NewLedger(kvDriver)
+	bs.staged = kvDatabase.NewStagedDriver(kvDriver)
+	bs.Ledger = basicAssetsLedger.NewLedger(bs.staged)
+
+	stateRoot, err := chainStructure.NewStateRootRecorder(kvDriver, bs.Ledger.CryptoTools.HashCalculator)
+	if err != nil {
+		log.Log.Error("load basic assets state root failed: ", err.Error())
+	}
+	bs.stateRoot = stateRoot
 	if sqlDriver != nil {
 		bs.SQLStorage = basicAssetsSQLStorage.NewStorage(sqlDriver)
 	}
@@ -98,6 +98,29 @@ func (l *Ledger) PushTransaction(req blockchainRequest.Entity) (err error) {
 	return
 }
 
+//return the transaction of a failed consensus round to the pool, the pooled and the saved ones are ignored.
+func (l *Ledger) ReturnTransactionToPool(req blockchainRequest.Entity) (err error) {
+	tx := Transaction{}
+	err = json.Unmarshal(req.Data, &tx)
+	if err != nil {
+		return
+	}
+
+	l.poolLock.Lock()
+	_, pooled := l.txPool[tx.HashString()]
+	l.poolLock.Unlock()
+
+	if pooled {
+		return
+	}
+
+	if _, err = l.GetOriginalTransactionWithBlockInfo(tx.Seal.Hash); err == nil {
+		return
+	}
+
+	return l.PushTransaction(req)
+}
+
 func (l *Ledger) GetTransactionsFromPool() (txList []blockchainRequest.Entity, count uint32) {
 	l.poolLock.Lock()
 	defer l.poolLock.Unlock()
@@ -48,6 +48,7 @@ type MemoApplication struct {
 	CryptoTools crypto.Tools
 	kvStorage   kvDatabase.IDriver
 	sqlStorage  *memoSQLStorage.Storage
+	stateRoot   *chainStructure.StateRootRecorder
 }
 
 var applicationActions struct {
@@ -116,7 +117,7 @@ func (m *MemoApplication) PreExecute(req blockchainRequest.Entity, _ block.Entit
 func (m *MemoApplication) Execute(
 	req blockchainRequest.Entity,
 	blk block.Entity,
-	_ uint32,
+	actIndex uint32,
 ) (result applicationResult.Entity, err error) {
 
 	_, memo, err := m.VerifyReq(req)
@@ -136,10 +137,17 @@ func (m *MemoApplication) Execute(
 		m.poolLock.Unlock()
 	}()
 
-	err = m.kvStorage.Put(kvDatabase.KVItem{
-		Key:  memo.Seal.Hash,
-		Data: memoJson,
-	})
+	//replayed after a crash
+	if m.stateRoot.Applied(blk.Header.Height, actIndex) {
+		return
+	}
+
+	err = m.stateRoot.Commit(blk.Header.Height, actIndex, []kvDatabase.KVItem{
+		{
+			Key:  memo.Seal.Hash,
+			Data: memoJson,
+		},
+	}, nil)
 
 	if err != nil {
 		log.Log.Error("save memo failed: ", err.Error())
@@ -153,6 +161,39 @@ func (m *MemoApplication) Execute(
 	return
 }
 
+func (m *MemoApplication) StateRoot() (root []byte) {
+	return m.stateRoot.Root()
+}
+
+func (m *MemoApplication) RevertTo(height uint64) (err error) {
+	m.operateLock.Lock()
+	defer m.operateLock.Unlock()
+
+	return m.stateRoot.RevertTo(height)
+}
+
+func (m *MemoApplication) PruneUndo(height uint64) (err error) {
+	m.operateLock.Lock()
+	defer m.operateLock.Unlock()
+
+	return m.stateRoot.PruneUndo(height)
+}
+
+func (m *MemoApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	m.operateLock.Lock()
+	defer m.operateLock.Unlock()
+
+	kvList, next = m.stateRoot.Snapshot(start, count)
+	return
+}
+
+func (m *MemoApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	m.operateLock.Lock()
+	defer m.operateLock.Unlock()
+
+	return m.stateRoot.Restore(kvList, first)
+}
+
 func (m *MemoApplication) RequestsForBlock(_ block.Entity) (reqList []blockchainRequest.Entity, cnt uint32) {
 	m.operateLock.Lock()
 	defer m.operateLock.Unlock()
@@ -208,6 +249,12 @@ func NewApplicationInterface(kvDriver kvDatabase.IDriver, sqlDriver simpleSQLDat
 
 	m.reqPool = map[string]blockchainRequest.Entity{}
 
+	stateRoot, err := chainStructure.NewStateRootRecorder(kvDriver, tools.HashCalculator)
+	if err != nil {
+		log.Log.Error("load memo state root failed: ", err.Error())
+	}
+	m.stateRoot = stateRoot
+
 	app = &m
 	return
 }
@@ -25,6 +25,7 @@ import (
 type Config struct {
 	commonCfg.Config
 	BaseAssets    smartAssetsLedger.BaseAssetsData
+	Genesis       smartAssetsLedger.Genesis
 	TxPoolLimit   int
 	ClientTxLimit int
 }
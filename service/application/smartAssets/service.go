@@ -55,6 +55,6 @@ func NewSmartAssetsApplication(config *Config) (app chainStructure.IBlockchainEx
 		sqlDriver = config.SQLStorage
 	}
 
-	app, err = smartAssetsInterface.NewApplicationInterface(kvDriver, sqlDriver, config.CryptoTools, config.BaseAssets, config.TxPoolLimit, config.ClientTxLimit)
+	app, err = smartAssetsInterface.NewApplicationInterface(kvDriver, sqlDriver, config.CryptoTools, config.BaseAssets, config.Genesis, config.TxPoolLimit, config.ClientTxLimit)
 	return
 }
@@ -93,7 +93,7 @@ func (s *SmartAssetsApplication) Execute(
 		return
 	}
 
-	_, err = s.ledger.Execute(txList, blk)
+	_, err = s.ledger.Execute(txList, blk, actIndex)
 	if err == nil && s.sqlStorage != nil {
 		for _, tx := range txList.Transactions {
 			_ = s.sqlStorage.StoreTransaction(tx, blk)
@@ -103,6 +103,38 @@ func (s *SmartAssetsApplication) Execute(
 	return
 }
 
+func (s *SmartAssetsApplication) Cancel(req blockchainRequest.Entity) (err error) {
+	txList := smartAssetsLedger.TransactionList{}
+	err = structSerializer.FromMFBytes(req.Data, &txList)
+	if err != nil {
+		return
+	}
+
+	s.ledger.ReturnTransactionsToPool(txList)
+	return
+}
+
+func (s *SmartAssetsApplication) StateRoot() (root []byte) {
+	return s.ledger.StateRoot()
+}
+
+func (s *SmartAssetsApplication) RevertTo(height uint64) (err error) {
+	return s.ledger.RevertTo(height)
+}
+
+func (s *SmartAssetsApplication) PruneUndo(height uint64) (err error) {
+	return s.ledger.PruneUndo(height)
+}
+
+func (s *SmartAssetsApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	kvList, next = s.ledger.SnapshotState(start, count)
+	return
+}
+
+func (s *SmartAssetsApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	return s.ledger.RestoreState(kvList, first)
+}
+
 func (s *SmartAssetsApplication) RequestsForBlock(blk block.Entity) (reqList []blockchainRequest.Entity, cnt uint32) {
 	txList, cnt, txRoot := s.ledger.GetTransactionsFromPool(blk)
 	if cnt == 0 {
@@ -190,6 +222,7 @@ func NewApplicationInterface(
 	sqlDriver simpleSQLDatabase.IDriver,
 	tools crypto.Tools,
 	assets smartAssetsLedger.BaseAssetsData,
+	genesis smartAssetsLedger.Genesis,
 	txPoolLimit int,
 	clientTxLimit int,
 ) (app chainStructure.IBlockchainExternalApplication, err error) {
@@ -206,7 +239,12 @@ func NewApplicationInterface(
 		return
 	}
 
-	err = sa.ledger.LoadGenesisAssets(ownerBytes, assets)
+	err = sa.ledger.LoadStateRoot()
+	if err != nil {
+		return
+	}
+
+	err = sa.ledger.LoadGenesisAssets(ownerBytes, assets, genesis)
 	if err != nil {
 		return
 	}
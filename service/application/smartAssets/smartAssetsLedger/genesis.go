@@ -0,0 +1,122 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package smartAssetsLedger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"math/big"
+)
+
+//the balance allocated from the supply of the system assets at genesis, the rest of the supply goes to the owner
+type GenesisBalance struct {
+	Address string
+	Amount  string
+}
+
+//the contract deployed at genesis, the code is the runtime code of the contract
+type GenesisContract struct {
+	Address string
+	Code    string
+}
+
+type Genesis struct {
+	Balances  []GenesisBalance
+	Contracts []GenesisContract
+}
+
+func (g Genesis) kvList(owner []byte, supply *big.Int, hashCalc hashes.IHashCalculator) (kvList []kvDatabase.KVItem, err error) {
+	ownerBalance := big.NewInt(0).Set(supply)
+	var addresses [][]byte
+	balances := map[string]*big.Int{}
+
+	for _, b := range g.Balances {
+		addr, decodeErr := hex.DecodeString(b.Address)
+		if decodeErr != nil {
+			err = decodeErr
+			return
+		}
+
+		amount, valid := big.NewInt(0).SetString(b.Amount, 10)
+		if !valid || amount.Sign() < 0 {
+			err = errors.New("invalid genesis balance of " + b.Address)
+			return
+		}
+
+		//the owner gets the rest of the supply anyway
+		if bytes.Equal(addr, owner) {
+			continue
+		}
+
+		ownerBalance.Sub(ownerBalance, amount)
+
+		if _, exists := balances[string(addr)]; !exists {
+			addresses = append(addresses, addr)
+			balances[string(addr)] = big.NewInt(0)
+		}
+		balances[string(addr)].Add(balances[string(addr)], amount)
+	}
+
+	if ownerBalance.Sign() < 0 {
+		err = errors.New("genesis balances exceed the supply")
+		return
+	}
+
+	kvList = append(kvList, kvDatabase.KVItem{
+		Key:  BuildKey(StoragePrefixes.Balance, owner),
+		Data: ownerBalance.Bytes(),
+	})
+
+	for _, addr := range addresses {
+		kvList = append(kvList, kvDatabase.KVItem{
+			Key:  BuildKey(StoragePrefixes.Balance, addr),
+			Data: balances[string(addr)].Bytes(),
+		})
+	}
+
+	for _, c := range g.Contracts {
+		addr, decodeErr := hex.DecodeString(c.Address)
+		if decodeErr != nil || len(addr) != ContractAddressLen {
+			err = errors.New("invalid genesis contract address " + c.Address)
+			return
+		}
+
+		code, decodeErr := hex.DecodeString(c.Code)
+		if decodeErr != nil || len(code) == 0 {
+			err = errors.New("invalid genesis contract code of " + c.Address)
+			return
+		}
+
+		//the evm addresses a contract by the integer of its address
+		contractAddr := big.NewInt(0).SetBytes(addr).Bytes()
+		kvList = append(kvList,
+			kvDatabase.KVItem{
+				Key:  BuildKey(StoragePrefixes.ContractCode, contractAddr),
+				Data: code,
+			},
+			kvDatabase.KVItem{
+				Key:  BuildKey(StoragePrefixes.ContractHash, contractAddr),
+				Data: hashCalc.Sum(code),
+			},
+		)
+	}
+
+	return
+}
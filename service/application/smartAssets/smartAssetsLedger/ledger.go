@@ -55,6 +55,7 @@ type Ledger struct {
 	chain       chainStructure.IChainInterface
 	CryptoTools crypto.Tools
 	Storage     kvDatabase.IDriver
+	stateRoot   *chainStructure.StateRootRecorder
 
 	storageForEVM contractStorage
 }
@@ -71,7 +72,44 @@ func (l *Ledger) SetChain(chain chainStructure.IChainInterface) {
 	l.chain = chain
 }
 
-func (l *Ledger) LoadGenesisAssets(owner []byte, assets BaseAssetsData) error {
+func (l *Ledger) LoadStateRoot() (err error) {
+	l.stateRoot, err = chainStructure.NewStateRootRecorder(l.Storage, l.CryptoTools.HashCalculator)
+	return
+}
+
+func (l *Ledger) StateRoot() (root []byte) {
+	return l.stateRoot.Root()
+}
+
+func (l *Ledger) RevertTo(height uint64) (err error) {
+	l.poolLock.Lock()
+	defer l.poolLock.Unlock()
+
+	return l.stateRoot.RevertTo(height)
+}
+
+func (l *Ledger) PruneUndo(height uint64) (err error) {
+	l.poolLock.Lock()
+	defer l.poolLock.Unlock()
+
+	return l.stateRoot.PruneUndo(height)
+}
+
+func (l *Ledger) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte) {
+	l.poolLock.Lock()
+	defer l.poolLock.Unlock()
+
+	return l.stateRoot.Snapshot(start, count)
+}
+
+func (l *Ledger) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	l.poolLock.Lock()
+	defer l.poolLock.Unlock()
+
+	return l.stateRoot.Restore(kvList, first)
+}
+
+func (l *Ledger) LoadGenesisAssets(owner []byte, assets BaseAssetsData, genesis Genesis) error {
 	_, exists, err := l.getSystemAssets()
 	if err != nil {
 		return err
@@ -89,25 +127,17 @@ func (l *Ledger) LoadGenesisAssets(owner []byte, assets BaseAssetsData) error {
 		return errors.New("no owner for system assets")
 	}
 
-	signer, err := l.CryptoTools.SignerGenerator.NewSigner(nil)
-	if err != nil {
-		return err
-	}
-
-	pk := signer.PrivateKeyBytes()
+	//the system assets is created by the genesis and signed by no one, its seals keep the hashes only,
+	//so every node stores the same genesis record.
 	metaBytes, _ := structSerializer.ToMFBytes(assets)
-	metaSeal := seal.Entity{}
-	err = metaSeal.Sign(metaBytes, l.CryptoTools, pk)
-	if err != nil {
-		return err
+	metaSeal := seal.Entity{
+		Hash: l.CryptoTools.HashCalculator.Sum(metaBytes),
 	}
 
 	assets.Supply = supply
 	issuedBytes, _ := structSerializer.ToMFBytes(assets)
-	issuedSeal := seal.Entity{}
-	err = issuedSeal.Sign(issuedBytes, l.CryptoTools, pk)
-	if err != nil {
-		return err
+	issuedSeal := seal.Entity{
+		Hash: l.CryptoTools.HashCalculator.Sum(issuedBytes),
 	}
 
 	sysAssets := BaseAssets{
@@ -116,11 +146,6 @@ func (l *Ledger) LoadGenesisAssets(owner []byte, assets BaseAssetsData) error {
 		MetaSeal:       metaSeal,
 	}
 
-	err = l.storeSystemAssets(sysAssets)
-	if err != nil {
-		return err
-	}
-
 	balance, valid := big.NewInt(0).SetString(assets.Supply, 10)
 	if !valid {
 		return errors.New("invalid assets supply")
@@ -130,13 +155,18 @@ func (l *Ledger) LoadGenesisAssets(owner []byte, assets BaseAssetsData) error {
 		return errors.New("supply is zero or negative")
 	}
 
-	err = l.Storage.Put(kvDatabase.KVItem{
-		Key:    BuildKey(StoragePrefixes.Balance, owner),
-		Data:   balance.Bytes(),
-		Exists: false,
-	})
+	kvList, err := genesis.kvList(owner, balance, l.CryptoTools.HashCalculator)
+	if err != nil {
+		return err
+	}
 
-	return err
+	//the system assets is saved last, so the genesis is loaded again if it's interrupted
+	err = l.Storage.BatchPut(kvList)
+	if err != nil {
+		return err
+	}
+
+	return l.storeSystemAssets(sysAssets)
 }
 
 func (l *Ledger) AddTx(req blockchainRequest.Entity) ([]byte, error) {
@@ -326,10 +356,16 @@ func (l *Ledger) removeTransactionsFromPool(txList []Transaction) {
 	l.txPoolRecord = newTxPoolRecord
 }
 
-func (l *Ledger) Execute(txList TransactionList, blk block.Entity) (result []byte, err error) {
+func (l *Ledger) Execute(txList TransactionList, blk block.Entity, actIndex uint32) (result []byte, err error) {
 	l.poolLock.Lock()
 	defer l.poolLock.Unlock()
 
+	//replayed after a crash
+	if l.stateRoot.Applied(blk.Header.Height, actIndex) {
+		l.removeTransactionsFromPool(txList.Transactions)
+		return
+	}
+
 	var kvList []kvDatabase.KVItem
 	for _, tx := range txList.Transactions {
 		txData, _ := structSerializer.ToMFBytes(tx)
@@ -348,7 +384,7 @@ func (l *Ledger) Execute(txList TransactionList, blk block.Entity) (result []byt
 		}
 	}
 
-	err = l.Storage.BatchPut(kvList)
+	err = l.stateRoot.Commit(blk.Header.Height, actIndex, kvList, nil)
 	if err != nil {
 		return
 	}
@@ -369,6 +405,48 @@ func (l Ledger) setTxNewState(err error, newState []StateData, tx *Transaction)
 	}
 }
 
+//return the transactions of a failed consensus round to the pool, the pooled and the saved ones are ignored,
+//and a transaction over the pool limit or the limit of its client is dropped as a pushed one is refused.
+//the results of the transactions will be rebuilt when they are pulled into a new block.
+func (l *Ledger) ReturnTransactionsToPool(txList TransactionList) {
+	l.poolLock.Lock()
+	defer l.poolLock.Unlock()
+
+	for _, tx := range txList.Transactions {
+		txHash := string(tx.DataSeal.Hash)
+		if l.txPool[txHash] != nil {
+			continue
+		}
+
+		_, exists, _ := l.getTxFromStorage(tx.DataSeal.Hash)
+		if exists {
+			continue
+		}
+
+		//the pool is full, the cancelled transaction is dropped
+		if len(l.txPool) >= l.txPoolLimit {
+			continue
+		}
+
+		client := string(tx.DataSeal.SignerPublicKey)
+		if l.clientTxCount[client] >= l.clientTxLimit {
+			continue
+		}
+
+		valid, _ := tx.verify(l.CryptoTools.HashCalculator)
+		if !valid {
+			continue
+		}
+
+		returnedTx := tx
+		returnedTx.TransactionResult = TransactionResult{}
+
+		l.txPool[txHash] = &returnedTx
+		l.txPoolRecord = append(l.txPoolRecord, txHash)
+		l.clientTxCount[client] += 1
+	}
+}
+
 func (l *Ledger) GetTransactionsFromPool(blk block.Entity) (txList TransactionList, count uint32, txRoot []byte) {
 	l.poolLock.Lock()
 	defer l.poolLock.Unlock()
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
 	"github.com/SealSC/SealABC/dataStructure/merkleTree"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/applicationResult"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
@@ -44,6 +45,9 @@ type TraceableStorageApplication struct {
 
 	poolLock sync.RWMutex
 	tsLedger *tsLedger.TSLedger
+
+	stateRoot *chainStructure.StateRootRecorder
+	staged    *kvDatabase.StagedDriver
 }
 
 type RequestList struct {
@@ -129,6 +133,16 @@ func (t *TraceableStorageApplication) Execute(
 		return
 	}
 
+	//replayed after a crash
+	if t.stateRoot.Applied(blk.Header.Height, actIndex) {
+		t.removeTransactionsFromPool(reqList)
+		return
+	}
+
+	//the requests in the list are saved in one atomic write
+	t.staged.Begin()
+	defer t.staged.Discard()
+
 	for _, req := range reqList.Requests {
 		_, err = t.PushClientRequest(req)
 		if err != nil {
@@ -147,12 +161,41 @@ func (t *TraceableStorageApplication) Execute(
 		}
 	}
 
-	if err == nil {
-		t.removeTransactionsFromPool(reqList)
+	if err != nil {
+		return
 	}
+
+	putList, delList := t.staged.Staged()
+	err = t.stateRoot.Commit(blk.Header.Height, actIndex, putList, delList)
+	if err != nil {
+		return
+	}
+
+	t.removeTransactionsFromPool(reqList)
 	return
 }
 
+func (t *TraceableStorageApplication) StateRoot() (root []byte) {
+	return t.stateRoot.Root()
+}
+
+func (t *TraceableStorageApplication) RevertTo(height uint64) (err error) {
+	return t.stateRoot.RevertTo(height)
+}
+
+func (t *TraceableStorageApplication) PruneUndo(height uint64) (err error) {
+	return t.stateRoot.PruneUndo(height)
+}
+
+func (t *TraceableStorageApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	kvList, next = t.stateRoot.Snapshot(start, count)
+	return
+}
+
+func (t *TraceableStorageApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	return t.stateRoot.Restore(kvList, first)
+}
+
 func (t *TraceableStorageApplication) Information() (info service.BasicInformation) {
 	info.Name = t.Name()
 	info.Description = "this is an traceableStorage application"
@@ -214,13 +257,21 @@ func Load() {
 }
 
 func NewApplicationInterface(kvDriver kvDatabase.IDriver, sqlDriver simpleSQLDatabase.IDriver) (app chainStructure.IBlockchainExternalApplication) {
+	staged := kvDatabase.NewStagedDriver(kvDriver)
 	ts := TraceableStorageApplication{
 		reqList:   []string{},
 		reqMap:    map[string]blockchainRequest.Entity{},
 		poolLock:  sync.RWMutex{},
 		poolLimit: 1000,
-		tsLedger:  tsLedger.NewTraceableStorage(kvDriver, sqlDriver),
+		tsLedger:  tsLedger.NewTraceableStorage(staged, sqlDriver),
+		staged:    staged,
+	}
+
+	stateRoot, err := chainStructure.NewStateRootRecorder(kvDriver, ts.tsLedger.CryptoTools.HashCalculator)
+	if err != nil {
+		log.Log.Error("load traceable storage state root failed: ", err.Error())
 	}
+	ts.stateRoot = stateRoot
 
 	app = &ts
 	return
@@ -20,7 +20,9 @@ package uidInterface
 import (
 	"errors"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
 	"github.com/SealSC/SealABC/dataStructure/merkleTree"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/applicationResult"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
@@ -45,7 +47,9 @@ type UniversalIdentificationApplication struct {
 
 	poolLock sync.Mutex
 
-	ledger uidLedger.UIDLedger
+	ledger    uidLedger.UIDLedger
+	stateRoot *chainStructure.StateRootRecorder
+	staged    *kvDatabase.StagedDriver
 }
 
 func (u *UniversalIdentificationApplication) Name() (name string) {
@@ -143,6 +147,16 @@ func (u *UniversalIdentificationApplication) Execute(
 		u.poolLock.Unlock()
 	}()
 
+	//replayed after a crash
+	if u.stateRoot.Applied(blk.Header.Height, actIndex) {
+		u.removeRequestFromPool(reqList.Actions)
+		return
+	}
+
+	//the actions of the request are saved in one atomic write
+	u.staged.Begin()
+	defer u.staged.Discard()
+
 	for _, req := range reqList.Actions {
 		reqKey := string(req.Seal.Hash)
 		if _, exist := u.reqPool[reqKey]; exist {
@@ -156,10 +170,37 @@ func (u *UniversalIdentificationApplication) Execute(
 		}
 	}
 
+	putList, delList := u.staged.Staged()
+	err = u.stateRoot.Commit(blk.Header.Height, actIndex, putList, delList)
+	if err != nil {
+		return
+	}
+
 	u.removeRequestFromPool(reqList.Actions)
 	return
 }
 
+func (u *UniversalIdentificationApplication) StateRoot() (root []byte) {
+	return u.stateRoot.Root()
+}
+
+func (u *UniversalIdentificationApplication) RevertTo(height uint64) (err error) {
+	return u.stateRoot.RevertTo(height)
+}
+
+func (u *UniversalIdentificationApplication) PruneUndo(height uint64) (err error) {
+	return u.stateRoot.PruneUndo(height)
+}
+
+func (u *UniversalIdentificationApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	kvList, next = u.stateRoot.Snapshot(start, count)
+	return
+}
+
+func (u *UniversalIdentificationApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	return u.stateRoot.Restore(kvList, first)
+}
+
 func (u *UniversalIdentificationApplication) RequestsForBlock(_ block.Entity) (reqList []blockchainRequest.Entity, cnt uint32) {
 	u.poolLock.Lock()
 
@@ -211,7 +252,14 @@ func Load() {}
 func NewApplicationInterface(kvDriver kvDatabase.IDriver, sqlDriver simpleSQLDatabase.IDriver) (app chainStructure.IBlockchainExternalApplication) {
 	uidApp := UniversalIdentificationApplication{}
 
-	uidApp.ledger = uidLedger.NewLedger(kvDriver, sqlDriver)
+	uidApp.staged = kvDatabase.NewStagedDriver(kvDriver)
+	uidApp.ledger = uidLedger.NewLedger(uidApp.staged, sqlDriver)
+
+	stateRoot, err := chainStructure.NewStateRootRecorder(kvDriver, sha3.Sha256)
+	if err != nil {
+		log.Log.Error("load universal identification state root failed: ", err.Error())
+	}
+	uidApp.stateRoot = stateRoot
 
 	return &uidApp
 }
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package validatorSetData
+
+import (
+	"github.com/SealSC/SealABC/dataStructure/enum"
+	"github.com/SealSC/SealABC/metadata/seal"
+)
+
+//a proposal to change the validator set, the keys are hex strings of the members' public keys.
+//the new set takes effect from the block at effective height.
+type ProposalData struct {
+	Operation       string
+	PublicKey       string
+	NewPublicKey    string
+	EffectiveHeight uint64
+	Nonce           uint64
+}
+
+type Proposal struct {
+	ProposalData
+	Seal seal.Entity
+}
+
+type ApprovalData struct {
+	ProposalHash string
+}
+
+type Approval struct {
+	ApprovalData
+	Seal seal.Entity
+}
+
+type ProposalState struct {
+	Proposal  Proposal
+	Approvals []string
+	Status    string
+}
+
+type ValidatorSet struct {
+	EffectiveHeight uint64
+	Members         []string
+}
+
+type QueryRequest struct {
+	QueryType string
+	Parameter []string
+}
+
+var Actions struct {
+	Propose enum.Element
+	Approve enum.Element
+}
+
+var Operations struct {
+	Add    enum.Element
+	Remove enum.Element
+	Rotate enum.Element
+}
+
+var ProposalStatus struct {
+	Pending   enum.Element
+	Scheduled enum.Element
+	Rejected  enum.Element
+	Expired   enum.Element
+}
+
+var QueryTypes struct {
+	CurrentSet  enum.Element
+	SetAtHeight enum.Element
+	History     enum.Element
+	Proposal    enum.Element
+}
+
+func Load() {
+	enum.SimpleBuild(&Actions)
+	enum.SimpleBuild(&Operations)
+	enum.SimpleBuild(&ProposalStatus)
+	enum.SimpleBuild(&QueryTypes)
+}
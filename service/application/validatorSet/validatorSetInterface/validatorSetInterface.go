@@ -0,0 +1,446 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package validatorSetInterface
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/crypto"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/applicationResult"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/metadata/blockchainRequest"
+	"github.com/SealSC/SealABC/service"
+	"github.com/SealSC/SealABC/service/application/validatorSet/validatorSetData"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"strconv"
+	"sync"
+)
+
+//the validator set application keeps the consensus members on chain, members propose and approve set changes by
+//transactions, and the consensus reloads its members from it.
+type ValidatorSetApplication struct {
+	chainStructure.BlankApplication
+
+	operateLock sync.RWMutex
+	poolLock    sync.Mutex
+
+	reqPool map[string]blockchainRequest.Entity
+	history []validatorSetData.ValidatorSet
+
+	chainInterface chainStructure.IChainInterface
+
+	CryptoTools crypto.Tools
+	kvStorage   kvDatabase.IDriver
+	stateRoot   *chainStructure.StateRootRecorder
+}
+
+func (v *ValidatorSetApplication) Name() (name string) {
+	return "Validator Set"
+}
+
+func (v *ValidatorSetApplication) SetChainInterface(ci chainStructure.IChainInterface) {
+	v.chainInterface = ci
+}
+
+func (v *ValidatorSetApplication) PushClientRequest(req blockchainRequest.Entity) (result interface{}, err error) {
+	v.operateLock.RLock()
+	err = v.verifyRequest(req)
+
+	//an approval could be pushed together with its proposal which is still in the pool
+	if err != nil && v.isApprovalOfPooledProposal(req) {
+		err = nil
+	}
+	v.operateLock.RUnlock()
+
+	if err != nil {
+		return
+	}
+
+	v.poolLock.Lock()
+	defer v.poolLock.Unlock()
+
+	v.reqPool[req.Seal.HexHash()] = req
+	result = req.Seal.Hash
+	return
+}
+
+func (v *ValidatorSetApplication) Query(req []byte) (result interface{}, err error) {
+	queryReq := validatorSetData.QueryRequest{}
+	err = json.Unmarshal(req, &queryReq)
+	if err != nil {
+		return
+	}
+
+	v.operateLock.RLock()
+	defer v.operateLock.RUnlock()
+
+	switch queryReq.QueryType {
+	case validatorSetData.QueryTypes.CurrentSet.String():
+		result = v.setAt(v.currentHeight() + 1)
+
+	case validatorSetData.QueryTypes.History.String():
+		result = v.history
+
+	case validatorSetData.QueryTypes.SetAtHeight.String():
+		if len(queryReq.Parameter) == 0 {
+			err = errors.New("invalid parameter")
+			return
+		}
+
+		height, parseErr := strconv.ParseUint(queryReq.Parameter[0], 10, 64)
+		if parseErr != nil {
+			err = errors.New("invalid height")
+			return
+		}
+		result = v.setAt(height)
+
+	case validatorSetData.QueryTypes.Proposal.String():
+		if len(queryReq.Parameter) == 0 {
+			err = errors.New("invalid parameter")
+			return
+		}
+
+		state, getErr := v.getProposal(queryReq.Parameter[0])
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		if state == nil {
+			err = errors.New("no such proposal")
+			return
+		}
+		result = state
+
+	default:
+		err = errors.New("not supported query type: " + queryReq.QueryType)
+	}
+
+	return
+}
+
+func (v *ValidatorSetApplication) PreExecute(req blockchainRequest.Entity, _ block.Entity) (result []byte, err error) {
+	v.operateLock.RLock()
+	defer v.operateLock.RUnlock()
+
+	err = v.verifyRequest(req)
+	return
+}
+
+func (v *ValidatorSetApplication) Execute(
+	req blockchainRequest.Entity,
+	blk block.Entity,
+	actIndex uint32,
+) (result applicationResult.Entity, err error) {
+
+	v.operateLock.Lock()
+	defer v.operateLock.Unlock()
+
+	v.poolLock.Lock()
+	defer func() {
+		delete(v.reqPool, req.Seal.HexHash())
+		v.poolLock.Unlock()
+	}()
+
+	//replayed after a crash, the result is saved with the writes of the request
+	if replayed, applied := v.stateRoot.AppliedResult(blk.Header.Height, actIndex); applied {
+		result = replayed
+		return
+	}
+
+	var state *validatorSetData.ProposalState
+	switch req.RequestAction {
+	case validatorSetData.Actions.Propose.String():
+		proposal, signer, verifyErr := v.verifyProposal(req)
+		if verifyErr != nil {
+			err = verifyErr
+			break
+		}
+
+		state = &validatorSetData.ProposalState{
+			Proposal:  proposal,
+			Approvals: []string{signer},
+			Status:    validatorSetData.ProposalStatus.Pending.String(),
+		}
+
+	case validatorSetData.Actions.Approve.String():
+		_, proposalState, signer, verifyErr := v.verifyApproval(req)
+		if verifyErr != nil {
+			err = verifyErr
+			break
+		}
+
+		state = proposalState
+		state.Approvals = append(state.Approvals, signer)
+
+	default:
+		err = errors.New("service not support " + req.RequestAction + "@" + v.Name())
+	}
+
+	if err != nil {
+		log.Log.Error("verify validator set request failed: ", err.Error())
+		return
+	}
+
+	history := v.trySchedule(state, blk.Header.Height)
+
+	stateItem, err := proposalItem(*state)
+	if err != nil {
+		return
+	}
+
+	kvList := []kvDatabase.KVItem{stateItem}
+	if history != nil {
+		hItem, hErr := historyItem(history)
+		if hErr != nil {
+			err = hErr
+			return
+		}
+
+		kvList = append(kvList, hItem)
+	}
+
+	executed := executedResult(state)
+	err = v.stateRoot.CommitResult(blk.Header.Height, actIndex, kvList, nil, executed)
+	if err != nil {
+		log.Log.Error("save validator set proposal failed: ", err.Error())
+		return
+	}
+
+	if history != nil {
+		v.history = history
+		log.Log.Println("validator set change scheduled @height ", state.Proposal.EffectiveHeight)
+	}
+
+	result = executed
+	return
+}
+
+func executedResult(state *validatorSetData.ProposalState) (result applicationResult.Entity) {
+	if state.Status == validatorSetData.ProposalStatus.Scheduled.String() {
+		result.Events = append(result.Events, applicationResult.Event{
+			Name: state.Status,
+			Data: state.Proposal.ProposalData,
+		})
+	}
+
+	result.Data = state
+	return
+}
+
+//the caller must hold the operate lock
+func (v *ValidatorSetApplication) isApprovalOfPooledProposal(req blockchainRequest.Entity) bool {
+	if req.RequestAction != validatorSetData.Actions.Approve.String() {
+		return false
+	}
+
+	approval, _, err := v.approvalFromRequest(req)
+	if err != nil {
+		return false
+	}
+
+	v.poolLock.Lock()
+	defer v.poolLock.Unlock()
+
+	for _, pooled := range v.reqPool {
+		if pooled.RequestAction != validatorSetData.Actions.Propose.String() {
+			continue
+		}
+
+		proposal := validatorSetData.Proposal{}
+		if json.Unmarshal(pooled.Data, &proposal) != nil {
+			continue
+		}
+
+		if proposal.Seal.HexHash() == approval.ProposalHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *ValidatorSetApplication) StateRoot() (root []byte) {
+	return v.stateRoot.Root()
+}
+
+func (v *ValidatorSetApplication) RevertTo(height uint64) (err error) {
+	v.operateLock.Lock()
+	defer v.operateLock.Unlock()
+
+	err = v.stateRoot.RevertTo(height)
+	if err != nil {
+		return
+	}
+
+	return v.loadHistory()
+}
+
+func (v *ValidatorSetApplication) PruneUndo(height uint64) (err error) {
+	v.operateLock.Lock()
+	defer v.operateLock.Unlock()
+
+	return v.stateRoot.PruneUndo(height)
+}
+
+func (v *ValidatorSetApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	v.operateLock.Lock()
+	defer v.operateLock.Unlock()
+
+	kvList, next = v.stateRoot.Snapshot(start, count)
+	return
+}
+
+func (v *ValidatorSetApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) {
+	v.operateLock.Lock()
+	defer v.operateLock.Unlock()
+
+	err = v.stateRoot.Restore(kvList, first)
+	if err != nil {
+		return
+	}
+
+	return v.loadHistory()
+}
+
+//only the requests valid on the current state are packed, an approval waits in the pool until its proposal is
+//on chain, so the replicas could verify every request of the block before executing any of them.
+func (v *ValidatorSetApplication) RequestsForBlock(_ block.Entity) (reqList []blockchainRequest.Entity, cnt uint32) {
+	v.operateLock.RLock()
+	defer v.operateLock.RUnlock()
+
+	var waiting []blockchainRequest.Entity
+	packedApprovals := map[string]bool{}
+
+	v.poolLock.Lock()
+	for key, req := range v.reqPool {
+		if req.RequestAction == validatorSetData.Actions.Approve.String() {
+			approval, _, signer, err := v.verifyApproval(req)
+			if err != nil {
+				waiting = append(waiting, req)
+				continue
+			}
+
+			approvalKey := approval.ProposalHash + signer
+			if packedApprovals[approvalKey] {
+				continue
+			}
+			packedApprovals[approvalKey] = true
+		} else if v.verifyRequest(req) != nil {
+			delete(v.reqPool, key)
+			continue
+		}
+
+		reqList = append(reqList, req)
+	}
+	v.poolLock.Unlock()
+
+	for _, req := range waiting {
+		if !v.isApprovalOfPooledProposal(req) {
+			v.poolLock.Lock()
+			delete(v.reqPool, req.Seal.HexHash())
+			v.poolLock.Unlock()
+		}
+	}
+
+	cnt = uint32(len(reqList))
+	return
+}
+
+func (v *ValidatorSetApplication) Information() (info service.BasicInformation) {
+	info.Name = v.Name()
+	info.Description = "this is a validator set governance application"
+
+	info.Api.Protocol = service.ApiProtocols.INTERNAL.String()
+	info.Api.Address = ""
+	info.Api.ApiList = []service.ApiInterface{}
+	return
+}
+
+func (v *ValidatorSetApplication) GetActionAsRequest(req blockchainRequest.Entity) blockchainRequest.Entity {
+	return req
+}
+
+//the members of the next block, it is the member source of the consensus
+func (v *ValidatorSetApplication) ConsensusMembers() (keys [][]byte, err error) {
+	v.operateLock.RLock()
+	defer v.operateLock.RUnlock()
+
+	return memberKeys(v.setAt(v.currentHeight() + 1))
+}
+
+//the members of the set effective at the height, they decided the block at the height
+func (v *ValidatorSetApplication) ConsensusMembersAt(height uint64) (keys [][]byte, err error) {
+	v.operateLock.RLock()
+	defer v.operateLock.RUnlock()
+
+	return memberKeys(v.setAt(height))
+}
+
+func memberKeys(set validatorSetData.ValidatorSet) (keys [][]byte, err error) {
+	for _, m := range set.Members {
+		key, decodeErr := hex.DecodeString(m)
+		if decodeErr != nil {
+			err = decodeErr
+			return
+		}
+
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+func NewApplicationInterface(kvDriver kvDatabase.IDriver, tools crypto.Tools, genesisMembers []string) (app *ValidatorSetApplication, err error) {
+	v := ValidatorSetApplication{
+		CryptoTools: tools,
+		kvStorage:   kvDriver,
+	}
+
+	v.reqPool = map[string]blockchainRequest.Entity{}
+
+	v.stateRoot, err = chainStructure.NewStateRootRecorder(kvDriver, tools.HashCalculator)
+	if err != nil {
+		return
+	}
+
+	err = v.loadHistory()
+	if err != nil {
+		return
+	}
+
+	if len(v.history) == 0 {
+		v.history = []validatorSetData.ValidatorSet{
+			{
+				EffectiveHeight: 0,
+				Members:         genesisMembers,
+			},
+		}
+
+		err = v.saveHistory(v.history)
+		if err != nil {
+			return
+		}
+	}
+
+	app = &v
+	return
+}
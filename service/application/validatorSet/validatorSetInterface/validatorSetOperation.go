@@ -0,0 +1,348 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package validatorSetInterface
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/metadata/blockchainRequest"
+	"github.com/SealSC/SealABC/metadata/seal"
+	"github.com/SealSC/SealABC/service/application/validatorSet/validatorSetData"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+const historyKey = "validatorSetHistory"
+const proposalKeyPrefix = "validatorSetProposal-"
+
+func proposalKey(hash string) []byte {
+	return []byte(proposalKeyPrefix + hash)
+}
+
+func (v *ValidatorSetApplication) loadHistory() (err error) {
+	kv, err := v.kvStorage.Get([]byte(historyKey))
+	if err != nil {
+		return
+	}
+
+	if !kv.Exists {
+		return
+	}
+
+	err = json.Unmarshal(kv.Data, &v.history)
+	return
+}
+
+func historyItem(history []validatorSetData.ValidatorSet) (item kvDatabase.KVItem, err error) {
+	historyBytes, err := json.Marshal(history)
+	if err != nil {
+		return
+	}
+
+	item = kvDatabase.KVItem{
+		Key:  []byte(historyKey),
+		Data: historyBytes,
+	}
+	return
+}
+
+func (v *ValidatorSetApplication) saveHistory(history []validatorSetData.ValidatorSet) (err error) {
+	item, err := historyItem(history)
+	if err != nil {
+		return
+	}
+
+	err = v.kvStorage.Put(item)
+	return
+}
+
+func (v *ValidatorSetApplication) getProposal(hash string) (state *validatorSetData.ProposalState, err error) {
+	kv, err := v.kvStorage.Get(proposalKey(hash))
+	if err != nil {
+		return
+	}
+
+	if !kv.Exists {
+		return
+	}
+
+	state = &validatorSetData.ProposalState{}
+	err = json.Unmarshal(kv.Data, state)
+	return
+}
+
+func proposalItem(state validatorSetData.ProposalState) (item kvDatabase.KVItem, err error) {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	item = kvDatabase.KVItem{
+		Key:  proposalKey(state.Proposal.Seal.HexHash()),
+		Data: stateBytes,
+	}
+	return
+}
+
+//the set effective at the height is the last one whose effective height is not greater than it
+func (v *ValidatorSetApplication) setAt(height uint64) (set validatorSetData.ValidatorSet) {
+	for _, s := range v.history {
+		if s.EffectiveHeight > height {
+			break
+		}
+
+		set = s
+	}
+
+	return
+}
+
+func (v *ValidatorSetApplication) currentHeight() uint64 {
+	if v.chainInterface == nil {
+		return 0
+	}
+
+	return v.chainInterface.CurrentHeight()
+}
+
+func isMemberOf(set validatorSetData.ValidatorSet, key string) bool {
+	for _, m := range set.Members {
+		if m == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+//the same quorum as the BFT consensus
+func quorumOf(set validatorSetData.ValidatorSet) int {
+	return consensus.QuorumOf(len(set.Members))
+}
+
+func (v *ValidatorSetApplication) verifySeal(data interface{}, s seal.Entity, set validatorSetData.ValidatorSet) (signer string, err error) {
+	dataBytes, err := structSerializer.ToMFBytes(data)
+	if err != nil {
+		return
+	}
+
+	_, err = s.Verify(dataBytes, v.CryptoTools.HashCalculator)
+	if err != nil {
+		return
+	}
+
+	signer = s.HexPublicKey()
+	if !isMemberOf(set, signer) {
+		err = errors.New("signer is not a member of the validator set")
+	}
+	return
+}
+
+func verifyOperation(proposal validatorSetData.ProposalData) (err error) {
+	if _, err = hex.DecodeString(proposal.PublicKey); err != nil || proposal.PublicKey == "" {
+		err = errors.New("invalid public key")
+		return
+	}
+
+	switch proposal.Operation {
+	case validatorSetData.Operations.Add.String(), validatorSetData.Operations.Remove.String():
+		return
+
+	case validatorSetData.Operations.Rotate.String():
+		if _, err = hex.DecodeString(proposal.NewPublicKey); err != nil || proposal.NewPublicKey == "" {
+			err = errors.New("invalid new public key")
+		}
+		return
+
+	default:
+		err = errors.New("unsupported operation: " + proposal.Operation)
+		return
+	}
+}
+
+//the requests are verified against the set of the next block, which is the block they will be packed in
+func (v *ValidatorSetApplication) verifyProposal(req blockchainRequest.Entity) (proposal validatorSetData.Proposal, signer string, err error) {
+	err = json.Unmarshal(req.Data, &proposal)
+	if err != nil {
+		err = errors.New("unmarshal proposal failed")
+		return
+	}
+
+	nextHeight := v.currentHeight() + 1
+	signer, err = v.verifySeal(proposal.ProposalData, proposal.Seal, v.setAt(nextHeight))
+	if err != nil {
+		return
+	}
+
+	err = verifyOperation(proposal.ProposalData)
+	if err != nil {
+		return
+	}
+
+	if proposal.EffectiveHeight <= nextHeight {
+		err = errors.New("effective height must be greater than the next block height")
+		return
+	}
+
+	existing, err := v.getProposal(proposal.Seal.HexHash())
+	if err != nil {
+		return
+	}
+
+	if existing != nil {
+		err = errors.New("proposal already exists")
+	}
+	return
+}
+
+func (v *ValidatorSetApplication) approvalFromRequest(req blockchainRequest.Entity) (approval validatorSetData.Approval, signer string, err error) {
+	err = json.Unmarshal(req.Data, &approval)
+	if err != nil {
+		err = errors.New("unmarshal approval failed")
+		return
+	}
+
+	signer, err = v.verifySeal(approval.ApprovalData, approval.Seal, v.setAt(v.currentHeight()+1))
+	return
+}
+
+func (v *ValidatorSetApplication) verifyApproval(req blockchainRequest.Entity) (approval validatorSetData.Approval, state *validatorSetData.ProposalState, signer string, err error) {
+	approval, signer, err = v.approvalFromRequest(req)
+	if err != nil {
+		return
+	}
+
+	state, err = v.getProposal(approval.ProposalHash)
+	if err != nil {
+		return
+	}
+
+	if state == nil {
+		err = errors.New("no such proposal")
+		return
+	}
+
+	if state.Status != validatorSetData.ProposalStatus.Pending.String() {
+		err = errors.New("proposal is " + state.Status)
+		return
+	}
+
+	for _, a := range state.Approvals {
+		if a == signer {
+			err = errors.New("already approved")
+			return
+		}
+	}
+
+	return
+}
+
+func (v *ValidatorSetApplication) verifyRequest(req blockchainRequest.Entity) (err error) {
+	switch req.RequestAction {
+	case validatorSetData.Actions.Propose.String():
+		_, _, err = v.verifyProposal(req)
+
+	case validatorSetData.Actions.Approve.String():
+		_, _, _, err = v.verifyApproval(req)
+
+	default:
+		err = errors.New("service not support " + req.RequestAction + "@" + v.Name())
+	}
+
+	return
+}
+
+//apply the proposal on the latest set, the result is nil if the operation could not be applied on it
+func applyOperation(latest validatorSetData.ValidatorSet, proposal validatorSetData.ProposalData) (newSet *validatorSetData.ValidatorSet) {
+	set := validatorSetData.ValidatorSet{
+		EffectiveHeight: proposal.EffectiveHeight,
+	}
+
+	switch proposal.Operation {
+	case validatorSetData.Operations.Add.String():
+		if isMemberOf(latest, proposal.PublicKey) {
+			return
+		}
+		set.Members = append(append(set.Members, latest.Members...), proposal.PublicKey)
+
+	case validatorSetData.Operations.Remove.String():
+		if !isMemberOf(latest, proposal.PublicKey) || len(latest.Members) <= 1 {
+			return
+		}
+
+		for _, m := range latest.Members {
+			if m != proposal.PublicKey {
+				set.Members = append(set.Members, m)
+			}
+		}
+
+	case validatorSetData.Operations.Rotate.String():
+		if !isMemberOf(latest, proposal.PublicKey) || isMemberOf(latest, proposal.NewPublicKey) {
+			return
+		}
+
+		for _, m := range latest.Members {
+			if m == proposal.PublicKey {
+				m = proposal.NewPublicKey
+			}
+			set.Members = append(set.Members, m)
+		}
+
+	default:
+		return
+	}
+
+	return &set
+}
+
+//schedule the proposal once a quorum of the members at the executing block approved it.
+//the proposal expires if the effective height was passed, and it is rejected if it could not apply on the latest set.
+//returns the new history if the proposal is scheduled, the caller saves it.
+func (v *ValidatorSetApplication) trySchedule(state *validatorSetData.ProposalState, height uint64) (history []validatorSetData.ValidatorSet) {
+	set := v.setAt(height)
+
+	approvalCount := 0
+	for _, a := range state.Approvals {
+		if isMemberOf(set, a) {
+			approvalCount += 1
+		}
+	}
+
+	if approvalCount < quorumOf(set) {
+		return
+	}
+
+	proposal := state.Proposal.ProposalData
+	if proposal.EffectiveHeight <= height {
+		state.Status = validatorSetData.ProposalStatus.Expired.String()
+		return
+	}
+
+	latest := v.history[len(v.history)-1]
+	newSet := applyOperation(latest, proposal)
+	if newSet == nil || newSet.EffectiveHeight <= latest.EffectiveHeight {
+		state.Status = validatorSetData.ProposalStatus.Rejected.String()
+		return
+	}
+
+	history = append(append([]validatorSetData.ValidatorSet{}, v.history...), *newSet)
+	state.Status = validatorSetData.ProposalStatus.Scheduled.String()
+	return
+}
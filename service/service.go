@@ -18,6 +18,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/network/http"
@@ -86,6 +87,15 @@ type IService interface {
 
 	//service information
 	Information() (info BasicInformation)
+
+	//stop the service and release its network and api resources, engine will call this method when shutdown
+	Stop(ctx context.Context) (err error)
+}
+
+//a service builds its requests on its own requests not decided yet for the pipelined consensus, the services not
+//implement it wait for their pending requests to be decided before building the new ones.
+type IPipelinedService interface {
+	RequestsBasedOn(pending [][]byte) (req [][]byte, cnt uint32)
 }
 
 //a blank service to simplify the creation of new services
@@ -120,6 +130,10 @@ func (b BlankService) Information() (info BasicInformation) {
 	return
 }
 
+func (b BlankService) Stop(ctx context.Context) (err error) {
+	return
+}
+
 func Load() {
 	enum.SimpleBuild(&ApiProtocols)
 	enum.SimpleBuild(&ApiProtocolMethod)
@@ -18,6 +18,7 @@
 package chainApi
 
 import (
+	"context"
 	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainApi/httpJSON"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainApi/httpJSON/actions"
@@ -41,3 +42,7 @@ func NewServer(cfg Config, chain *chainStructure.Blockchain, p2p *chainNetwork.P
 
 	return &api
 }
+
+func (a *ApiServers) Stop(ctx context.Context) (err error) {
+	return a.HttpJSON.Stop(ctx)
+}
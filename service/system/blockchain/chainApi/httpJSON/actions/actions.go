@@ -122,6 +122,9 @@ func NewActions(basePath string, chain *chainStructure.Blockchain, p2p *chainNet
 		&getTransactions{},
 		&queryApplication{},
 		&getCurrentHeight{},
+		&getReceiptByHash{},
+		&getRequestProof{},
+		&getSyncProgress{},
 	}
 	action.appQueryHandler = map[string]applicationQueryHandler{}
 
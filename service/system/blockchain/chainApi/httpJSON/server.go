@@ -18,6 +18,7 @@
 package httpJSON
 
 import (
+	"context"
 	"github.com/SealSC/SealABC/network/http"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainApi/httpJSON/actions"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainNetwork"
@@ -34,6 +35,10 @@ func (a ApiServer) Address() string {
 	return a.server.Config.Address
 }
 
+func (a *ApiServer) Stop(ctx context.Context) (err error) {
+	return a.server.Shutdown(ctx)
+}
+
 func NewApiServer(cfg http.Config,
 	chain *chainStructure.Blockchain,
 	p2p *chainNetwork.P2PService,
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainArchive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/metadata/block"
+	"io"
+)
+
+//an archive is a gzip stream of length-prefixed records, the length is a big endian uint32 and the record is json.
+//the first record is the header, and every record after it is a block with its finality proof in height order.
+const Format = "SealABC-chain-archive"
+const Version = 1
+
+//a record larger than it is treated as a broken archive
+const maxRecordSize = 256 << 20
+
+type Header struct {
+	Format     string
+	Version    int
+	FromHeight uint64
+	ToHeight   uint64
+}
+
+type Record struct {
+	Block         block.Entity
+	FinalityProof []byte
+}
+
+type Writer struct {
+	gz  *gzip.Writer
+	buf *bufio.Writer
+}
+
+func NewWriter(w io.Writer, fromHeight uint64, toHeight uint64) (aw *Writer, err error) {
+	buf := bufio.NewWriter(w)
+	aw = &Writer{
+		gz:  gzip.NewWriter(buf),
+		buf: buf,
+	}
+
+	err = aw.write(Header{
+		Format:     Format,
+		Version:    Version,
+		FromHeight: fromHeight,
+		ToHeight:   toHeight,
+	})
+	return
+}
+
+func (w *Writer) write(data interface{}) (err error) {
+	recordBytes, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	length := make([]byte, 4, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(recordBytes)))
+	_, err = w.gz.Write(append(length, recordBytes...))
+	return
+}
+
+func (w *Writer) Write(blk block.Entity, finalityProof []byte) (err error) {
+	return w.write(Record{
+		Block:         blk,
+		FinalityProof: finalityProof,
+	})
+}
+
+func (w *Writer) Close() (err error) {
+	err = w.gz.Close()
+	if err != nil {
+		return
+	}
+
+	return w.buf.Flush()
+}
+
+type Reader struct {
+	Header Header
+
+	gz *gzip.Reader
+}
+
+func NewReader(r io.Reader) (ar *Reader, err error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return
+	}
+
+	ar = &Reader{
+		gz: gz,
+	}
+
+	err = ar.read(&ar.Header)
+	if err != nil {
+		return
+	}
+
+	if ar.Header.Format != Format || ar.Header.Version != Version {
+		err = errors.New("not a supported chain archive")
+	}
+	return
+}
+
+//returns io.EOF at the end of the archive
+func (r *Reader) read(data interface{}) (err error) {
+	length := make([]byte, 4, 4)
+	_, err = io.ReadFull(r.gz, length)
+	if err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(length)
+	if size > maxRecordSize {
+		err = errors.New("record is too large")
+		return
+	}
+
+	recordBytes := make([]byte, size, size)
+	_, err = io.ReadFull(r.gz, recordBytes)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(recordBytes, data)
+	return
+}
+
+func (r *Reader) Read() (record Record, err error) {
+	err = r.read(&record)
+	return
+}
+
+func (r *Reader) Close() (err error) {
+	return r.gz.Close()
+}
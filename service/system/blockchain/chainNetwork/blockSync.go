@@ -18,61 +18,423 @@
 package chainNetwork
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/metadata/message"
 	"github.com/SealSC/SealABC/network"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
-var syncBlockWait sync.WaitGroup
-var Syncing = false
+//the headers are fetched in batches from one peer, then the bodies of a batch are downloaded in parallel from several peers.
+const syncHeadersBatch = 256
+const syncBodiesBatch = 16
+const syncRequestTimeout = 10 * time.Second
+const syncMaxAttempts = 3
+
+//a peer scored under the floor is not asked while other peers are available
+const peerScoreMax = 100
+const peerScoreFloor = -20
+const peerScoreSuccess = 1
+const peerScoreTimeout = -5
+const peerScoreInvalid = -10
+
+type SyncProgress struct {
+	Syncing       bool
+	StartHeight   uint64
+	HeaderHeight  uint64
+	CurrentHeight uint64
+	TargetHeight  uint64
+	PeerScores    map[string]int
+}
+
+type syncState struct {
+	syncing    bool
+	progress   SyncProgress
+	peerScores map[string]int
+	pending    map[string]chan message.Message
+	requestSeq uint64
+}
+
+func (p *P2PService) IsSyncing() bool {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	return p.sync.syncing
+}
+
+func (p *P2PService) SyncProgress() (progress SyncProgress) {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	progress = p.sync.progress
+	progress.Syncing = p.sync.syncing
+	progress.PeerScores = map[string]int{}
+	for peer, score := range p.sync.peerScores {
+		progress.PeerScores[peer] = score
+	}
+
+	return
+}
 
 func (p *P2PService) StartSync(nodes []network.Node, targetHeight uint64) {
-	if Syncing {
+	p.syncLock.Lock()
+	if p.sync.syncing {
+		p.syncLock.Unlock()
 		return
 	}
 
-	p.syncLock.Lock()
-	Syncing = true
+	p.sync.syncing = true
+	p.sync.progress = SyncProgress{
+		TargetHeight: targetHeight,
+	}
+	p.syncLock.Unlock()
 
 	defer func() {
 		if r := recover(); r != nil {
 			log.Log.Error("got a panic: ", r)
 		}
 
-		Syncing = false
+		p.syncLock.Lock()
+		p.sync.syncing = false
 		p.syncLock.Unlock()
 	}()
 
-	seedsCnt := len(nodes)
-	for s := p.chain.CurrentHeight(); s < targetHeight; s++ {
-		var syncErr error = nil
-		seedIdx := 0
-		for i := 0; i < seedsCnt; i++ {
-			seedIdx = (int(s) + 1) % seedsCnt
-			syncErr = p.syncBlockFrom(nodes[seedIdx], s+1)
-
-			if syncErr == nil {
-				syncBlockWait.Add(1)
-				break
+	//an empty chain is restored from a snapshot if possible, or syncs from the genesis block
+	if p.fastSync && p.chain.GetLastBlock() == nil {
+		if err := p.syncSnapshot(nodes); err != nil {
+			log.Log.Warn("fast sync failed, sync all the blocks: ", err.Error())
+		}
+	}
+
+	prevHash := p.chain.Config.GenesisHash
+	startHeight := uint64(0)
+	if last := p.chain.GetLastBlock(); last != nil {
+		prevHash = last.Seal.Hash
+		startHeight = last.Header.Height + 1
+	}
+
+	p.updateProgress(func(progress *SyncProgress) {
+		progress.StartHeight = startHeight
+		progress.HeaderHeight = startHeight
+		progress.CurrentHeight = startHeight
+	})
+
+	for next := startHeight; next <= targetHeight; {
+		count := targetHeight - next + 1
+		if count > syncHeadersBatch {
+			count = syncHeadersBatch
+		}
+
+		headers, err := p.syncHeaders(nodes, next, count, prevHash)
+		if err != nil {
+			log.Log.Error("sync headers from ", next, " failed: ", err.Error())
+			return
+		}
+
+		p.updateProgress(func(progress *SyncProgress) {
+			progress.HeaderHeight = next + uint64(len(headers)) - 1
+		})
+
+		blocks, err := p.syncBodies(nodes, headers)
+		for _, blk := range blocks {
+			if applyErr := p.applySyncedBlock(blk); applyErr != nil {
+				log.Log.Error("apply synced block @", blk.Block.Header.Height, " failed: ", applyErr.Error())
+				return
 			}
+
+			p.updateProgress(func(progress *SyncProgress) {
+				progress.CurrentHeight = blk.Block.Header.Height
+			})
+		}
+
+		if err != nil {
+			log.Log.Error("sync bodies from ", next, " failed: ", err.Error())
+			return
+		}
+
+		next += uint64(len(headers))
+		prevHash = headers[len(headers)-1].Seal.Hash
+	}
+
+	log.Log.Println("sync blocks to ", targetHeight, " over.")
+}
+
+//the verifier checks the finality proof of a synced block, the blocks are not checked if it's not set.
+func (p *P2PService) SetFinalityVerifier(verifier func(blk block.Entity, proof []byte) error) {
+	p.finalityVerifier = verifier
+}
+
+func (p *P2PService) applySyncedBlock(blk syncBlock) (err error) {
+	err = p.chain.VerifyRoots(blk.Block)
+	if err != nil {
+		return
+	}
+
+	return p.chain.AddBlock(blk.Block, blk.FinalityProof)
+}
+
+func (p *P2PService) updateProgress(update func(progress *SyncProgress)) {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	update(&p.sync.progress)
+}
+
+func (p *P2PService) scorePeer(node network.Node, delta int) {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	score := p.sync.peerScores[node.ServeAddress] + delta
+	if score > peerScoreMax {
+		score = peerScoreMax
+	}
+
+	p.sync.peerScores[node.ServeAddress] = score
+}
+
+//the peers ordered by score, the peers under the floor are dropped unless no other peer is left.
+func (p *P2PService) rankedPeers(nodes []network.Node) (ranked []network.Node) {
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	for _, n := range nodes {
+		if p.sync.peerScores[n.ServeAddress] >= peerScoreFloor {
+			ranked = append(ranked, n)
+		}
+	}
+
+	if len(ranked) == 0 {
+		ranked = append(ranked, nodes...)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return p.sync.peerScores[ranked[i].ServeAddress] > p.sync.peerScores[ranked[j].ServeAddress]
+	})
+	return
+}
+
+//send a range request to the peer and wait for the reply with the same request id until timeout
+func (p *P2PService) syncRequest(node network.Node, msgType enum.Element, from uint64, count uint64) (reply message.Message, err error) {
+	p.syncLock.Lock()
+	p.sync.requestSeq += 1
+	id := strconv.FormatUint(p.sync.requestSeq, 10)
+	replyChan := make(chan message.Message, 1)
+	p.sync.pending[id] = replyChan
+	p.syncLock.Unlock()
+
+	defer func() {
+		p.syncLock.Lock()
+		delete(p.sync.pending, id)
+		p.syncLock.Unlock()
+	}()
+
+	_, err = p.NetworkService.SendTo(node, newSyncRangeMessage(msgType, id, from, count))
+	if err != nil {
+		return
+	}
+
+	select {
+	case reply = <-replyChan:
+	case <-time.After(syncRequestTimeout):
+		err = errors.New("sync request timeout")
+	}
+	return
+}
+
+func (p *P2PService) receiveSyncReply(msg message.Message) {
+	id, err := getRequestIDFromSyncReply(msg)
+	if err != nil {
+		log.Log.Error("invalid sync reply: ", err.Error())
+		return
+	}
+
+	p.syncLock.Lock()
+	defer p.syncLock.Unlock()
+
+	replyChan, exists := p.sync.pending[id]
+	if !exists {
+		return
+	}
+
+	select {
+	case replyChan <- msg:
+	default:
+	}
+}
+
+func (p *P2PService) syncHeaders(nodes []network.Node, from uint64, count uint64, prevHash []byte) (headers []syncHeader, err error) {
+	peers := p.rankedPeers(nodes)
+	err = errors.New("no peer to sync")
+
+	for i := 0; i < len(peers) && i < syncMaxAttempts; i++ {
+		reply, reqErr := p.syncRequest(peers[i], MessageTypes.SyncHeaders, from, count)
+		if reqErr != nil {
+			err = reqErr
+			p.scorePeer(peers[i], peerScoreTimeout)
+			continue
+		}
+
+		headersReply := syncHeadersReplyMessage{}
+		if jsonErr := json.Unmarshal(reply.Payload, &headersReply); jsonErr != nil {
+			err = jsonErr
+			p.scorePeer(peers[i], peerScoreInvalid)
+			continue
+		}
+
+		if len(headersReply.Headers) == 0 {
+			err = errors.New("peer has no block @" + strconv.FormatUint(from, 10))
+			continue
+		}
+
+		if verifyErr := p.verifyHeaders(headersReply.Headers, from, count, prevHash); verifyErr != nil {
+			err = verifyErr
+			p.scorePeer(peers[i], peerScoreInvalid)
+			continue
+		}
+
+		p.scorePeer(peers[i], peerScoreSuccess)
+		return headersReply.Headers, nil
+	}
+
+	return
+}
+
+//the headers must be signed, continuous from the height and linked to the previous block
+func (p *P2PService) verifyHeaders(headers []syncHeader, from uint64, count uint64, prevHash []byte) (err error) {
+	if uint64(len(headers)) > count {
+		return errors.New("too many headers")
+	}
+
+	for i, h := range headers {
+		if h.Header.Height != from+uint64(i) {
+			return errors.New("headers are not continuous")
+		}
+
+		if !bytes.Equal(h.Header.PrevBlock, prevHash) {
+			return errors.New("header is not linked to the previous block")
+		}
+
+		blk := block.Entity{}
+		blk.Header = h.Header
+		blk.Seal = h.Seal
+		if _, err = blk.Verify(p.chain.Config.CryptoTools); err != nil {
+			return
+		}
+
+		prevHash = h.Seal.Hash
+	}
+
+	return
+}
+
+//download the bodies of the headers in parallel, returns the blocks in order until the first failed batch.
+func (p *P2PService) syncBodies(nodes []network.Node, headers []syncHeader) (blocks []syncBlock, err error) {
+	peers := p.rankedPeers(nodes)
+	if len(peers) == 0 {
+		err = errors.New("no peer to sync")
+		return
+	}
+
+	batchCount := (len(headers) + syncBodiesBatch - 1) / syncBodiesBatch
+	batches := make([][]syncBlock, batchCount)
+	batchErrors := make([]error, batchCount)
+
+	wg := sync.WaitGroup{}
+	for b := 0; b < batchCount; b++ {
+		end := (b + 1) * syncBodiesBatch
+		if end > len(headers) {
+			end = len(headers)
+		}
+
+		wg.Add(1)
+		go func(idx int, batchHeaders []syncHeader) {
+			defer wg.Done()
+			batches[idx], batchErrors[idx] = p.syncBodiesBatch(peers, idx, batchHeaders)
+		}(b, headers[b*syncBodiesBatch:end])
+	}
+	wg.Wait()
+
+	for b := 0; b < batchCount; b++ {
+		if batchErrors[b] != nil {
+			err = batchErrors[b]
+			return
 		}
 
-		if syncErr != nil {
-			log.Log.Error("sync block ", s, " failed")
-			time.Sleep(time.Second)
+		blocks = append(blocks, batches[b]...)
+	}
+	return
+}
+
+//every batch starts from a different peer and turns to the next peer on failure
+func (p *P2PService) syncBodiesBatch(peers []network.Node, batchIdx int, headers []syncHeader) (blocks []syncBlock, err error) {
+	from := headers[0].Header.Height
+	count := uint64(len(headers))
+
+	for attempt := 0; attempt < syncMaxAttempts; attempt++ {
+		peer := peers[(batchIdx+attempt)%len(peers)]
+		reply, reqErr := p.syncRequest(peer, MessageTypes.SyncBodies, from, count)
+		if reqErr != nil {
+			err = reqErr
+			p.scorePeer(peer, peerScoreTimeout)
 			continue
 		}
 
-		log.Log.Println("waiting for block ", s+1, " from node ", nodes[seedIdx].ServeAddress)
-		syncBlockWait.Wait()
-		log.Log.Println("sync block ", s+1, " over.")
+		bodiesReply := syncBodiesReplyMessage{}
+		if jsonErr := json.Unmarshal(reply.Payload, &bodiesReply); jsonErr != nil {
+			err = jsonErr
+			p.scorePeer(peer, peerScoreInvalid)
+			continue
+		}
+
+		if verifyErr := p.verifyBodies(bodiesReply.Blocks, headers); verifyErr != nil {
+			err = verifyErr
+			p.scorePeer(peer, peerScoreInvalid)
+			continue
+		}
+
+		p.scorePeer(peer, peerScoreSuccess)
+		return bodiesReply.Blocks, nil
 	}
+
 	return
 }
 
-func (p *P2PService) syncBlockFrom(node network.Node, height uint64) (err error) {
-	reqMsg := newSyncBlockMessage(height)
-	p.NetworkService.SendTo(node, reqMsg)
+//the blocks must be the blocks of the synced headers, their bodies must match the transactions root,
+//and they must be decided by the consensus members.
+func (p *P2PService) verifyBodies(blocks []syncBlock, headers []syncHeader) (err error) {
+	if len(blocks) != len(headers) {
+		return errors.New("blocks count is not same as headers")
+	}
+
+	for i, synced := range blocks {
+		blk := synced.Block
+		if !bytes.Equal(blk.Seal.Hash, headers[i].Seal.Hash) {
+			return errors.New("block is not the one of the header")
+		}
+
+		if _, err = blk.Verify(p.chain.Config.CryptoTools); err != nil {
+			return
+		}
+
+		if err = p.chain.VerifyBlockBody(blk); err != nil {
+			return
+		}
+
+		if p.finalityVerifier == nil {
+			continue
+		}
+
+		if err = p.finalityVerifier(blk, synced.FinalityProof); err != nil {
+			return
+		}
+	}
+
 	return
 }
@@ -21,6 +21,8 @@ import (
 	"errors"
 	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/metadata/message"
 	"github.com/SealSC/SealABC/network"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
 	"sync"
@@ -28,6 +30,9 @@ import (
 
 type P2PService struct {
 	syncLock              sync.Mutex
+	sync                  syncState
+	finalityVerifier      func(blk block.Entity, proof []byte) error
+	fastSync              bool
 	chain                 *chainStructure.Blockchain
 	networkMessageHandler map[string]p2pMessageHandler
 
@@ -59,6 +64,8 @@ func startChainP2PNetwork(cfg network.Config, p2p *P2PService) (networkService n
 
 	err = networkService.Join(seedNodes, nil)
 
+	//the requests are gossiped again by the others, so they are the first dropped
+	network.SetMessagePriority(messageFamily, MessageTypes.PushRequest.String(), network.PRIORITY_LOW)
 	networkService.RegisterMessageProcessor(messageFamily, p2p.handleP2PMessage)
 	return
 }
@@ -70,10 +77,19 @@ func Load() {
 func NewNetwork(cfg network.Config, chain *chainStructure.Blockchain) *P2PService {
 	p2p := P2PService{}
 	p2p.networkMessageHandler = map[string]p2pMessageHandler{
-		MessageTypes.PushRequest.String():    p2p.handlePushRequest,
-		MessageTypes.SyncBlock.String():      p2p.handleSyncBlock,
-		MessageTypes.SyncBlockReply.String(): p2p.handleSyncBlockReply,
+		MessageTypes.PushRequest.String():      p2p.handlePushRequest,
+		MessageTypes.SyncHeaders.String():      p2p.handleSyncHeaders,
+		MessageTypes.SyncHeadersReply.String(): p2p.handleSyncReply,
+		MessageTypes.SyncBodies.String():       p2p.handleSyncBodies,
+		MessageTypes.SyncBodiesReply.String():  p2p.handleSyncReply,
+
+		MessageTypes.GetSnapshot.String():        p2p.handleGetSnapshot,
+		MessageTypes.SnapshotInfoReply.String():  p2p.handleSyncReply,
+		MessageTypes.GetSnapshotChunk.String():   p2p.handleGetSnapshotChunk,
+		MessageTypes.SnapshotChunkReply.String(): p2p.handleSyncReply,
 	}
+	p2p.sync.peerScores = map[string]int{}
+	p2p.sync.pending = map[string]chan message.Message{}
 
 	ns, err := startChainP2PNetwork(cfg, &p2p)
 	if err != nil {
@@ -85,3 +101,9 @@ func NewNetwork(cfg network.Config, chain *chainStructure.Blockchain) *P2PServic
 
 	return &p2p
 }
+
+func (p *P2PService) Stop() {
+	if p.NetworkService != nil {
+		p.NetworkService.Stop()
+	}
+}
@@ -19,55 +19,82 @@ package chainNetwork
 
 import (
 	"encoding/json"
-	"errors"
 	"github.com/SealSC/SealABC/dataStructure/enum"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
 	"github.com/SealSC/SealABC/metadata/message"
+	"github.com/SealSC/SealABC/metadata/seal"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
 )
 
 const messageFamily = "seal-chain-message"
 const messageVersion = "0.1"
 
 var MessageTypes struct {
-	PushRequest    enum.Element
-	SyncBlock      enum.Element
-	SyncBlockReply enum.Element
+	PushRequest      enum.Element
+	SyncHeaders      enum.Element
+	SyncHeadersReply enum.Element
+	SyncBodies       enum.Element
+	SyncBodiesReply  enum.Element
+
+	GetSnapshot        enum.Element
+	SnapshotInfoReply  enum.Element
+	GetSnapshotChunk   enum.Element
+	SnapshotChunkReply enum.Element
 }
 
-type syncBlockReplyMessage struct {
-	Success bool
-	Block   block.Entity
+//the request of a range of blocks from the height, the reply carries the same request id
+type syncRangeMessage struct {
+	RequestID string
+	From      uint64
+	Count     uint64
 }
 
-type syncBlockMessage struct {
-	BlockHeight uint64
+type syncHeader struct {
+	Header block.Header
+	Seal   seal.Entity
 }
 
-func getBlockFromSyncReplyMessage(msg message.Message) (blk *block.Entity, err error) {
-	replyMsg := syncBlockReplyMessage{}
-	err = json.Unmarshal(msg.Payload, &replyMsg)
-	if err != nil {
-		return
-	}
+type syncHeadersReplyMessage struct {
+	RequestID string
+	Headers   []syncHeader
+}
 
-	if !replyMsg.Success {
-		err = errors.New("block not exits")
-		return
-	}
+//the block with the finality proof of the consensus decided it
+type syncBlock struct {
+	Block         block.Entity
+	FinalityProof []byte
+}
 
-	blk = &replyMsg.Block
+type syncBodiesReplyMessage struct {
+	RequestID string
+	Blocks    []syncBlock
+}
+
+//a peer without a snapshot replies an empty info
+type snapshotInfoReplyMessage struct {
+	RequestID string
+	Info      chainStructure.SnapshotInfo
+}
+
+//the chunk request reuses the range message, From is the snapshot height and Count is the chunk index
+type snapshotChunkReplyMessage struct {
+	RequestID string
+	Data      []byte
+}
+
+func getSyncRangeFromMessage(msg message.Message) (syncRange syncRangeMessage, err error) {
+	err = json.Unmarshal(msg.Payload, &syncRange)
 	return
 }
 
-func getHeightFromSyncMessage(msg message.Message) (height uint64, err error) {
-	syncBlkMsg := syncBlockMessage{}
-	err = json.Unmarshal(msg.Payload, &syncBlkMsg)
-	if err != nil {
-		return
-	}
+func getRequestIDFromSyncReply(msg message.Message) (id string, err error) {
+	reply := struct {
+		RequestID string
+	}{}
 
-	height = syncBlkMsg.BlockHeight
+	err = json.Unmarshal(msg.Payload, &reply)
+	id = reply.RequestID
 	return
 }
 
@@ -80,30 +107,55 @@ func getBlockchainRequestFromPushRequestMessage(msg message.Message) (req blockc
 	return
 }
 
-func newSyncBlockMessage(height uint64) (msg message.Message) {
-	syncMsg := syncBlockMessage{
-		BlockHeight: height,
+func newSyncRangeMessage(msgType enum.Element, id string, from uint64, count uint64) (msg message.Message) {
+	syncMsg := syncRangeMessage{
+		RequestID: id,
+		From:      from,
+		Count:     count,
 	}
 
 	payload, _ := json.Marshal(syncMsg)
-	msg = newMessage(MessageTypes.SyncBlock, payload)
+	msg = newMessage(msgType, payload)
 	return
 }
 
-func newSyncBlockReplyMessage(blk *block.Entity, height uint64) (msg message.Message) {
-	replyMsg := syncBlockReplyMessage{}
+func newSyncHeadersReplyMessage(id string, headers []syncHeader) (msg message.Message) {
+	payload, _ := json.Marshal(syncHeadersReplyMessage{
+		RequestID: id,
+		Headers:   headers,
+	})
 
-	if blk == nil {
-		replyMsg.Success = false
-		replyMsg.Block.Header.Height = height
-	} else {
-		replyMsg.Success = true
-		replyMsg.Block = *blk
-	}
+	msg = newMessage(MessageTypes.SyncHeadersReply, payload)
+	return
+}
+
+func newSyncBodiesReplyMessage(id string, blocks []syncBlock) (msg message.Message) {
+	payload, _ := json.Marshal(syncBodiesReplyMessage{
+		RequestID: id,
+		Blocks:    blocks,
+	})
+
+	msg = newMessage(MessageTypes.SyncBodiesReply, payload)
+	return
+}
+
+func newSnapshotInfoReplyMessage(id string, info chainStructure.SnapshotInfo) (msg message.Message) {
+	payload, _ := json.Marshal(snapshotInfoReplyMessage{
+		RequestID: id,
+		Info:      info,
+	})
+
+	msg = newMessage(MessageTypes.SnapshotInfoReply, payload)
+	return
+}
 
-	payload, _ := json.Marshal(replyMsg)
-	msg = newMessage(MessageTypes.SyncBlockReply, payload)
+func newSnapshotChunkReplyMessage(id string, data []byte) (msg message.Message) {
+	payload, _ := json.Marshal(snapshotChunkReplyMessage{
+		RequestID: id,
+		Data:      data,
+	})
 
+	msg = newMessage(MessageTypes.SnapshotChunkReply, payload)
 	return
 }
 
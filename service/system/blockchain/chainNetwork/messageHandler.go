@@ -21,6 +21,7 @@ import (
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
 	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
 )
 
 type p2pMessageHandler func(msg network.Message) *network.Message
@@ -39,51 +40,110 @@ func (p *P2PService) handlePushRequest(msg network.Message) (_ *network.Message)
 	return
 }
 
-func (p *P2PService) handleSyncBlock(msg network.Message) (reply *network.Message) {
-	height, err := getHeightFromSyncMessage(msg.Message)
+//serve the headers of a range of blocks, the reply stops at the first block the local chain doesn't have.
+func (p *P2PService) handleSyncHeaders(msg network.Message) (reply *network.Message) {
+	syncRange, err := getSyncRangeFromMessage(msg.Message)
 	if err != nil {
 		log.Log.Error(err.Error())
-		replyMsg := newSyncBlockReplyMessage(nil, height)
-		reply = &network.Message{
-			Message: replyMsg,
+		return
+	}
+
+	if syncRange.Count > syncHeadersBatch {
+		syncRange.Count = syncHeadersBatch
+	}
+
+	var headers []syncHeader
+	for h := syncRange.From; h < syncRange.From+syncRange.Count; h++ {
+		blk, err := p.chain.GetBlockByHeight(h)
+		if err != nil {
+			break
 		}
 
-		return reply
+		headers = append(headers, syncHeader{
+			Header: blk.Header,
+			Seal:   blk.Seal,
+		})
 	}
-	blk, err := p.chain.GetBlockByHeight(height)
+
+	reply = &network.Message{
+		Message: newSyncHeadersReplyMessage(syncRange.RequestID, headers),
+	}
+	return
+}
+
+func (p *P2PService) handleSyncBodies(msg network.Message) (reply *network.Message) {
+	syncRange, err := getSyncRangeFromMessage(msg.Message)
 	if err != nil {
-		log.Log.Error("get block@", height, " failed")
-		replyMsg := newSyncBlockReplyMessage(nil, height)
-		reply = &network.Message{
-			Message: replyMsg,
+		log.Log.Error(err.Error())
+		return
+	}
+
+	if syncRange.Count > syncBodiesBatch {
+		syncRange.Count = syncBodiesBatch
+	}
+
+	var blocks []syncBlock
+	for h := syncRange.From; h < syncRange.From+syncRange.Count; h++ {
+		blk, err := p.chain.GetBlockByHeight(h)
+		if err != nil {
+			break
 		}
+
+		//the blocks saved before the finality proof was supported have no proof
+		proof, _ := p.chain.GetFinalityProof(h)
+		blocks = append(blocks, syncBlock{
+			Block:         blk,
+			FinalityProof: proof,
+		})
 	}
 
-	log.Log.Warn("block@", height, " sync to remote: ", msg.From.ServeAddress)
-	replyMsg := newSyncBlockReplyMessage(&blk, height)
+	log.Log.Warn("blocks@", syncRange.From, " count ", len(blocks), " sync to remote: ", msg.From.ServeAddress)
 	reply = &network.Message{
-		Message: replyMsg,
+		Message: newSyncBodiesReplyMessage(syncRange.RequestID, blocks),
 	}
+	return
+}
 
+//only the snapshot below the last block is served, the next block commits its root.
+func (p *P2PService) handleGetSnapshot(msg network.Message) (reply *network.Message) {
+	syncRange, err := getSyncRangeFromMessage(msg.Message)
+	if err != nil {
+		log.Log.Error(err.Error())
+		return
+	}
+
+	info, err := p.chain.LastSnapshot()
+	if err != nil || p.chain.CurrentHeight() <= info.Height {
+		info = chainStructure.SnapshotInfo{}
+	}
+
+	reply = &network.Message{
+		Message: newSnapshotInfoReplyMessage(syncRange.RequestID, info),
+	}
 	return
 }
 
-func (p *P2PService) handleSyncBlockReply(msg network.Message) (_ *network.Message) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Log.Error("got a panic: ", r)
-		}
-	}()
+func (p *P2PService) handleGetSnapshotChunk(msg network.Message) (reply *network.Message) {
+	syncRange, err := getSyncRangeFromMessage(msg.Message)
+	if err != nil {
+		log.Log.Error(err.Error())
+		return
+	}
 
-	blk, err := getBlockFromSyncReplyMessage(msg.Message)
+	data, err := p.chain.SnapshotChunk(syncRange.From, syncRange.Count)
 	if err != nil {
-		log.Log.Error("get block failed: ", err.Error())
-	} else {
-		p.chain.AddBlock(*blk)
+		log.Log.Error("read snapshot chunk failed: ", err.Error())
+		data = nil
 	}
 
-	//todo: call block sync module's method, not call syncBlockWait directly
-	syncBlockWait.Done()
+	reply = &network.Message{
+		Message: newSnapshotChunkReplyMessage(syncRange.RequestID, data),
+	}
+	return
+}
+
+func (p *P2PService) handleSyncReply(msg network.Message) (_ *network.Message) {
+	p.receiveSyncReply(msg.Message)
 	return
 }
 
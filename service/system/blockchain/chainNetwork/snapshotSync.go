@@ -0,0 +1,182 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainNetwork
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/network"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
+	"strconv"
+)
+
+//fast sync restores an empty chain from the last snapshot of the peers instead of executing all the blocks:
+//1. the snapshot info is fetched from the peers, and the highest one is chosen.
+//2. the headers are synced to the block next to the snapshot, the next block commits the snapshot root.
+//3. the blocks at the snapshot height and the next one are synced and verified with their finality proofs.
+//4. the snapshot chunks are downloaded one by one, every chunk is verified by its root committed and restored.
+//the blocks after the snapshot are synced as usual.
+func (p *P2PService) EnableFastSync() {
+	p.fastSync = true
+}
+
+func (p *P2PService) latestSnapshot(nodes []network.Node) (info chainStructure.SnapshotInfo, err error) {
+	err = errors.New("no snapshot on the peers")
+
+	for _, peer := range p.rankedPeers(nodes) {
+		reply, reqErr := p.syncRequest(peer, MessageTypes.GetSnapshot, 0, 0)
+		if reqErr != nil {
+			p.scorePeer(peer, peerScoreTimeout)
+			continue
+		}
+
+		infoReply := snapshotInfoReplyMessage{}
+		if jsonErr := json.Unmarshal(reply.Payload, &infoReply); jsonErr != nil {
+			p.scorePeer(peer, peerScoreInvalid)
+			continue
+		}
+
+		if infoReply.Info.Height > info.Height {
+			info = infoReply.Info
+			err = nil
+		}
+	}
+
+	return
+}
+
+//sync the headers from the genesis block to the height, only the last two headers are returned
+func (p *P2PService) syncHeadersTo(nodes []network.Node, height uint64) (headers []syncHeader, err error) {
+	prevHash := p.chain.Config.GenesisHash
+	for next := uint64(0); next <= height; {
+		count := height - next + 1
+		if count > syncHeadersBatch {
+			count = syncHeadersBatch
+		}
+
+		batch, syncErr := p.syncHeaders(nodes, next, count, prevHash)
+		if syncErr != nil {
+			err = syncErr
+			return
+		}
+
+		headers = append(headers, batch...)
+		if len(headers) > 2 {
+			headers = headers[len(headers)-2:]
+		}
+		next += uint64(len(batch))
+		prevHash = batch[len(batch)-1].Seal.Hash
+
+		p.updateProgress(func(progress *SyncProgress) {
+			progress.HeaderHeight = next - 1
+		})
+	}
+
+	return
+}
+
+//the chunks are downloaded and restored one by one, a chunk not matching its root is fetched from the other peers.
+func (p *P2PService) restoreSnapshotChunks(nodes []network.Node, restorer *chainStructure.SnapshotRestorer, height uint64) (err error) {
+	peers := p.rankedPeers(nodes)
+	if len(peers) == 0 {
+		err = errors.New("no peer to sync")
+		return
+	}
+
+	for idx := uint64(0); idx < restorer.ChunkCount(); idx++ {
+		err = errors.New("restore snapshot chunk " + strconv.FormatUint(idx, 10) + " failed")
+
+		for attempt := 0; attempt < syncMaxAttempts; attempt++ {
+			peer := peers[(int(idx)+attempt)%len(peers)]
+			reply, reqErr := p.syncRequest(peer, MessageTypes.GetSnapshotChunk, height, idx)
+			if reqErr != nil {
+				p.scorePeer(peer, peerScoreTimeout)
+				continue
+			}
+
+			chunkReply := snapshotChunkReplyMessage{}
+			if jsonErr := json.Unmarshal(reply.Payload, &chunkReply); jsonErr != nil || len(chunkReply.Data) == 0 {
+				p.scorePeer(peer, peerScoreInvalid)
+				continue
+			}
+
+			if restoreErr := restorer.RestoreChunk(chunkReply.Data); restoreErr != nil {
+				log.Log.Warn("restore snapshot chunk ", idx, " failed: ", restoreErr.Error())
+				p.scorePeer(peer, peerScoreInvalid)
+				continue
+			}
+
+			p.scorePeer(peer, peerScoreSuccess)
+			err = nil
+			break
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (p *P2PService) syncSnapshot(nodes []network.Node) (err error) {
+	info, err := p.latestSnapshot(nodes)
+	if err != nil {
+		return
+	}
+
+	log.Log.Println("fast sync from the snapshot @height ", info.Height)
+	headers, err := p.syncHeadersTo(nodes, info.Height+1)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(headers[1].Header.SnapshotRoot, info.Root) {
+		err = errors.New("snapshot root is not same as the block committed")
+		return
+	}
+
+	blocks, err := p.syncBodies(nodes, headers)
+	if err != nil {
+		return
+	}
+
+	restorer, err := p.chain.NewSnapshotRestorer(info, blocks[0].Block, blocks[0].FinalityProof, blocks[1].Block.Header)
+	if err != nil {
+		return
+	}
+
+	err = p.restoreSnapshotChunks(nodes, restorer, info.Height)
+	if err == nil {
+		err = restorer.Finish()
+	}
+
+	//the chain syncs from the genesis block after a failed fast sync, so the states restored are cleared
+	if err != nil {
+		if abortErr := restorer.Abort(); abortErr != nil {
+			log.Log.Error("clear the restored states failed: ", abortErr.Error())
+		}
+		return
+	}
+
+	p.updateProgress(func(progress *SyncProgress) {
+		progress.CurrentHeight = info.Height
+	})
+	return
+}
@@ -19,10 +19,14 @@ package chainStructure
 
 import (
 	"errors"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/applicationResult"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
 	"github.com/SealSC/SealABC/service"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"sort"
 	"sync"
 )
 
@@ -50,6 +54,22 @@ type IBlockchainExternalApplication interface {
 	//handle consensus failed
 	Cancel(req blockchainRequest.Entity) (err error)
 
+	//commitment of the application state after the last executed block, must be same on every node
+	StateRoot() (root []byte)
+
+	//undo the blocks above the height, it works offline
+	RevertTo(height uint64) (err error)
+
+	//drop the undo data of the blocks not above the height, they can't be reverted any more
+	PruneUndo(height uint64) (err error)
+
+	//the state after the last executed block for a snapshot, at most count key-value pairs from the start key,
+	//the next is the start key of the following range and empty at the end.
+	SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error)
+
+	//restore the state from a snapshot range by range in key order, the first range replaces the whole state
+	RestoreState(kvList []kvDatabase.KVItem, first bool) (err error)
+
 	//build request list for new block
 	RequestsForBlock(block block.Entity) (entity []blockchainRequest.Entity, cnt uint32)
 
@@ -79,6 +99,13 @@ func (BlankApplication) Execute(req blockchainRequest.Entity, header block.Entit
 	return
 }
 func (BlankApplication) Cancel(req blockchainRequest.Entity) (err error) { return }
+func (BlankApplication) StateRoot() (root []byte)                        { return }
+func (BlankApplication) RevertTo(height uint64) (err error)              { return }
+func (BlankApplication) PruneUndo(height uint64) (err error)             { return }
+func (BlankApplication) SnapshotState(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte, err error) {
+	return
+}
+func (BlankApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) (err error) { return }
 func (BlankApplication) RequestsForBlock(block block.Entity) (entity []blockchainRequest.Entity, cnt uint32) {
 	return
 }
@@ -145,6 +172,73 @@ func (a *applicationExecutor) ExecuteRequest(req blockchainRequest.Entity, blk b
 	return exe.Execute(req, blk, actIndex)
 }
 
+func (a *applicationExecutor) CancelRequest(req blockchainRequest.Entity) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	exe, err := a.getExternalExecutor(req.RequestApplication)
+	if err != nil {
+		return
+	}
+
+	return exe.Cancel(req)
+}
+
+func (a *applicationExecutor) RevertTo(height uint64) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	for name, exe := range a.ExternalExecutors {
+		err = exe.RevertTo(height)
+		if err != nil {
+			log.Log.Error("revert application ", name, " failed: ", err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+func (a *applicationExecutor) PruneUndo(height uint64) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	for name, exe := range a.ExternalExecutors {
+		err = exe.PruneUndo(height)
+		if err != nil {
+			log.Log.Error("prune undo records of application ", name, " failed: ", err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+//combine the state roots of the applications in name order, the applications without state are skipped.
+func (a *applicationExecutor) StateRoot(hashCalc hashes.IHashCalculator) (root []byte) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	var names []string
+	for name := range a.ExternalExecutors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var roots []byte
+	for _, name := range names {
+		appRoot := a.ExternalExecutors[name].StateRoot()
+		if len(appRoot) == 0 {
+			continue
+		}
+
+		roots = append(roots, []byte(name)...)
+		roots = append(roots, appRoot...)
+	}
+
+	return hashCalc.Sum(roots)
+}
+
 func (a *applicationExecutor) GetRequestListToBuildBlock(block block.Entity) (reqList []blockchainRequest.Entity) {
 	a.externalExeLock.RLock()
 	defer a.externalExeLock.RUnlock()
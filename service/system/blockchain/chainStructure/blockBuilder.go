@@ -18,6 +18,8 @@
 package chainStructure
 
 import (
+	"bytes"
+	"errors"
 	"github.com/SealSC/SealABC/dataStructure/merkleTree"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/block"
@@ -25,10 +27,32 @@ import (
 	"time"
 )
 
+func transactionsRoot(requests []blockchainRequest.Entity) (root []byte, err error) {
+	mt := merkleTree.Tree{}
+	for _, req := range requests {
+		mt.AddHash(req.Seal.Hash)
+	}
+
+	return mt.Calculate()
+}
+
+//the body of a block must be same as the transactions root in its signed header
+func (b *Blockchain) VerifyBlockBody(blk block.Entity) (err error) {
+	rt, err := transactionsRoot(blk.Body.Requests)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(rt, blk.Header.TransactionsRoot) {
+		err = errors.New("block body is not same as the transactions root")
+	}
+	return
+}
+
 func (b *Blockchain) buildBasicBlock(requests []blockchainRequest.Entity) (newBlock block.Entity) {
 	newBlock = block.Entity{}
 
-	newBlock.Header.Version = "1"
+	newBlock.Header.Version = block.CurrentVersion
 	newBlock.Body.RequestsCount = len(requests)
 
 	for _, req := range requests {
@@ -40,17 +64,16 @@ func (b *Blockchain) buildBasicBlock(requests []blockchainRequest.Entity) (newBl
 	newBlock.Body.Requests = requests
 	newBlock.Header.Timestamp = uint64(time.Now().Unix())
 
-	mt := merkleTree.Tree{}
-	for _, req := range requests {
-		mt.AddHash(req.Seal.Hash)
-	}
-
-	rt, err := mt.Calculate()
+	rt, err := transactionsRoot(requests)
 	if err != nil {
 		log.Log.Error("calc merkle  tree failed: ", err.Error())
 	}
 	newBlock.Header.TransactionsRoot = rt
 
+	//a block commits the roots after executing its previous block, the roots of itself are known only after it executed.
+	newBlock.Header.StateRoot, newBlock.Header.ResultsRoot = b.ExecutedRoots()
+	newBlock.Header.SnapshotRoot = b.executedSnapshotRoot()
+
 	return
 }
 
@@ -62,6 +85,8 @@ func (b *Blockchain) NewBlankBlock() (newBlock block.Entity) {
 
 		//set block prev hash
 		newBlock.Header.PrevBlock = append([]byte{}, b.lastBlock.Seal.Hash...)
+	} else if len(b.Config.GenesisHash) > 0 {
+		newBlock.Header.PrevBlock = append([]byte{}, b.Config.GenesisHash...)
 	}
 
 	//set block hash
@@ -86,6 +111,8 @@ func (b *Blockchain) NewBlock(requests []blockchainRequest.Entity, blankBlock bl
 
 		//set block prev hash
 		newBlock.Header.PrevBlock = append([]byte{}, b.lastBlock.Seal.Hash...)
+	} else if len(b.Config.GenesisHash) > 0 {
+		newBlock.Header.PrevBlock = append([]byte{}, b.Config.GenesisHash...)
 	}
 
 	//set block hash
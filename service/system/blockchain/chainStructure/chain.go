@@ -28,16 +28,18 @@ type Blockchain struct {
 	Config   Config
 	Executor applicationExecutor
 
-	lastBlock     *block.Entity
-	SQLStorage    *chainSQLStorage.Storage
-	currentHeight uint64
-	operateLock   sync.RWMutex
+	lastBlock       *block.Entity
+	lastResultsRoot []byte
+	SQLStorage      *chainSQLStorage.Storage
+	currentHeight   uint64
+	operateLock     sync.RWMutex
 }
 
 func (b *Blockchain) SetSQLStorage(sqlStorage *chainSQLStorage.Storage) {
 	b.SQLStorage = sqlStorage
 }
 
+//a block left uncommitted by a crash is replayed by Recover after the executors are registered.
 func (b *Blockchain) LoadBlockchain(cfg Config) (err error) {
 	b.Config = cfg
 	b.Executor.ExternalExecutors = map[string]IBlockchainExternalApplication{}
@@ -51,6 +53,7 @@ func (b *Blockchain) LoadBlockchain(cfg Config) (err error) {
 	log.Log.Println("get latest block: ", lastBlock.Header.Height)
 
 	b.lastBlock = lastBlock
+	b.lastResultsRoot = b.getLastResultsRoot()
 	b.currentHeight = lastBlock.Header.Height
 	return
 }
@@ -28,6 +28,15 @@ type Config struct {
 	Signer         signerCommon.ISigner
 	CryptoTools    crypto.Tools
 	NewWhenGenesis bool
-	StorageDriver  kvDatabase.IDriver
-	SQLStorage     *chainSQLStorage.Storage
+
+	//the hash of the genesis file, the block 0 takes it as the previous block
+	GenesisHash   []byte
+	StorageDriver kvDatabase.IDriver
+	SQLStorage    *chainSQLStorage.Storage
+
+	//the application states are snapshot every interval blocks and the snapshot root is committed by the next block,
+	//so the interval must be same on every node, and 0 disables the snapshots.
+	//the snapshot files are saved in the dir for the fast syncing nodes.
+	SnapshotInterval uint64
+	SnapshotDir      string
 }
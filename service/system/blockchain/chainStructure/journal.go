@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainStructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+//the commit protocol of a block:
+//1. the block is written to the journal under its height before any application executes it.
+//2. the receipt of every executed request is written to the journal, and every application saves its writes with
+//   the position and the result of the request in one atomic write, so a replayed request is skipped by the journal
+//   or by the application itself, which returns the saved result for the receipt.
+//3. the block, the receipts and the last block are saved and the journal is deleted in one atomic write.
+//a journal left by a crash is replayed on start, so the chain height and the application states move together.
+const journalKeyPrefix = "journal-"
+
+type blockJournal struct {
+	Block         block.Entity
+	FinalityProof []byte
+}
+
+func journalKey(heightKey []byte) []byte {
+	return append([]byte(journalKeyPrefix), heightKey...)
+}
+
+func journalReceiptKey(heightKey []byte, actIndex uint32) (key []byte) {
+	key = journalKey(heightKey)
+	idx := make([]byte, 4, 4)
+	binary.BigEndian.PutUint32(idx, actIndex)
+	return append(key, idx...)
+}
+
+func (b *Blockchain) writeJournal(heightKey []byte, blk block.Entity, finalityProof []byte) (err error) {
+	journalBytes, err := json.Marshal(blockJournal{
+		Block:         blk,
+		FinalityProof: finalityProof,
+	})
+	if err != nil {
+		return
+	}
+
+	//the receipts journaled for another block at the same height are useless
+	var staleKeys [][]byte
+	kv, err := b.Config.StorageDriver.Get(journalKey(heightKey))
+	if err != nil {
+		return
+	}
+
+	if kv.Exists {
+		last := blockJournal{}
+		if json.Unmarshal(kv.Data, &last) != nil || !bytes.Equal(last.Block.Seal.Hash, blk.Seal.Hash) {
+			for _, r := range b.Config.StorageDriver.Traversal(journalKey(heightKey)) {
+				if len(r.Key) > len(kv.Key) {
+					staleKeys = append(staleKeys, r.Key)
+				}
+			}
+		}
+	}
+
+	err = b.Config.StorageDriver.BatchWrite([]kvDatabase.KVItem{
+		{
+			Key:  journalKey(heightKey),
+			Data: journalBytes,
+		},
+	}, staleKeys)
+	return
+}
+
+//the next block must be committed before anything else if its journal exists.
+//call it after all the application executors are registered.
+func (b *Blockchain) Recover() (err error) {
+	nextHeight := uint64(0)
+	if b.lastBlock != nil {
+		nextHeight = b.lastBlock.Header.Height + 1
+	}
+
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, nextHeight)
+
+	kv, err := b.Config.StorageDriver.Get(journalKey(heightKey))
+	if err != nil || !kv.Exists {
+		return
+	}
+
+	journal := blockJournal{}
+	err = json.Unmarshal(kv.Data, &journal)
+	if err != nil {
+		return
+	}
+
+	log.Log.Warn("recover the uncommitted block @height ", nextHeight)
+	err = b.AddBlock(journal.Block, journal.FinalityProof)
+	if err != nil {
+		log.Log.Error("recover block failed: ", err.Error())
+	}
+	return
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainStructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRecoverReplaysJournaledBlock(t *testing.T) {
+	uninterrupted, _ := newTestChain(t, 0)
+	addTestBlocks(t, uninterrupted, 0, 2)
+
+	chain, app := newTestChain(t, 0)
+	addTestBlocks(t, chain, 0, 1)
+
+	//crash after the journal is written and the first request is executed
+	blk := newTestBlock(2, 2)
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, 2)
+	if err := chain.writeJournal(heightKey, blk, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Execute(blk.Body.Requests[0], blk, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.Recover(); err != nil {
+		t.Fatal(err)
+	}
+
+	if chain.CurrentHeight() != 2 {
+		t.Fatal("journaled block not committed, height: ", chain.CurrentHeight())
+	}
+
+	if app.counter() != 6 {
+		t.Fatal("replayed request executed twice, counter: ", app.counter())
+	}
+
+	//the receipt of the request applied before the crash is rebuilt with its result
+	if !bytes.Equal(chain.lastResultsRoot, uninterrupted.lastResultsRoot) {
+		t.Fatal("results root after the replay differs from the uninterrupted one")
+	}
+
+	receipt, err := chain.GetReceiptByHash(blk.Body.Requests[0].Seal.Hash)
+	if err != nil || receipt.Data == nil || len(receipt.Events) != 1 {
+		t.Fatal("result of the replayed request lost: ", err)
+	}
+
+	if len(chain.Config.StorageDriver.Traversal([]byte(journalKeyPrefix))) != 0 {
+		t.Fatal("journal left after the block committed")
+	}
+}
@@ -18,10 +18,12 @@
 package chainStructure
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"github.com/SealSC/SealABC/dataStructure/merkleTree"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/blockchainRequest"
@@ -30,14 +32,69 @@ import (
 )
 
 const lastBlockKey = "lastBlockKey"
-
-func (b *Blockchain) executeRequest(blk block.Entity) (err error) {
+const lastResultsRootKey = "lastResultsRootKey"
+const finalityProofKeyPrefix = "finality-"
+
+//execute the requests in the block, a failed request gets a failed receipt and will not stop the block.
+//a request has a receipt in the journal was executed before a crash, it is not executed again.
+//returns the receipts to save, the journal keys to delete and the merkle root of the receipts in requests order.
+func (b *Blockchain) executeRequest(blk block.Entity, heightKey []byte) (resultsRoot []byte, receipts []kvDatabase.KVItem, journalKeys [][]byte, err error) {
+	mt := merkleTree.Tree{}
 	for idx, req := range blk.Body.Requests {
+		jKey := journalReceiptKey(heightKey, uint32(idx))
+		journalKeys = append(journalKeys, jKey)
+
+		journaled, getErr := b.Config.StorageDriver.Get(jKey)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		if journaled.Exists {
+			committedHash, hashErr := committedReceiptHash(journaled.Data, b.Config.CryptoTools.HashCalculator)
+			if hashErr != nil {
+				err = hashErr
+				return
+			}
+
+			receipts = append(receipts, kvDatabase.KVItem{
+				Key:  receiptKey(req.Seal.Hash),
+				Data: journaled.Data,
+			})
+			mt.AddHash(committedHash)
+			continue
+		}
+
 		appRet, exeErr := b.Executor.ExecuteRequest(req, blk, uint32(idx))
+		if exeErr != nil {
+			log.Log.Warn("execute request ", req.Seal.HexHash(), " failed: ", exeErr.Error())
+		}
+
+		receipt, kvErr := newReceipt(req, blk.Header.Height, uint32(idx), appRet, exeErr).toKVItem()
+		if kvErr != nil {
+			err = kvErr
+			return
+		}
+
+		committedHash, hashErr := committedReceiptHash(receipt.Data, b.Config.CryptoTools.HashCalculator)
+		if hashErr != nil {
+			err = hashErr
+			return
+		}
+
+		err = b.Config.StorageDriver.Put(kvDatabase.KVItem{
+			Key:  jKey,
+			Data: receipt.Data,
+		})
+		if err != nil {
+			return
+		}
+
+		receipts = append(receipts, receipt)
+		mt.AddHash(committedHash)
 
 		if exeErr != nil {
-			err = exeErr
-			break
+			continue
 		}
 
 		app, _ := b.Executor.getExternalExecutor(req.RequestApplication)
@@ -71,9 +128,9 @@ func (b *Blockchain) executeRequest(blk block.Entity) (err error) {
 				go b.SQLStorage.StoreAddress(blk, newReq)
 			}
 		}
-		//todo: record result
 	}
 
+	resultsRoot, err = mt.Calculate()
 	return
 }
 
@@ -86,8 +143,23 @@ func (b *Blockchain) InternalCall(src string, dst string, data []byte) (ret inte
 	return app.ApplicationInternalCall(src, data)
 }
 
-func (b *Blockchain) AddBlock(blk block.Entity) (err error) {
-	err = b.executeRequest(blk)
+func finalityProofKey(heightKey []byte) []byte {
+	return append([]byte(finalityProofKeyPrefix), heightKey...)
+}
+
+//the finality proof built by the consensus is saved with the block, so the syncing nodes could verify the block.
+//the block is committed by the journal, see journal.go.
+func (b *Blockchain) AddBlock(blk block.Entity, finalityProof []byte) (err error) {
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, blk.Header.Height)
+
+	err = b.writeJournal(heightKey, blk, finalityProof)
+	if err != nil {
+		log.Log.Error("write block journal failed: ", err.Error())
+		return
+	}
+
+	resultsRoot, receipts, journalKeys, err := b.executeRequest(blk, heightKey)
 	if err != nil {
 		log.Log.Error("execute requests in the block failed!")
 		return
@@ -101,10 +173,15 @@ func (b *Blockchain) AddBlock(blk block.Entity) (err error) {
 		return
 	}
 
-	heightKey := make([]byte, 8, 8)
-	binary.BigEndian.PutUint64(heightKey, blk.Header.Height)
+	kvList := receipts
+	if len(finalityProof) > 0 {
+		kvList = append(kvList, kvDatabase.KVItem{
+			Key:  finalityProofKey(heightKey),
+			Data: finalityProof,
+		})
+	}
 
-	err = b.Config.StorageDriver.BatchPut([]kvDatabase.KVItem{
+	err = b.Config.StorageDriver.BatchWrite(append(kvList, []kvDatabase.KVItem{
 		//first: key is height and data is block
 		{
 			Key:  heightKey,
@@ -117,12 +194,18 @@ func (b *Blockchain) AddBlock(blk block.Entity) (err error) {
 			Data: heightKey,
 		},
 
-		//last: save block as last block
+		//save block as last block
 		{
 			Key:  []byte(lastBlockKey),
 			Data: blockBytes,
 		},
-	})
+
+		//last: save the results root of the last block
+		{
+			Key:  []byte(lastResultsRootKey),
+			Data: resultsRoot,
+		},
+	}...), append(journalKeys, journalKey(heightKey)))
 
 	if err != nil {
 		return
@@ -130,6 +213,18 @@ func (b *Blockchain) AddBlock(blk block.Entity) (err error) {
 
 	b.currentHeight = blk.Header.Height
 	b.lastBlock = &blk
+	b.lastResultsRoot = resultsRoot
+
+	//the snapshot root is committed by the next block, so the snapshot must be taken before building it
+	if b.isSnapshotHeight(blk.Header.Height) {
+		if snapshotErr := b.takeSnapshot(blk.Header.Height); snapshotErr != nil {
+			log.Log.Error("snapshot @height ", blk.Header.Height, " failed: ", snapshotErr.Error())
+		}
+	}
+
+	if pruneErr := b.pruneUndo(blk.Header.Height); pruneErr != nil {
+		log.Log.Error("prune undo records @height ", blk.Header.Height, " failed: ", pruneErr.Error())
+	}
 
 	if b.SQLStorage != nil {
 		go func() {
@@ -161,6 +256,26 @@ func (b *Blockchain) GetBlockByHeight(height uint64) (blk block.Entity, err erro
 	return
 }
 
+func (b *Blockchain) GetFinalityProof(height uint64) (proof []byte, err error) {
+	b.operateLock.RLock()
+	defer b.operateLock.RUnlock()
+
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, height)
+	kv, err := b.Config.StorageDriver.Get(finalityProofKey(heightKey))
+	if err != nil {
+		return
+	}
+
+	if !kv.Exists {
+		err = errors.New("no finality proof")
+		return
+	}
+
+	proof = kv.Data
+	return
+}
+
 func (b *Blockchain) getBlockFromKVDBByHeight(height uint64) (blk chainTables.BlockListRow, err error) {
 	blkEntity, err := b.GetBlockByHeight(height)
 	if err != nil {
@@ -243,6 +358,48 @@ func (b *Blockchain) GetLastBlock() (last *block.Entity) {
 	return
 }
 
+func (b *Blockchain) getLastResultsRoot() (root []byte) {
+	kv, _ := b.Config.StorageDriver.Get([]byte(lastResultsRootKey))
+	return kv.Data
+}
+
+//the state root and the results root after executing the last block, they are committed by the next block.
+func (b *Blockchain) ExecutedRoots() (stateRoot []byte, resultsRoot []byte) {
+	stateRoot = b.Executor.StateRoot(b.Config.CryptoTools.HashCalculator)
+
+	b.operateLock.RLock()
+	defer b.operateLock.RUnlock()
+
+	resultsRoot = append([]byte{}, b.lastResultsRoot...)
+	return
+}
+
+func (b *Blockchain) VerifyRoots(blk block.Entity) (err error) {
+	//legacy blocks commit no roots, they are only accepted until the first block committing the roots
+	if blk.Header.Version == block.LegacyVersion {
+		last := b.GetLastBlock()
+		if last != nil && last.Header.Version != block.LegacyVersion {
+			return errors.New("legacy block after the blocks committing the roots")
+		}
+		return
+	}
+
+	stateRoot, resultsRoot := b.ExecutedRoots()
+	if !bytes.Equal(blk.Header.StateRoot, stateRoot) {
+		return errors.New("state root is not same as local execution")
+	}
+
+	if !bytes.Equal(blk.Header.ResultsRoot, resultsRoot) {
+		return errors.New("results root is not same as local execution")
+	}
+
+	if !bytes.Equal(blk.Header.SnapshotRoot, b.executedSnapshotRoot()) {
+		return errors.New("snapshot root is not same as local snapshot")
+	}
+
+	return
+}
+
 func (b *Blockchain) CurrentHeight() uint64 {
 	return b.currentHeight
 }
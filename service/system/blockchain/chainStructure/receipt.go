@@ -0,0 +1,132 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainStructure
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/metadata/applicationResult"
+	"github.com/SealSC/SealABC/metadata/blockchainRequest"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+const receiptKeyPrefix = "receipt-"
+
+//the error code of a failed request whose error has no code
+const UnknownErrorCode = -1
+
+//the outcome of a request executed in a block
+type Receipt struct {
+	RequestHash        []byte
+	RequestApplication string
+	RequestAction      string
+
+	Success   bool
+	ErrorCode int64
+	Error     string
+
+	Data   interface{}
+	Events []applicationResult.Event
+
+	BlockHeight uint64
+	ActionIndex uint32
+}
+
+type codedError interface {
+	Code() int64
+}
+
+func receiptKey(reqHash []byte) []byte {
+	return append([]byte(receiptKeyPrefix), reqHash...)
+}
+
+func newReceipt(req blockchainRequest.Entity, height uint64, actIndex uint32, appRet applicationResult.Entity, exeErr error) (receipt Receipt) {
+	receipt = Receipt{
+		RequestHash:        req.Seal.Hash,
+		RequestApplication: req.RequestApplication,
+		RequestAction:      req.RequestAction,
+		BlockHeight:        height,
+		ActionIndex:        actIndex,
+	}
+
+	if exeErr != nil {
+		receipt.ErrorCode = UnknownErrorCode
+		if coded, ok := exeErr.(codedError); ok {
+			receipt.ErrorCode = coded.Code()
+		}
+
+		receipt.Error = exeErr.Error()
+		return
+	}
+
+	receipt.Success = true
+	receipt.Data = appRet.Data
+	receipt.Events = appRet.Events
+	return
+}
+
+//the hash of a saved receipt in the results root. the error text is the local diagnostics of a node and may differ
+//between nodes, so it's removed and only the error code of a failed request is committed.
+func committedReceiptHash(receiptBytes []byte, hashCalc hashes.IHashCalculator) (hash []byte, err error) {
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(receiptBytes, &fields)
+	if err != nil {
+		return
+	}
+
+	delete(fields, "Error")
+	committedBytes, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	hash = hashCalc.Sum(committedBytes)
+	return
+}
+
+func (r Receipt) toKVItem() (item kvDatabase.KVItem, err error) {
+	receiptBytes, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	item = kvDatabase.KVItem{
+		Key:  receiptKey(r.RequestHash),
+		Data: receiptBytes,
+	}
+	return
+}
+
+func (b *Blockchain) GetReceiptByHash(reqHash []byte) (receipt Receipt, err error) {
+	b.operateLock.RLock()
+	defer b.operateLock.RUnlock()
+
+	kv, err := b.Config.StorageDriver.Get(receiptKey(reqHash))
+	if err != nil {
+		return
+	}
+
+	if !kv.Exists {
+		err = errors.New("no such receipt")
+		return
+	}
+
+	err = json.Unmarshal(kv.Data, &receipt)
+	return
+}
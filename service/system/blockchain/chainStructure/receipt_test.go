@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainStructure
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/metadata/applicationResult"
+	"github.com/SealSC/SealABC/metadata/blockchainRequest"
+)
+
+type testCodedError struct {
+	code int64
+	text string
+}
+
+func (e testCodedError) Error() string { return e.text }
+func (e testCodedError) Code() int64   { return e.code }
+
+func committedHashOf(t *testing.T, r Receipt) []byte {
+	kv, err := r.toKVItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := committedReceiptHash(kv.Data, sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestReceiptCommittedHash(t *testing.T) {
+	sha3.Load()
+	req := blockchainRequest.Entity{}
+	req.Seal.Hash = []byte("request")
+
+	local := newReceipt(req, 1, 0, applicationResult.Entity{}, testCodedError{code: 7, text: "no balance of 0x01"})
+	remote := newReceipt(req, 1, 0, applicationResult.Entity{}, testCodedError{code: 7, text: "insufficient balance"})
+	if !bytes.Equal(committedHashOf(t, local), committedHashOf(t, remote)) {
+		t.Fatal("the error text must not change the committed hash")
+	}
+
+	if local.Error != "no balance of 0x01" {
+		t.Fatal("the local receipt must keep the error text")
+	}
+
+	other := newReceipt(req, 1, 0, applicationResult.Entity{}, testCodedError{code: 8, text: "no balance of 0x01"})
+	if bytes.Equal(committedHashOf(t, local), committedHashOf(t, other)) {
+		t.Fatal("the error code must change the committed hash")
+	}
+
+	unknown := newReceipt(req, 1, 0, applicationResult.Entity{}, errors.New("failed"))
+	if unknown.ErrorCode != UnknownErrorCode {
+		t.Fatal("an error without code must get the unknown error code")
+	}
+
+	//the hash of a saved receipt is same as the hash of the executed one
+	success := newReceipt(req, 1, 0, applicationResult.Entity{Data: map[string]interface{}{"b": 1, "a": "x"}}, nil)
+	kv, _ := success.toKVItem()
+	saved := Receipt{}
+	if err := json.Unmarshal(kv.Data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committedHashOf(t, success), committedHashOf(t, saved)) {
+		t.Fatal("the committed hash changed after saving the receipt")
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainStructure
+
+import (
+	"bytes"
+	"errors"
+	"github.com/SealSC/SealABC/crypto"
+	"github.com/SealSC/SealABC/dataStructure/merkleTree"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/metadata/seal"
+)
+
+//the proof that a request is included in a block: the merkle audit path from the request hash to the transactions
+//root, and the signed header carries the root. the finality proof of the block is attached if it was saved.
+type RequestProof struct {
+	Header        block.Header
+	Seal          seal.Entity
+	FinalityProof []byte
+	Proof         merkleTree.Proof
+}
+
+func (b *Blockchain) GetRequestProof(reqHash []byte) (proof RequestProof, err error) {
+	receipt, err := b.GetReceiptByHash(reqHash)
+	if err != nil {
+		return
+	}
+
+	blk, err := b.GetBlockByHeight(receipt.BlockHeight)
+	if err != nil {
+		return
+	}
+
+	idx := int(receipt.ActionIndex)
+	if idx >= len(blk.Body.Requests) || !bytes.Equal(blk.Body.Requests[idx].Seal.Hash, reqHash) {
+		err = errors.New("request is not in the block of its receipt")
+		return
+	}
+
+	mt := merkleTree.Tree{}
+	for _, req := range blk.Body.Requests {
+		mt.AddHash(req.Seal.Hash)
+	}
+
+	_, err = mt.Calculate()
+	if err != nil {
+		return
+	}
+
+	proof.Proof, err = mt.Proof(idx)
+	if err != nil {
+		return
+	}
+
+	proof.Header = blk.Header
+	proof.Seal = blk.Seal
+	proof.FinalityProof, _ = b.GetFinalityProof(blk.Header.Height)
+	return
+}
+
+//verify the proof without the chain: the header must be signed by its seal and the audit path of the request
+//must lead to the transactions root in the header. the finality proof is checked by the consensus if needed.
+func VerifyRequestProof(proof RequestProof, reqHash []byte, tools crypto.Tools) (err error) {
+	if !bytes.Equal(proof.Proof.Leaf, reqHash) {
+		return errors.New("proof is not for the request")
+	}
+
+	blk := block.Entity{}
+	blk.Header = proof.Header
+	blk.Seal = proof.Seal
+	if _, err = blk.Verify(tools); err != nil {
+		return
+	}
+
+	if !merkleTree.VerifyProof(proof.Proof, proof.Header.TransactionsRoot) {
+		return errors.New("request is not included in the transactions root")
+	}
+
+	return
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainStructure
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/dataStructure/merkleTree"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"strconv"
+)
+
+const undoPrunedHeightKey = "undoPrunedHeight"
+
+//the undo records are kept for the blocks above the oldest kept snapshot,
+//or for the last undoBlocksKept blocks if the snapshots are disabled.
+const undoBlocksKept = 1024
+
+func (b *Blockchain) undoPruneInterval() (interval uint64, kept uint64) {
+	if b.Config.SnapshotInterval > 0 {
+		return b.Config.SnapshotInterval, snapshotsKept * b.Config.SnapshotInterval
+	}
+
+	return undoBlocksKept, undoBlocksKept
+}
+
+//the chain can't be reverted to below the height
+func (b *Blockchain) undoPrunedHeight() (height uint64, err error) {
+	kv, err := b.Config.StorageDriver.Get([]byte(undoPrunedHeightKey))
+	if err != nil || !kv.Exists {
+		return
+	}
+
+	height = binary.BigEndian.Uint64(kv.Data)
+	return
+}
+
+//prune the undo records of the applications every interval blocks after committing the block at the height.
+//the pruned height is saved first, so a revert never starts on the records pruned partly.
+func (b *Blockchain) pruneUndo(height uint64) (err error) {
+	interval, kept := b.undoPruneInterval()
+	if height%interval != 0 || height <= kept {
+		return
+	}
+
+	prunedHeight := height - kept
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, prunedHeight)
+	err = b.Config.StorageDriver.Put(kvDatabase.KVItem{
+		Key:  []byte(undoPrunedHeightKey),
+		Data: heightKey,
+	})
+	if err != nil {
+		return
+	}
+
+	return b.Executor.PruneUndo(prunedHeight)
+}
+
+//the results root after executing the block, rebuilt from its saved receipts
+func (b *Blockchain) resultsRootOf(blk block.Entity) (root []byte, err error) {
+	mt := merkleTree.Tree{}
+	for _, req := range blk.Body.Requests {
+		kv, getErr := b.Config.StorageDriver.Get(receiptKey(req.Seal.Hash))
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		if !kv.Exists {
+			err = errors.New("no receipt of request " + req.Seal.HexHash())
+			return
+		}
+
+		committedHash, hashErr := committedReceiptHash(kv.Data, b.Config.CryptoTools.HashCalculator)
+		if hashErr != nil {
+			err = hashErr
+			return
+		}
+
+		mt.AddHash(committedHash)
+	}
+
+	return mt.Calculate()
+}
+
+//the keys saved for the committed block
+func (b *Blockchain) blockKeys(blk block.Entity, heightKey []byte) (keys [][]byte) {
+	keys = append(keys, heightKey, blk.Seal.Hash, finalityProofKey(heightKey))
+	for _, req := range blk.Body.Requests {
+		keys = append(keys, receiptKey(req.Seal.Hash))
+	}
+
+	return
+}
+
+//undo the blocks above the height, the applications are reverted first and the chain last, and both of them could
+//run again, so an interrupted revert is finished by running it again.
+//the blockchain must not run when reverting, and the sql storage is not reverted.
+func (b *Blockchain) RevertTo(height uint64) (err error) {
+	if b.lastBlock == nil || height > b.currentHeight {
+		err = errors.New("no such block")
+		return
+	}
+
+	prunedHeight, err := b.undoPrunedHeight()
+	if err != nil {
+		return
+	}
+
+	if height < prunedHeight {
+		err = errors.New("the undo records of the blocks below height " + strconv.FormatUint(prunedHeight, 10) + " are pruned")
+		return
+	}
+
+	target, err := b.GetBlockByHeight(height)
+	if err != nil {
+		return
+	}
+
+	resultsRoot, err := b.resultsRootOf(target)
+	if err != nil {
+		return
+	}
+
+	//a block left in the journal may be executed partly
+	err = b.Executor.RevertTo(height)
+	if err != nil {
+		return
+	}
+
+	var delList [][]byte
+	for h := height + 1; h <= b.currentHeight+1; h++ {
+		heightKey := make([]byte, 8, 8)
+		binary.BigEndian.PutUint64(heightKey, h)
+
+		for _, kv := range b.Config.StorageDriver.Traversal(journalKey(heightKey)) {
+			delList = append(delList, kv.Key)
+		}
+
+		if h > b.currentHeight {
+			break
+		}
+
+		blk, getErr := b.GetBlockByHeight(h)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		delList = append(delList, b.blockKeys(blk, heightKey)...)
+	}
+
+	targetBytes, err := json.Marshal(target)
+	if err != nil {
+		return
+	}
+
+	b.operateLock.Lock()
+	defer b.operateLock.Unlock()
+
+	err = b.Config.StorageDriver.BatchWrite([]kvDatabase.KVItem{
+		{
+			Key:  []byte(lastBlockKey),
+			Data: targetBytes,
+		},
+
+		{
+			Key:  []byte(lastResultsRootKey),
+			Data: resultsRoot,
+		},
+	}, delList)
+
+	if err != nil {
+		return
+	}
+
+	log.Log.Println("block chain reverted from height ", b.currentHeight, " to ", height)
+	b.currentHeight = height
+	b.lastBlock = &target
+	b.lastResultsRoot = resultsRoot
+	return
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainStructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto"
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/applicationResult"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/metadata/blockchainRequest"
+	"github.com/SealSC/SealABC/storage/db/dbDrivers/levelDB"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+const testCounterKey = "counter"
+
+//every request adds one to the counter, and the result is the new count
+type testCounterApplication struct {
+	BlankApplication
+	storage   kvDatabase.IDriver
+	stateRoot *StateRootRecorder
+}
+
+func (c *testCounterApplication) Name() string { return "testCounter" }
+
+func (c *testCounterApplication) counter() (count uint64) {
+	kv, _ := c.storage.Get([]byte(testCounterKey))
+	if kv.Exists {
+		count = binary.BigEndian.Uint64(kv.Data)
+	}
+	return
+}
+
+func (c *testCounterApplication) Execute(req blockchainRequest.Entity, blk block.Entity, actIndex uint32) (result applicationResult.Entity, err error) {
+	if replayed, applied := c.stateRoot.AppliedResult(blk.Header.Height, actIndex); applied {
+		return replayed, nil
+	}
+
+	count := c.counter() + 1
+	countBytes := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(countBytes, count)
+
+	executed := applicationResult.Entity{
+		Data:   map[string]uint64{"count": count},
+		Events: []applicationResult.Event{{Name: "counted", Data: count}},
+	}
+
+	err = c.stateRoot.CommitResult(blk.Header.Height, actIndex, []kvDatabase.KVItem{
+		{Key: []byte(testCounterKey), Data: countBytes},
+	}, nil, executed)
+	if err != nil {
+		return
+	}
+
+	result = executed
+	return
+}
+
+func (c *testCounterApplication) StateRoot() []byte { return c.stateRoot.Root() }
+
+func (c *testCounterApplication) RevertTo(height uint64) error { return c.stateRoot.RevertTo(height) }
+
+func (c *testCounterApplication) PruneUndo(height uint64) error { return c.stateRoot.PruneUndo(height) }
+
+func (c *testCounterApplication) SnapshotState(start []byte, count int) ([]kvDatabase.KVItem, []byte, error) {
+	kvList, next := c.stateRoot.Snapshot(start, count)
+	return kvList, next, nil
+}
+
+func (c *testCounterApplication) RestoreState(kvList []kvDatabase.KVItem, first bool) error {
+	return c.stateRoot.Restore(kvList, first)
+}
+
+func newTestDriver(t *testing.T, name string) kvDatabase.IDriver {
+	driver, err := levelDB.NewDriver(levelDB.Config{DBFilePath: filepath.Join(t.TempDir(), name)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(driver.Close)
+	return driver
+}
+
+func newTestChain(t *testing.T, snapshotInterval uint64) (chain *Blockchain, app *testCounterApplication) {
+	log.SetUpLogger(log.Config{})
+	sha3.Load()
+
+	chain = &Blockchain{}
+	err := chain.LoadBlockchain(Config{
+		CryptoTools:      crypto.Tools{HashCalculator: sha3.Sha256},
+		StorageDriver:    newTestDriver(t, "chain"),
+		SnapshotInterval: snapshotInterval,
+		SnapshotDir:      t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app = &testCounterApplication{storage: newTestDriver(t, "app")}
+	app.stateRoot, err = NewStateRootRecorder(app.storage, sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = chain.Executor.RegisterApplicationExecutor(app, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func newTestBlock(height uint64, reqCount int) (blk block.Entity) {
+	blk.Header.Version = block.CurrentVersion
+	blk.Header.Height = height
+	blk.Seal.Hash = []byte("block-" + strconv.FormatUint(height, 10))
+
+	for i := 0; i < reqCount; i++ {
+		req := blockchainRequest.Entity{}
+		req.RequestApplication = "testCounter"
+		req.Seal.Hash = []byte("request-" + strconv.FormatUint(height, 10) + "-" + strconv.Itoa(i))
+		blk.Body.Requests = append(blk.Body.Requests, req)
+	}
+	blk.Body.RequestsCount = reqCount
+	return
+}
+
+func addTestBlocks(t *testing.T, chain *Blockchain, from uint64, to uint64) {
+	for h := from; h <= to; h++ {
+		if err := chain.AddBlock(newTestBlock(h, 2), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRevertTo(t *testing.T) {
+	chain, app := newTestChain(t, 0)
+	addTestBlocks(t, chain, 0, 1)
+
+	root := app.StateRoot()
+	resultsRoot := append([]byte{}, chain.lastResultsRoot...)
+	addTestBlocks(t, chain, 2, 4)
+
+	if err := chain.RevertTo(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if chain.CurrentHeight() != 1 || app.counter() != 4 {
+		t.Fatal("not reverted, height: ", chain.CurrentHeight(), " counter: ", app.counter())
+	}
+
+	if !bytes.Equal(app.StateRoot(), root) || !bytes.Equal(chain.lastResultsRoot, resultsRoot) {
+		t.Fatal("roots not reverted")
+	}
+
+	if _, err := chain.GetBlockByHeight(2); err == nil {
+		t.Fatal("block above the height not deleted")
+	}
+
+	//the reverted chain goes on
+	addTestBlocks(t, chain, 2, 2)
+	if app.counter() != 6 {
+		t.Fatal("wrong counter after reverted: ", app.counter())
+	}
+}
+
+func TestPruneUndo(t *testing.T) {
+	//the undo records are kept for the 2 snapshots
+	chain, app := newTestChain(t, 1)
+	addTestBlocks(t, chain, 0, 4)
+
+	if len(app.storage.Traversal([]byte(undoKeyPrefix))) != 4 {
+		t.Fatal("undo records not pruned: ", len(app.storage.Traversal([]byte(undoKeyPrefix))))
+	}
+
+	if err := chain.RevertTo(1); err == nil {
+		t.Fatal("reverted to below the pruned height")
+	}
+
+	if chain.CurrentHeight() != 4 || app.counter() != 10 {
+		t.Fatal("a refused revert changed the chain")
+	}
+
+	if err := chain.RevertTo(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if app.counter() != 6 {
+		t.Fatal("wrong counter after reverted: ", app.counter())
+	}
+}
@@ -0,0 +1,545 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainStructure
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/dataStructure/merkleTree"
+	"github.com/SealSC/SealABC/log"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+const snapshotRootKeyPrefix = "snapshotRoot-"
+const lastSnapshotKey = "lastSnapshot"
+
+//the state of an application is read in ranges of pairs, and cut into a chunk once the chunk is over the size,
+//only the last snapshots are kept
+const SnapshotChunkSize = 1 << 20
+const snapshotReadCount = 256
+const snapshotsKept = 2
+
+//the snapshot at the height, the states of the applications are cut into chunks by key range,
+//the root commits the roots of the chunks in order, so every chunk is synced and verified alone.
+type SnapshotInfo struct {
+	Height     uint64
+	Root       []byte
+	ChunkRoots [][]byte
+}
+
+//the key-value pairs of an application in a key range
+type snapshotChunk struct {
+	Application string
+	State       []kvDatabase.KVItem
+}
+
+func snapshotRootKey(heightKey []byte) []byte {
+	return append([]byte(snapshotRootKeyPrefix), heightKey...)
+}
+
+func (b *Blockchain) isSnapshotHeight(height uint64) bool {
+	return b.Config.SnapshotInterval > 0 && height > 0 && height%b.Config.SnapshotInterval == 0
+}
+
+//every snapshot is a dir of the chunk files named by their indexes
+func (b *Blockchain) snapshotPath(height uint64) string {
+	return filepath.Join(b.Config.SnapshotDir, "snapshot-"+strconv.FormatUint(height, 10))
+}
+
+func (b *Blockchain) snapshotChunkPath(height uint64, index uint64) string {
+	return filepath.Join(b.snapshotPath(height), strconv.FormatUint(index, 10))
+}
+
+//cut the states of the applications into chunks in name order and key order, the applications without state
+//have no chunk. the chunks are handled one by one, so the whole state is never loaded.
+func (a *applicationExecutor) snapshotChunks(handle func(chunk snapshotChunk) error) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	var names []string
+	for name := range a.ExternalExecutors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		chunk := snapshotChunk{Application: name}
+		chunkSize := 0
+
+		var start []byte
+		for {
+			kvList, next, stateErr := a.ExternalExecutors[name].SnapshotState(start, snapshotReadCount)
+			if stateErr != nil {
+				err = stateErr
+				return
+			}
+
+			for _, kv := range kvList {
+				chunk.State = append(chunk.State, kv)
+				chunkSize += len(kv.Key) + len(kv.Data)
+				if chunkSize < SnapshotChunkSize {
+					continue
+				}
+
+				err = handle(chunk)
+				if err != nil {
+					return
+				}
+
+				chunk = snapshotChunk{Application: name}
+				chunkSize = 0
+			}
+
+			if len(next) == 0 {
+				break
+			}
+			start = next
+		}
+
+		if len(chunk.State) > 0 {
+			err = handle(chunk)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+//the first chunk of an application replaces its whole state
+func (a *applicationExecutor) restoreChunk(chunk snapshotChunk, first bool) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	exe, exists := a.ExternalExecutors[chunk.Application]
+	if !exists {
+		return errors.New("no applicationExecutor named " + chunk.Application)
+	}
+
+	return exe.RestoreState(chunk.State, first)
+}
+
+//clear the applications not in the restored list, they had no state in the snapshot
+func (a *applicationExecutor) clearStates(restored map[string]bool) (err error) {
+	a.externalExeLock.RLock()
+	defer a.externalExeLock.RUnlock()
+
+	for name, exe := range a.ExternalExecutors {
+		if restored[name] {
+			continue
+		}
+
+		err = exe.RestoreState(nil, true)
+		if err != nil {
+			log.Log.Error("clear application ", name, " failed: ", err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+//the root of a chunk combines the application name and the merkle root of its key-value pairs
+func snapshotChunkRoot(chunk snapshotChunk, hashCalc hashes.IHashCalculator) (root []byte, err error) {
+	mt := merkleTree.Tree{}
+	for _, kv := range chunk.State {
+		mt.AddHash(hashCalc.Sum(append(append([]byte{}, kv.Key...), hashCalc.Sum(kv.Data)...)))
+	}
+
+	stateRoot, err := mt.Calculate()
+	if err != nil {
+		return
+	}
+
+	root = hashCalc.Sum(append([]byte(chunk.Application), stateRoot...))
+	return
+}
+
+//the snapshot root combines the roots of the chunks in order
+func snapshotRoot(chunkRoots [][]byte, hashCalc hashes.IHashCalculator) (root []byte) {
+	var roots []byte
+	for _, chunkRoot := range chunkRoots {
+		roots = append(roots, chunkRoot...)
+	}
+
+	return hashCalc.Sum(roots)
+}
+
+//the state roots of the applications in a chunk, the state root of a snapshot combines them in order
+func snapshotStateRoots(chunk snapshotChunk) (roots []byte) {
+	for _, kv := range chunk.State {
+		if string(kv.Key) != stateRootKey || len(kv.Data) == 0 {
+			continue
+		}
+
+		roots = append(roots, []byte(chunk.Application)...)
+		roots = append(roots, kv.Data...)
+	}
+
+	return
+}
+
+func encodeSnapshotChunk(chunk snapshotChunk) (data []byte, err error) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	err = json.NewEncoder(gz).Encode(chunk)
+	if err != nil {
+		return
+	}
+
+	err = gz.Close()
+	data = buf.Bytes()
+	return
+}
+
+func decodeSnapshotChunk(data []byte) (chunk snapshotChunk, err error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	err = json.NewDecoder(gz).Decode(&chunk)
+	return
+}
+
+//record the snapshot root, and the info of the snapshot if the chunk files are saved.
+func (b *Blockchain) saveSnapshot(info SnapshotInfo) (err error) {
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, info.Height)
+
+	kvList := []kvDatabase.KVItem{
+		{
+			Key:  snapshotRootKey(heightKey),
+			Data: info.Root,
+		},
+	}
+
+	if b.Config.SnapshotDir != "" {
+		infoBytes, _ := json.Marshal(info)
+		kvList = append(kvList, kvDatabase.KVItem{
+			Key:  []byte(lastSnapshotKey),
+			Data: infoBytes,
+		})
+
+		if b.Config.SnapshotInterval > 0 && info.Height > snapshotsKept*b.Config.SnapshotInterval {
+			_ = os.RemoveAll(b.snapshotPath(info.Height - snapshotsKept*b.Config.SnapshotInterval))
+		}
+	}
+
+	return b.Config.StorageDriver.BatchPut(kvList)
+}
+
+//a new snapshot dir, the one left by an interrupted snapshot at the height is dropped
+func (b *Blockchain) newSnapshotDir(height uint64) (err error) {
+	if b.Config.SnapshotDir == "" {
+		return
+	}
+
+	err = os.RemoveAll(b.snapshotPath(height))
+	if err != nil {
+		return
+	}
+
+	return os.MkdirAll(b.snapshotPath(height), 0755)
+}
+
+//save the chunk file if the dir is set
+func (b *Blockchain) saveSnapshotChunk(height uint64, index uint64, data []byte) (err error) {
+	if b.Config.SnapshotDir == "" {
+		return
+	}
+
+	return ioutil.WriteFile(b.snapshotChunkPath(height, index), data, 0644)
+}
+
+//snapshot the states after executing the block at a snapshot height, the chunks are saved as they are cut
+func (b *Blockchain) takeSnapshot(height uint64) (err error) {
+	err = b.newSnapshotDir(height)
+	if err != nil {
+		return
+	}
+
+	hashCalc := b.Config.CryptoTools.HashCalculator
+	info := SnapshotInfo{Height: height}
+	err = b.Executor.snapshotChunks(func(chunk snapshotChunk) (err error) {
+		chunkRoot, err := snapshotChunkRoot(chunk, hashCalc)
+		if err != nil {
+			return
+		}
+
+		if b.Config.SnapshotDir != "" {
+			data, encodeErr := encodeSnapshotChunk(chunk)
+			if encodeErr != nil {
+				return encodeErr
+			}
+
+			err = b.saveSnapshotChunk(height, uint64(len(info.ChunkRoots)), data)
+			if err != nil {
+				return
+			}
+		}
+
+		info.ChunkRoots = append(info.ChunkRoots, chunkRoot)
+		return
+	})
+
+	if err != nil {
+		if b.Config.SnapshotDir != "" {
+			_ = os.RemoveAll(b.snapshotPath(height))
+		}
+		return
+	}
+
+	info.Root = snapshotRoot(info.ChunkRoots, hashCalc)
+	err = b.saveSnapshot(info)
+	if err != nil {
+		return
+	}
+
+	log.Log.Println("snapshot @height ", height, " root: ", info.Root, " chunks: ", len(info.ChunkRoots))
+	return
+}
+
+//the snapshot root committed by the next block, it's empty if the last block is not at a snapshot height.
+func (b *Blockchain) executedSnapshotRoot() (root []byte) {
+	b.operateLock.RLock()
+	defer b.operateLock.RUnlock()
+
+	if b.lastBlock == nil || !b.isSnapshotHeight(b.lastBlock.Header.Height) {
+		return
+	}
+
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, b.lastBlock.Header.Height)
+	kv, _ := b.Config.StorageDriver.Get(snapshotRootKey(heightKey))
+	return kv.Data
+}
+
+func (b *Blockchain) LastSnapshot() (info SnapshotInfo, err error) {
+	kv, err := b.Config.StorageDriver.Get([]byte(lastSnapshotKey))
+	if err != nil {
+		return
+	}
+
+	if !kv.Exists {
+		err = errors.New("no snapshot")
+		return
+	}
+
+	err = json.Unmarshal(kv.Data, &info)
+	return
+}
+
+func (b *Blockchain) SnapshotChunk(height uint64, index uint64) (data []byte, err error) {
+	return ioutil.ReadFile(b.snapshotChunkPath(height, index))
+}
+
+//restores an empty chain from a snapshot downloaded from the peers chunk by chunk.
+//the chunk roots are verified by the snapshot root in the header of the next block and every chunk by its root,
+//the block at the snapshot height becomes the last block once all the chunks are restored,
+//and the blocks before it are never synced.
+type SnapshotRestorer struct {
+	chain         *Blockchain
+	info          SnapshotInfo
+	blk           block.Entity
+	finalityProof []byte
+	next          block.Header
+
+	restored     uint64
+	restoredApps map[string]bool
+	stateRoots   []byte
+}
+
+func (b *Blockchain) NewSnapshotRestorer(info SnapshotInfo, blk block.Entity, finalityProof []byte, next block.Header) (r *SnapshotRestorer, err error) {
+	if b.GetLastBlock() != nil {
+		err = errors.New("only an empty chain could be restored from a snapshot")
+		return
+	}
+
+	if next.Height != blk.Header.Height+1 || !bytes.Equal(next.PrevBlock, blk.Seal.Hash) {
+		err = errors.New("the header is not the next of the snapshot block")
+		return
+	}
+
+	if info.Height != blk.Header.Height {
+		err = errors.New("snapshot is not at the block height")
+		return
+	}
+
+	if !bytes.Equal(snapshotRoot(info.ChunkRoots, b.Config.CryptoTools.HashCalculator), next.SnapshotRoot) {
+		err = errors.New("snapshot root is not same as the block committed")
+		return
+	}
+
+	err = b.newSnapshotDir(info.Height)
+	if err != nil {
+		return
+	}
+
+	r = &SnapshotRestorer{
+		chain:         b,
+		info:          info,
+		blk:           blk,
+		finalityProof: finalityProof,
+		next:          next,
+		restoredApps:  map[string]bool{},
+	}
+	return
+}
+
+func (r *SnapshotRestorer) ChunkCount() uint64 {
+	return uint64(len(r.info.ChunkRoots))
+}
+
+//verify the chunk by its root and restore it, the chunks must be restored in order.
+//the restored chunk is saved, so the chain could serve the snapshot once restored.
+func (r *SnapshotRestorer) RestoreChunk(data []byte) (err error) {
+	if r.restored >= r.ChunkCount() {
+		return errors.New("all chunks restored")
+	}
+
+	chunk, err := decodeSnapshotChunk(data)
+	if err != nil {
+		return
+	}
+
+	chunkRoot, err := snapshotChunkRoot(chunk, r.chain.Config.CryptoTools.HashCalculator)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(chunkRoot, r.info.ChunkRoots[r.restored]) {
+		return errors.New("chunk root is not same as the snapshot committed")
+	}
+
+	err = r.chain.Executor.restoreChunk(chunk, !r.restoredApps[chunk.Application])
+	if err != nil {
+		return
+	}
+
+	if saveErr := r.chain.saveSnapshotChunk(r.info.Height, r.restored, data); saveErr != nil {
+		log.Log.Warn("save the restored snapshot chunk failed: ", saveErr.Error())
+	}
+
+	r.restoredApps[chunk.Application] = true
+	r.stateRoots = append(r.stateRoots, snapshotStateRoots(chunk)...)
+	r.restored++
+	return
+}
+
+//clear the states restored, so the chain could sync from the genesis block
+func (r *SnapshotRestorer) Abort() (err error) {
+	if r.chain.GetLastBlock() != nil {
+		return errors.New("the chain is restored")
+	}
+
+	return r.chain.Executor.clearStates(nil)
+}
+
+//the state root of the restored applications must be same as the next block committed,
+//the snapshot block becomes the last block after it.
+func (r *SnapshotRestorer) Finish() (err error) {
+	if r.restored != r.ChunkCount() {
+		return errors.New("not all chunks restored")
+	}
+
+	if !bytes.Equal(r.chain.Config.CryptoTools.HashCalculator.Sum(r.stateRoots), r.next.StateRoot) {
+		return errors.New("state root is not same as the block committed")
+	}
+
+	err = r.chain.Executor.clearStates(r.restoredApps)
+	if err != nil {
+		return
+	}
+
+	b := r.chain
+	blk := r.blk
+	b.operateLock.Lock()
+	defer b.operateLock.Unlock()
+
+	if b.lastBlock != nil {
+		return errors.New("only an empty chain could be restored from a snapshot")
+	}
+
+	blockBytes, err := json.Marshal(blk)
+	if err != nil {
+		return
+	}
+
+	heightKey := make([]byte, 8, 8)
+	binary.BigEndian.PutUint64(heightKey, blk.Header.Height)
+
+	kvList := []kvDatabase.KVItem{
+		{
+			Key:  heightKey,
+			Data: blockBytes,
+		},
+
+		{
+			Key:  blk.Seal.Hash,
+			Data: heightKey,
+		},
+
+		{
+			Key:  []byte(lastBlockKey),
+			Data: blockBytes,
+		},
+
+		//the results root after executing the block is committed by the next block
+		{
+			Key:  []byte(lastResultsRootKey),
+			Data: r.next.ResultsRoot,
+		},
+	}
+
+	if len(r.finalityProof) > 0 {
+		kvList = append(kvList, kvDatabase.KVItem{
+			Key:  finalityProofKey(heightKey),
+			Data: r.finalityProof,
+		})
+	}
+
+	err = b.Config.StorageDriver.BatchPut(kvList)
+	if err != nil {
+		return
+	}
+
+	b.currentHeight = blk.Header.Height
+	b.lastBlock = &blk
+	b.lastResultsRoot = r.next.ResultsRoot
+
+	err = b.saveSnapshot(r.info)
+	if err != nil {
+		log.Log.Warn("save the restored snapshot failed: ", err.Error())
+		err = nil
+	}
+
+	log.Log.Println("chain restored from the snapshot @height ", blk.Header.Height)
+	return
+}
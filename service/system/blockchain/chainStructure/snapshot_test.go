@@ -0,0 +1,279 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package chainStructure
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/SealSC/SealABC/crypto/hashes/sha3"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+)
+
+func TestSnapshotRoot(t *testing.T) {
+	sha3.Load()
+	chunks := []snapshotChunk{
+		{Application: "a", State: []kvDatabase.KVItem{{Key: []byte("k1"), Data: []byte("v1")}, {Key: []byte("k2"), Data: []byte("v2")}}},
+		{Application: "b", State: []kvDatabase.KVItem{{Key: []byte("k1"), Data: []byte("v1")}}},
+	}
+
+	chunkRoots := func(chunks []snapshotChunk) (roots [][]byte) {
+		for _, chunk := range chunks {
+			root, err := snapshotChunkRoot(chunk, sha3.Sha256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roots = append(roots, root)
+		}
+		return
+	}
+
+	root := snapshotRoot(chunkRoots(chunks), sha3.Sha256)
+	if !bytes.Equal(root, snapshotRoot(chunkRoots(chunks), sha3.Sha256)) {
+		t.Fatal("snapshot root is not deterministic")
+	}
+
+	changed := []snapshotChunk{chunks[0], {Application: "b", State: []kvDatabase.KVItem{{Key: []byte("k1"), Data: []byte("v2")}}}}
+	if bytes.Equal(root, snapshotRoot(chunkRoots(changed), sha3.Sha256)) {
+		t.Fatal("snapshot root not changed by the state")
+	}
+
+	//the same state moved to another application
+	renamed := []snapshotChunk{chunks[0], {Application: "c", State: chunks[1].State}}
+	if bytes.Equal(root, snapshotRoot(chunkRoots(renamed), sha3.Sha256)) {
+		t.Fatal("snapshot root not changed by the application name")
+	}
+
+	reordered := []snapshotChunk{chunks[1], chunks[0]}
+	if bytes.Equal(root, snapshotRoot(chunkRoots(reordered), sha3.Sha256)) {
+		t.Fatal("snapshot root not changed by the chunk order")
+	}
+}
+
+//the header of the block after the snapshot block, built from the roots of the local execution
+func nextTestHeader(chain *Blockchain) (next block.Header) {
+	last := chain.GetLastBlock()
+	next.Version = block.CurrentVersion
+	next.Height = last.Header.Height + 1
+	next.PrevBlock = last.Seal.Hash
+	next.StateRoot, next.ResultsRoot = chain.ExecutedRoots()
+	next.SnapshotRoot = chain.executedSnapshotRoot()
+	return
+}
+
+//restore the last snapshot of the chain to the restored chain chunk by chunk
+func restoreTestSnapshot(t *testing.T, chain *Blockchain, restored *Blockchain, next block.Header) (err error) {
+	info, err := chain.LastSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotBlock, err := chain.GetBlockByHeight(info.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restorer, err := restored.NewSnapshotRestorer(info, snapshotBlock, nil, next)
+	if err != nil {
+		return
+	}
+
+	for idx := uint64(0); idx < restorer.ChunkCount(); idx++ {
+		data, readErr := chain.SnapshotChunk(info.Height, idx)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+
+		err = restorer.RestoreChunk(data)
+		if err != nil {
+			return
+		}
+	}
+
+	return restorer.Finish()
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	chain, app := newTestChain(t, 2)
+	addTestBlocks(t, chain, 0, 2)
+
+	next := nextTestHeader(chain)
+	if len(next.SnapshotRoot) == 0 {
+		t.Fatal("no snapshot root at the snapshot height")
+	}
+
+	wrongRoot := next
+	wrongRoot.SnapshotRoot = []byte("wrong root")
+	restored, restoredApp := newTestChain(t, 2)
+	if restoreTestSnapshot(t, chain, restored, wrongRoot) == nil {
+		t.Fatal("snapshot restored with a wrong snapshot root")
+	}
+
+	wrongState := next
+	wrongState.StateRoot = []byte("wrong root")
+	if restoreTestSnapshot(t, chain, restored, wrongState) == nil {
+		t.Fatal("snapshot restored with a wrong state root")
+	}
+
+	if err := restoreTestSnapshot(t, chain, restored, next); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.CurrentHeight() != 2 || restoredApp.counter() != app.counter() {
+		t.Fatal("wrong state restored, height: ", restored.CurrentHeight(), " counter: ", restoredApp.counter())
+	}
+
+	//the restored chain executes the next block same as the chain snapshot
+	nextBlock := newTestBlock(3, 2)
+	nextBlock.Header = next
+	if err := restored.VerifyRoots(nextBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	addTestBlocks(t, chain, 3, 3)
+	addTestBlocks(t, restored, 3, 3)
+	if !bytes.Equal(nextTestHeader(chain).StateRoot, nextTestHeader(restored).StateRoot) {
+		t.Fatal("restored chain diverged")
+	}
+
+	if restoreTestSnapshot(t, chain, restored, next) == nil {
+		t.Fatal("snapshot restored on a chain not empty")
+	}
+}
+
+func TestRestoreSnapshotChunks(t *testing.T) {
+	chain, app := newTestChain(t, 2)
+
+	//the state is several chunks large
+	filler := make([]byte, 64*1024)
+	var fillerList []kvDatabase.KVItem
+	for i := 0; i < 3*SnapshotChunkSize/len(filler); i++ {
+		fillerList = append(fillerList, kvDatabase.KVItem{Key: []byte("filler-" + strconv.Itoa(i)), Data: filler})
+	}
+
+	if err := app.storage.BatchPut(fillerList); err != nil {
+		t.Fatal(err)
+	}
+	addTestBlocks(t, chain, 0, 2)
+
+	info, err := chain.LastSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(info.ChunkRoots) < 3 {
+		t.Fatal("snapshot not cut into chunks: ", len(info.ChunkRoots))
+	}
+
+	next := nextTestHeader(chain)
+	snapshotBlock, _ := chain.GetBlockByHeight(info.Height)
+	restored, restoredApp := newTestChain(t, 2)
+
+	//a chunk out of order is rejected by its root
+	restorer, err := restored.NewSnapshotRestorer(info, snapshotBlock, nil, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _ := chain.SnapshotChunk(info.Height, 1)
+	if restorer.RestoreChunk(second) == nil {
+		t.Fatal("chunk restored out of order")
+	}
+
+	if restorer.Finish() == nil {
+		t.Fatal("snapshot restored without all chunks")
+	}
+
+	if err = restoreTestSnapshot(t, chain, restored, next); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kv := range fillerList {
+		restoredKV, _ := restoredApp.storage.Get(kv.Key)
+		if !restoredKV.Exists || !bytes.Equal(restoredKV.Data, kv.Data) {
+			t.Fatal("filler not restored: ", string(kv.Key))
+		}
+	}
+
+	if restoredApp.counter() != app.counter() || !bytes.Equal(restoredApp.StateRoot(), app.StateRoot()) {
+		t.Fatal("wrong state restored")
+	}
+
+	//the restored chain serves the same snapshot
+	restoredInfo, err := restored.LastSnapshot()
+	if err != nil || !bytes.Equal(restoredInfo.Root, info.Root) {
+		t.Fatal("restored chain has no snapshot to serve")
+	}
+
+	for idx := range info.ChunkRoots {
+		data, _ := chain.SnapshotChunk(info.Height, uint64(idx))
+		restoredData, readErr := restored.SnapshotChunk(info.Height, uint64(idx))
+		if readErr != nil || !bytes.Equal(data, restoredData) {
+			t.Fatal("restored chain serves a different chunk ", idx)
+		}
+	}
+}
+
+func TestStateSnapshotRanges(t *testing.T) {
+	sha3.Load()
+	staged := kvDatabase.NewStagedDriver(newTestDriver(t, "state"))
+	recorder, err := NewStateRootRecorder(staged, sha3.Sha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		err = recorder.Commit(1, uint32(i), []kvDatabase.KVItem{{Key: []byte("k" + strconv.Itoa(i)), Data: []byte("v")}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//the staged writes are read in the ranges too
+	staged.Begin()
+	_ = staged.Put(kvDatabase.KVItem{Key: []byte("k10"), Data: []byte("v")})
+	_ = staged.Delete([]byte("k3"))
+
+	var expected []string
+	for _, kv := range staged.Traversal(nil) {
+		if !isLocalKey(kv.Key) {
+			expected = append(expected, string(kv.Key))
+		}
+	}
+
+	var keys []string
+	var start []byte
+	for {
+		kvList, next := recorder.Snapshot(start, 3)
+		for _, kv := range kvList {
+			keys = append(keys, string(kv.Key))
+		}
+
+		if len(next) == 0 {
+			break
+		}
+		start = next
+	}
+	staged.Discard()
+
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Fatal("wrong state snapshot: ", keys, " expected: ", expected)
+	}
+}
@@ -0,0 +1,434 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package chainStructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/SealSC/SealABC/crypto/hashes"
+	"github.com/SealSC/SealABC/metadata/applicationResult"
+	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
+	"math"
+	"sync"
+)
+
+const stateRootKey = "applicationStateRoot"
+const executedPositionKey = "applicationExecutedPosition"
+const undoKeyPrefix = "applicationUndo-"
+const executedResultKey = "applicationExecutedResult"
+
+//the old state is deleted in batches before restoring a snapshot
+const restoreClearBatch = 1024
+
+//the state root of an application folds the state changes of every executed request into the last root
+//in execution order, so the nodes executed the same blocks always have the same root.
+//the recorder also saves the position of the last executed request with the root, an application saves its
+//writes with them in one atomic write, so a request replayed after a crash could be found and skipped.
+//the old values of the writes are saved as an undo record of the position, so the application could be reverted.
+//the result of the request is saved with them too, the receipt of a request applied but not journaled by the chain
+//before a crash is rebuilt from it, so the results root is same as the one of an uninterrupted execution.
+type StateRootRecorder struct {
+	storage  kvDatabase.IDriver
+	hashCalc hashes.IHashCalculator
+
+	root     []byte
+	position []byte
+	lock     sync.RWMutex
+}
+
+func NewStateRootRecorder(storage kvDatabase.IDriver, hashCalc hashes.IHashCalculator) (r *StateRootRecorder, err error) {
+	r = &StateRootRecorder{
+		storage:  storage,
+		hashCalc: hashCalc,
+	}
+
+	rootKV, err := storage.Get([]byte(stateRootKey))
+	if err != nil {
+		return
+	}
+
+	positionKV, err := storage.Get([]byte(executedPositionKey))
+	if err != nil {
+		return
+	}
+
+	r.root = rootKV.Data
+	r.position = positionKV.Data
+	return
+}
+
+//the values before the writes of the request at a position, Exists is false if the key did not exist
+type undoRecord struct {
+	PrevRoot     []byte
+	PrevPosition []byte
+	Items        []kvDatabase.KVItem
+}
+
+func undoKey(position []byte) []byte {
+	return append([]byte(undoKeyPrefix), position...)
+}
+
+//the root and the position are deleted if they are empty
+func recorderItems(root []byte, position []byte) (putList []kvDatabase.KVItem, delList [][]byte) {
+	items := []kvDatabase.KVItem{
+		{
+			Key:  []byte(stateRootKey),
+			Data: root,
+		},
+
+		{
+			Key:  []byte(executedPositionKey),
+			Data: position,
+		},
+	}
+
+	for _, item := range items {
+		if len(item.Data) == 0 {
+			delList = append(delList, item.Key)
+		} else {
+			putList = append(putList, item)
+		}
+	}
+	return
+}
+
+//the position is big endian height and action index, so the positions compare as bytes
+func executedPosition(height uint64, actIndex uint32) (position []byte) {
+	position = make([]byte, 12, 12)
+	binary.BigEndian.PutUint64(position, height)
+	binary.BigEndian.PutUint32(position[8:], actIndex)
+	return
+}
+
+//the result of the last executed request, the data is kept as the json it was executed to,
+//so the receipt rebuilt from it has the same bytes
+type savedResult struct {
+	Position []byte
+	Data     json.RawMessage
+	Events   []savedEvent
+}
+
+type savedEvent struct {
+	Name string
+	Data json.RawMessage
+}
+
+func newSavedResult(position []byte, result applicationResult.Entity) (saved savedResult, err error) {
+	saved.Position = position
+	saved.Data, err = json.Marshal(result.Data)
+	if err != nil {
+		return
+	}
+
+	for _, e := range result.Events {
+		eventData, marshalErr := json.Marshal(e.Data)
+		if marshalErr != nil {
+			err = marshalErr
+			return
+		}
+
+		saved.Events = append(saved.Events, savedEvent{
+			Name: e.Name,
+			Data: eventData,
+		})
+	}
+	return
+}
+
+func (s savedResult) toResult() (result applicationResult.Entity) {
+	if string(s.Data) != "null" {
+		result.Data = s.Data
+	}
+
+	for _, e := range s.Events {
+		event := applicationResult.Event{Name: e.Name}
+		if string(e.Data) != "null" {
+			event.Data = e.Data
+		}
+
+		result.Events = append(result.Events, event)
+	}
+	return
+}
+
+//the request at the position was executed and committed
+func (r *StateRootRecorder) Applied(height uint64, actIndex uint32) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if len(r.position) == 0 {
+		return false
+	}
+
+	return string(executedPosition(height, actIndex)) <= string(r.position)
+}
+
+//the request at the position was executed and committed, the result is the one saved by CommitResult if the
+//request is the last executed one, only the last one could be applied but not journaled by the chain.
+func (r *StateRootRecorder) AppliedResult(height uint64, actIndex uint32) (result applicationResult.Entity, applied bool) {
+	if !r.Applied(height, actIndex) {
+		return
+	}
+
+	kv, err := r.storage.Get([]byte(executedResultKey))
+	if err != nil || !kv.Exists {
+		return result, true
+	}
+
+	saved := savedResult{}
+	if json.Unmarshal(kv.Data, &saved) != nil || !bytes.Equal(saved.Position, executedPosition(height, actIndex)) {
+		return result, true
+	}
+
+	return saved.toResult(), true
+}
+
+//save the writes of the request at the position with the new root in one atomic write
+func (r *StateRootRecorder) Commit(height uint64, actIndex uint32, putList []kvDatabase.KVItem, delList [][]byte) (err error) {
+	return r.CommitResult(height, actIndex, putList, delList, applicationResult.Entity{})
+}
+
+//save the writes and the result of the request at the position with the new root in one atomic write
+func (r *StateRootRecorder) CommitResult(height uint64, actIndex uint32, putList []kvDatabase.KVItem, delList [][]byte, result applicationResult.Entity) (err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var changesHash []byte
+	for _, kv := range putList {
+		changesHash = append(changesHash, r.hashCalc.Sum(kv.Key)...)
+		changesHash = append(changesHash, r.hashCalc.Sum(kv.Data)...)
+	}
+
+	for _, k := range delList {
+		changesHash = append(changesHash, r.hashCalc.Sum(k)...)
+	}
+
+	newRoot := r.hashCalc.Sum(append(append([]byte{}, r.root...), r.hashCalc.Sum(changesHash)...))
+	position := executedPosition(height, actIndex)
+
+	undo := undoRecord{
+		PrevRoot:     r.root,
+		PrevPosition: r.position,
+	}
+
+	for _, k := range append(keysOf(putList), delList...) {
+		old, getErr := r.storage.Get(k)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		undo.Items = append(undo.Items, old)
+	}
+
+	undoBytes, err := json.Marshal(undo)
+	if err != nil {
+		return
+	}
+
+	saved, err := newSavedResult(position, result)
+	if err != nil {
+		return
+	}
+
+	resultBytes, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+
+	recorderPutList, _ := recorderItems(newRoot, position)
+	writeList := append(append([]kvDatabase.KVItem{}, putList...), recorderPutList...)
+	writeList = append(writeList, kvDatabase.KVItem{
+		Key:  undoKey(position),
+		Data: undoBytes,
+	}, kvDatabase.KVItem{
+		Key:  []byte(executedResultKey),
+		Data: resultBytes,
+	})
+
+	err = r.storage.BatchWrite(writeList, delList)
+	if err != nil {
+		return
+	}
+
+	r.root = newRoot
+	r.position = position
+	return
+}
+
+func keysOf(kvList []kvDatabase.KVItem) (keys [][]byte) {
+	for _, kv := range kvList {
+		keys = append(keys, kv.Key)
+	}
+	return
+}
+
+//undo the requests executed in the blocks above the height from the last one, every undo is one atomic write,
+//so an interrupted revert could simply run again.
+func (r *StateRootRecorder) RevertTo(height uint64) (err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	last := string(executedPosition(height, math.MaxUint32))
+	for len(r.position) != 0 && string(r.position) > last {
+		kv, getErr := r.storage.Get(undoKey(r.position))
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		if !kv.Exists {
+			err = errors.New("no undo record of the executed request")
+			return
+		}
+
+		undo := undoRecord{}
+		err = json.Unmarshal(kv.Data, &undo)
+		if err != nil {
+			return
+		}
+
+		putList, delList := recorderItems(undo.PrevRoot, undo.PrevPosition)
+		for _, item := range undo.Items {
+			if item.Exists {
+				putList = append(putList, item)
+			} else {
+				delList = append(delList, item.Key)
+			}
+		}
+
+		err = r.storage.BatchWrite(putList, append(delList, kv.Key))
+		if err != nil {
+			return
+		}
+
+		r.root = undo.PrevRoot
+		r.position = undo.PrevPosition
+	}
+
+	return
+}
+
+//drop the undo records of the requests executed in the blocks not above the height,
+//the application can't be reverted to below the height any more.
+func (r *StateRootRecorder) PruneUndo(height uint64) (err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	last := string(executedPosition(height, math.MaxUint32))
+	var delList [][]byte
+	for _, kv := range r.storage.Traversal([]byte(undoKeyPrefix)) {
+		if string(kv.Key[len(undoKeyPrefix):]) <= last {
+			delList = append(delList, kv.Key)
+		}
+	}
+
+	if len(delList) == 0 {
+		return
+	}
+
+	return r.storage.BatchWrite(nil, delList)
+}
+
+func isLocalKey(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(undoKeyPrefix)) || string(key) == executedResultKey
+}
+
+//at most count key-value pairs of the state from the start key for a snapshot, the next is the start key
+//of the following range and empty at the end. the undo records and the last result are local and not in the snapshot.
+func (r *StateRootRecorder) Snapshot(start []byte, count int) (kvList []kvDatabase.KVItem, next []byte) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	next = start
+	for len(kvList) < count {
+		rangeList := r.storage.RangeTraversal(next, count)
+		for _, kv := range rangeList {
+			next = append(append([]byte{}, kv.Key...), 0)
+			if isLocalKey(kv.Key) {
+				continue
+			}
+
+			kvList = append(kvList, kv)
+			if len(kvList) == count {
+				break
+			}
+		}
+
+		if len(rangeList) < count {
+			break
+		}
+	}
+
+	if len(kvList) < count {
+		next = nil
+	}
+	return
+}
+
+//restore the state from a snapshot range by range in key order, the first range replaces the whole state.
+//the root and the position are in the ranges, they are loaded once restored.
+func (r *StateRootRecorder) Restore(kvList []kvDatabase.KVItem, first bool) (err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for first {
+		var delList [][]byte
+		for _, kv := range r.storage.RangeTraversal(nil, restoreClearBatch) {
+			delList = append(delList, kv.Key)
+		}
+
+		if len(delList) == 0 {
+			break
+		}
+
+		err = r.storage.BatchDelete(delList)
+		if err != nil {
+			return
+		}
+	}
+
+	err = r.storage.BatchPut(kvList)
+	if err != nil {
+		return
+	}
+
+	if first {
+		r.root = nil
+		r.position = nil
+	}
+
+	for _, kv := range kvList {
+		switch string(kv.Key) {
+		case stateRootKey:
+			r.root = kv.Data
+		case executedPositionKey:
+			r.position = kv.Data
+		}
+	}
+	return
+}
+
+func (r *StateRootRecorder) Root() (root []byte) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return append([]byte{}, r.root...)
+}
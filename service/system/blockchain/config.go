@@ -18,6 +18,7 @@
 package blockchain
 
 import (
+	"github.com/SealSC/SealABC/consensus"
 	"github.com/SealSC/SealABC/network"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainApi"
 	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
@@ -34,4 +35,10 @@ type Config struct {
 	EnableSQLDB       bool
 	SQLStorage        simpleSQLDatabase.IDriver
 	ExternalExecutors []chainStructure.IBlockchainExternalApplication
+
+	//verify the finality proofs of the synced blocks, such as the hot-stuff consensus service
+	FinalityVerifier consensus.IFinalityVerifier
+
+	//restore an empty chain from the snapshot of the peers
+	FastSync bool
 }
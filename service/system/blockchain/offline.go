@@ -0,0 +1,176 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package blockchain
+
+import (
+	"errors"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainArchive"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
+	"github.com/SealSC/SealABC/service/system/blockchain/serviceInterface"
+	"io"
+	"os"
+)
+
+//report the progress of an offline work
+type ProgressReporter func(done uint64, total uint64)
+
+//load the chain with its applications only, without network, api and consensus
+func loadOfflineChain(cfg Config) (chain *chainStructure.Blockchain, err error) {
+	chain = &chainStructure.Blockchain{}
+	err = chain.LoadBlockchain(cfg.Blockchain)
+	if err != nil {
+		return
+	}
+
+	for _, exe := range cfg.ExternalExecutors {
+		_ = chain.Executor.RegisterApplicationExecutor(exe, chain)
+	}
+
+	return
+}
+
+//revert the chain and the applications to the height, the node must be stopped.
+//a block left in the journal is reverted too.
+func RevertTo(cfg Config, height uint64) (err error) {
+	chain, err := loadOfflineChain(cfg)
+	if err != nil {
+		return
+	}
+
+	return chain.RevertTo(height)
+}
+
+//export the blocks from the height to the height into the archive file, the to height is limited to the last block.
+func Export(cfg Config, file string, fromHeight uint64, toHeight uint64, progress ProgressReporter) (err error) {
+	chain, err := loadOfflineChain(cfg)
+	if err != nil {
+		return
+	}
+
+	if chain.GetLastBlock() == nil {
+		return errors.New("no block to export")
+	}
+
+	if toHeight > chain.CurrentHeight() {
+		toHeight = chain.CurrentHeight()
+	}
+
+	if fromHeight > toHeight {
+		return errors.New("no block in the range")
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	writer, err := chainArchive.NewWriter(f, fromHeight, toHeight)
+	if err != nil {
+		return
+	}
+
+	total := toHeight - fromHeight + 1
+	for h := fromHeight; h <= toHeight; h++ {
+		blk, getErr := chain.GetBlockByHeight(h)
+		if getErr != nil {
+			return getErr
+		}
+
+		//the blocks of the solo and pbft consensus have no finality proof
+		proof, _ := chain.GetFinalityProof(h)
+		err = writer.Write(blk, proof)
+		if err != nil {
+			return
+		}
+
+		if progress != nil {
+			progress(h-fromHeight+1, total)
+		}
+	}
+
+	return writer.Close()
+}
+
+//import the blocks of the archive file above the local height, every block is verified and executed as a synced block.
+//the finality proofs are verified only if the config has a verifier.
+func Import(cfg Config, file string, progress ProgressReporter) (err error) {
+	chain, err := loadOfflineChain(cfg)
+	if err != nil {
+		return
+	}
+
+	err = chain.Recover()
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	reader, err := chainArchive.NewReader(f)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	importer := serviceInterface.NewOfflineService(chain, cfg.FinalityVerifier)
+	total := reader.Header.ToHeight - reader.Header.FromHeight + 1
+	for done := uint64(1); ; done++ {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+
+		nextHeight := uint64(0)
+		if chain.GetLastBlock() != nil {
+			nextHeight = chain.CurrentHeight() + 1
+		}
+
+		height := record.Block.Header.Height
+		if height > nextHeight {
+			return errors.New("the archive does not continue the local chain")
+		}
+
+		if height == nextHeight {
+			err = importer.ImportBlock(record.Block, record.FinalityProof)
+			if err != nil {
+				return
+			}
+		}
+
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	return
+}
@@ -55,6 +55,9 @@ func NewService(cfg Config) service.IService {
 
 	//new networks & service
 	p2p := chainNetwork.NewNetwork(cfg.Network, &chain)
+	if cfg.FastSync {
+		p2p.EnableFastSync()
+	}
 
 	//start api server
 	apiServers := chainApi.NewServer(cfg.Api, &chain, p2p, sqlStorage)
@@ -65,7 +68,14 @@ func NewService(cfg Config) service.IService {
 		apiServers.HttpJSON.Actions.RegisterApplicationQueryHandler(exe.Name(), exe.Query)
 	}
 
-	chainService := serviceInterface.NewServiceInterface(cfg.ServiceName, &chain, p2p, apiServers)
+	//commit the block left by a crash before serving
+	err = chain.Recover()
+	if err != nil {
+		log.Log.Error("recover block chain failed")
+		return nil
+	}
+
+	chainService := serviceInterface.NewServiceInterface(cfg.ServiceName, &chain, p2p, apiServers, cfg.FinalityVerifier)
 
 	//mount to engine
 	_ = engineService.Mount(chainService)
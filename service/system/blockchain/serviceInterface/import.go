@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package serviceInterface
+
+import (
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/metadata/block"
+	"github.com/SealSC/SealABC/service/system/blockchain/chainStructure"
+)
+
+//the blockchain service without network and api, it only imports blocks.
+func NewOfflineService(chain *chainStructure.Blockchain, finalityVerifier consensus.IFinalityVerifier) (bs *BlockchainService) {
+	return &BlockchainService{
+		serviceName:      defaultServiceName,
+		chain:            chain,
+		finalityVerifier: finalityVerifier,
+	}
+}
+
+//verify and execute a block got out of the consensus, such as a block of an archive.
+//the finality proof is verified only if the service has a verifier, and it is saved with the block anyway.
+func (b *BlockchainService) ImportBlock(blk block.Entity, finalityProof []byte) (err error) {
+	b.syncLock.Lock()
+	defer b.syncLock.Unlock()
+
+	err = b.verifyBlock(blk)
+	if err != nil {
+		return
+	}
+
+	if b.finalityVerifier != nil {
+		err = b.verifyFinality(blk, finalityProof)
+		if err != nil {
+			return
+		}
+	}
+
+	return b.chain.AddBlock(blk, finalityProof)
+}
@@ -19,9 +19,12 @@ package serviceInterface
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/SealSC/SealABC/common/utility/serializer/structSerializer"
+	"github.com/SealSC/SealABC/consensus"
+	"github.com/SealSC/SealABC/engine/engineService"
 	"github.com/SealSC/SealABC/log"
 	"github.com/SealSC/SealABC/metadata/block"
 	"github.com/SealSC/SealABC/metadata/serviceRequest"
@@ -38,8 +41,9 @@ type BlockchainService struct {
 	serviceName string
 	syncLock    sync.Mutex
 
-	chain      *chainStructure.Blockchain
-	p2pService *chainNetwork.P2PService
+	chain            *chainStructure.Blockchain
+	p2pService       *chainNetwork.P2PService
+	finalityVerifier consensus.IFinalityVerifier
 
 	apiServers *chainApi.ApiServers
 }
@@ -80,7 +84,7 @@ func (b *BlockchainService) Name() (name string) {
 
 //build a new block for consensus
 func (b *BlockchainService) RequestsForConsensus() (req [][]byte, cnt uint32) {
-	if chainNetwork.Syncing {
+	if b.p2pService.IsSyncing() {
 		return
 	}
 
@@ -123,14 +127,18 @@ func (b *BlockchainService) verifyBlock(blk block.Entity) (err error) {
 		return
 	}
 
-	//genesis block has no prev-block
+	//genesis block commits the hash of the genesis file as the prev-block
 	if blk.Header.Height == 0 {
-		return
+		if !bytes.Equal(blk.Header.PrevBlock, b.chain.Config.GenesisHash) {
+			err = errors.New("genesis block is not built from the local genesis")
+			return
+		}
+		return b.verifyRoots(blk)
 	}
 
 	lastBlk := b.chain.GetLastBlock()
 	if lastBlk == nil {
-		return
+		return b.verifyRoots(blk)
 	}
 
 	if blk.Header.Height != lastBlk.Header.Height+1 {
@@ -145,9 +153,52 @@ func (b *BlockchainService) verifyBlock(blk block.Entity) (err error) {
 		return
 	}
 
+	return b.verifyRoots(blk)
+}
+
+//the roots in the block must be same as the roots after executing the local chain
+func (b *BlockchainService) verifyRoots(blk block.Entity) (err error) {
+	err = b.chain.VerifyRoots(blk)
+	if err != nil {
+		log.Log.Error("block @", blk.Header.Height, " verify roots failed: ", err.Error())
+	}
 	return
 }
 
+//the finality proof must be decided by the consensus members at the block height and the decided data must contain the block
+func (b *BlockchainService) verifyFinality(blk block.Entity, proof []byte) (err error) {
+	if len(proof) == 0 {
+		return errors.New("no finality proof")
+	}
+
+	customerData, err := b.finalityVerifier.VerifyFinality(proof, blk.Header.Height)
+	if err != nil {
+		return
+	}
+
+	reqList, err := engineService.ServiceRequestsFromConsensus(customerData)
+	if err != nil {
+		return
+	}
+
+	for _, req := range reqList {
+		if req.RequestService != b.Name() {
+			continue
+		}
+
+		decided := block.Entity{}
+		if json.Unmarshal([]byte(req.Payload), &decided) != nil {
+			continue
+		}
+
+		if bytes.Equal(decided.Seal.Hash, blk.Seal.Hash) {
+			return
+		}
+	}
+
+	return errors.New("block is not decided by the finality proof")
+}
+
 //handle the new block received from consensus
 func (b *BlockchainService) PreExecute(data interface{}) (result []byte, err error) {
 	blk, err := b.getNewBlockRequestFromConsensus(data)
@@ -212,7 +263,8 @@ func (b *BlockchainService) Execute(data interface{}) (result []byte, err error)
 	//    return
 	//}
 
-	err = b.chain.AddBlock(blk)
+	srvData, _ := data.(serviceRequest.Entity)
+	err = b.chain.AddBlock(blk, srvData.FinalityProof)
 	if err != nil {
 		log.Log.Error("add block @", blk.Header.Height, " failed.")
 	} else {
@@ -221,9 +273,27 @@ func (b *BlockchainService) Execute(data interface{}) (result []byte, err error)
 	return
 }
 
-//placeholder for action rollback
+//the block of a failed consensus round was never added to chain, so there is nothing to roll back,
+//the requests of the block are cancelled by their applications.
 func (b *BlockchainService) Cancel(srvData interface{}) (err error) {
-	//todo: roll back the action if needed (maybe re-sync the block from a right height is the easiest way)
+	blk, err := b.getNewBlockRequestFromConsensus(srvData)
+	if err != nil {
+		return
+	}
+
+	//the block has been synced from the other nodes
+	if blk.Header.Height <= b.chain.CurrentHeight() && b.chain.GetLastBlock() != nil {
+		return
+	}
+
+	for _, req := range blk.Body.Requests {
+		cancelErr := b.chain.Executor.CancelRequest(req)
+		if cancelErr != nil {
+			log.Log.Warn("cancel request of block @", blk.Header.Height, " failed: ", cancelErr.Error())
+		}
+	}
+
+	log.Log.Warn("block @", blk.Header.Height, " cancelled.")
 	return
 }
 
@@ -238,11 +308,22 @@ func (b *BlockchainService) Information() (info service.BasicInformation) {
 	return
 }
 
+func (b *BlockchainService) Stop(ctx context.Context) (err error) {
+	err = b.apiServers.Stop(ctx)
+	if err != nil {
+		log.Log.Warn("stop blockchain api server failed: ", err.Error())
+	}
+
+	b.p2pService.Stop()
+	return
+}
+
 func NewServiceInterface(
 	name string,
 	chain *chainStructure.Blockchain,
 	p2p *chainNetwork.P2PService,
 	apiServers *chainApi.ApiServers,
+	finalityVerifier consensus.IFinalityVerifier,
 ) service.IService {
 
 	if name == "" {
@@ -250,10 +331,15 @@ func NewServiceInterface(
 	}
 
 	bs := &BlockchainService{
-		serviceName: name,
-		chain:       chain,
-		p2pService:  p2p,
-		apiServers:  apiServers,
+		serviceName:      name,
+		chain:            chain,
+		p2pService:       p2p,
+		apiServers:       apiServers,
+		finalityVerifier: finalityVerifier,
+	}
+
+	if finalityVerifier != nil {
+		p2p.SetFinalityVerifier(bs.verifyFinality)
 	}
 
 	return bs
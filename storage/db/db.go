@@ -23,6 +23,7 @@ import (
 	"github.com/SealSC/SealABC/storage/db/dbInterface"
 	"github.com/SealSC/SealABC/storage/db/dbInterface/kvDatabase"
 	"github.com/SealSC/SealABC/storage/db/dbInterface/simpleSQLDatabase"
+	"sync"
 )
 
 type kvDriverLoader func(cfg interface{}) (engine kvDatabase.IDriver, err error)
@@ -36,6 +37,9 @@ var simpleSQLDBDriverLoader = map[string]simpleSQLDriverLoader{
 	dbInterface.MySQL: simpleMysql.NewDriver,
 }
 
+var openedKVDrivers []kvDatabase.IDriver
+var openedKVDriversLock sync.Mutex
+
 func Load() {
 	simpleMysql.Load()
 }
@@ -47,9 +51,28 @@ func NewKVDatabaseDriver(name string, cfg interface{}) (driver kvDatabase.IDrive
 	}
 
 	driver, err = driverLoader(cfg)
+	if err != nil {
+		return
+	}
+
+	openedKVDriversLock.Lock()
+	openedKVDrivers = append(openedKVDrivers, driver)
+	openedKVDriversLock.Unlock()
 	return
 }
 
+//close all the kv database drivers created by NewKVDatabaseDriver, the last opened one will be closed first.
+func CloseAll() {
+	openedKVDriversLock.Lock()
+	defer openedKVDriversLock.Unlock()
+
+	for i := len(openedKVDrivers) - 1; i >= 0; i-- {
+		openedKVDrivers[i].Close()
+	}
+
+	openedKVDrivers = nil
+}
+
 func NewSimpleSQLDatabaseDriver(name string, cfg interface{}) (driver simpleSQLDatabase.IDriver, err error) {
 	driverLoader, supported := simpleSQLDBDriverLoader[name]
 	if !supported {
@@ -74,6 +74,19 @@ func (l *levelDBDriver) BatchCheck(kList [][]byte) (kvList []kvDatabase.KVItem,
 	return l.batchRead(kList, false)
 }
 
+func (l *levelDBDriver) BatchWrite(putList []kvDatabase.KVItem, delList [][]byte) (err error) {
+	batch := new(leveldb.Batch)
+	for _, kv := range putList {
+		batch.Put(kv.Key, kv.Data)
+	}
+
+	for _, k := range delList {
+		batch.Delete(k)
+	}
+	err = l.db.Write(batch, nil)
+	return
+}
+
 func (l *levelDBDriver) Traversal(condition []byte) (kvList []kvDatabase.KVItem) {
 	iterator := l.db.NewIterator(util.BytesPrefix(condition), nil)
 	defer iterator.Release()
@@ -94,3 +107,20 @@ func (l *levelDBDriver) Traversal(condition []byte) (kvList []kvDatabase.KVItem)
 
 	return
 }
+
+func (l *levelDBDriver) RangeTraversal(start []byte, count int) (kvList []kvDatabase.KVItem) {
+	iterator := l.db.NewIterator(&util.Range{Start: start}, nil)
+	defer iterator.Release()
+
+	for len(kvList) < count && iterator.Next() {
+		k := iterator.Key()
+		v := iterator.Value()
+		kvList = append(kvList, kvDatabase.KVItem{
+			Key:    append([]byte{}, k...),
+			Data:   append([]byte{}, v...),
+			Exists: true,
+		})
+	}
+
+	return
+}
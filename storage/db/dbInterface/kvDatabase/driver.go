@@ -30,7 +30,13 @@ type IDriver interface {
 	BatchDelete(kList [][]byte) (err error)
 	BatchCheck(kList [][]byte) (kvList []KVItem, err error)
 
+	//put and delete in one atomic write
+	BatchWrite(putList []KVItem, delList [][]byte) (err error)
+
 	Traversal(condition []byte) (kvList []KVItem)
 
+	//at most count key-value pairs from the start key in key order, the whole store is read range by range with it
+	RangeTraversal(start []byte, count int) (kvList []KVItem)
+
 	Stat() (state interface{}, err error)
 }
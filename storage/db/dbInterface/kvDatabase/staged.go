@@ -0,0 +1,240 @@
+/*
+ * Copyright 2020 The SealABC Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package kvDatabase
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+//a staged driver keeps the writes in memory between Begin and Staged, the reads see the staged writes,
+//so the writes made by many calls could be saved by the caller in one atomic BatchWrite.
+//out of a stage the writes go to the underlying driver directly.
+type StagedDriver struct {
+	IDriver
+
+	staging bool
+	staged  map[string]KVItem
+	lock    sync.RWMutex
+}
+
+func NewStagedDriver(driver IDriver) (s *StagedDriver) {
+	return &StagedDriver{
+		IDriver: driver,
+	}
+}
+
+func (s *StagedDriver) Begin() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.staging = true
+	s.staged = map[string]KVItem{}
+}
+
+//end the stage and return the staged writes
+func (s *StagedDriver) Staged() (putList []KVItem, delList [][]byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var keys []string
+	for k := range s.staged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		kv := s.staged[k]
+		if kv.Exists {
+			putList = append(putList, kv)
+		} else {
+			delList = append(delList, kv.Key)
+		}
+	}
+
+	s.staging = false
+	s.staged = nil
+	return
+}
+
+func (s *StagedDriver) Discard() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.staging = false
+	s.staged = nil
+}
+
+func (s *StagedDriver) stage(kv KVItem) {
+	kv.Key = append([]byte{}, kv.Key...)
+	kv.Data = append([]byte{}, kv.Data...)
+	s.staged[string(kv.Key)] = kv
+}
+
+func (s *StagedDriver) Put(kv KVItem) (err error) {
+	return s.BatchPut([]KVItem{kv})
+}
+
+func (s *StagedDriver) Get(k []byte) (kv KVItem, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if staged, exists := s.staged[string(k)]; exists {
+		return staged, nil
+	}
+
+	return s.IDriver.Get(k)
+}
+
+func (s *StagedDriver) Delete(k []byte) (err error) {
+	return s.BatchDelete([][]byte{k})
+}
+
+func (s *StagedDriver) Check(k []byte) (exists bool, err error) {
+	kv, err := s.Get(k)
+	return kv.Exists, err
+}
+
+func (s *StagedDriver) BatchPut(kvList []KVItem) (err error) {
+	return s.BatchWrite(kvList, nil)
+}
+
+func (s *StagedDriver) BatchGet(kList [][]byte) (kvList []KVItem, err error) {
+	for _, k := range kList {
+		kv, getErr := s.Get(k)
+		if getErr != nil {
+			err = getErr
+			return
+		}
+
+		kvList = append(kvList, kv)
+	}
+
+	return
+}
+
+func (s *StagedDriver) BatchDelete(kList [][]byte) (err error) {
+	return s.BatchWrite(nil, kList)
+}
+
+func (s *StagedDriver) BatchCheck(kList [][]byte) (kvList []KVItem, err error) {
+	kvList, err = s.BatchGet(kList)
+	for i := range kvList {
+		kvList[i].Data = nil
+	}
+
+	return
+}
+
+func (s *StagedDriver) BatchWrite(putList []KVItem, delList [][]byte) (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.staging {
+		return s.IDriver.BatchWrite(putList, delList)
+	}
+
+	for _, kv := range putList {
+		kv.Exists = true
+		s.stage(kv)
+	}
+
+	for _, k := range delList {
+		s.stage(KVItem{Key: k})
+	}
+	return
+}
+
+func (s *StagedDriver) Traversal(condition []byte) (kvList []KVItem) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.staged) == 0 {
+		return s.IDriver.Traversal(condition)
+	}
+
+	merged := map[string]KVItem{}
+	for _, kv := range s.IDriver.Traversal(condition) {
+		merged[string(kv.Key)] = kv
+	}
+
+	for k, kv := range s.staged {
+		if !bytes.HasPrefix(kv.Key, condition) {
+			continue
+		}
+
+		if kv.Exists {
+			merged[k] = kv
+		} else {
+			delete(merged, k)
+		}
+	}
+
+	var keys []string
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		kvList = append(kvList, merged[k])
+	}
+	return
+}
+
+//the staged deletes may drop some pairs of the underlying range, so the range read is enlarged by them
+func (s *StagedDriver) RangeTraversal(start []byte, count int) (kvList []KVItem) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.staged) == 0 {
+		return s.IDriver.RangeTraversal(start, count)
+	}
+
+	merged := map[string]KVItem{}
+	for _, kv := range s.IDriver.RangeTraversal(start, count+len(s.staged)) {
+		merged[string(kv.Key)] = kv
+	}
+
+	for k, kv := range s.staged {
+		if bytes.Compare(kv.Key, start) < 0 {
+			continue
+		}
+
+		if kv.Exists {
+			merged[k] = kv
+		} else {
+			delete(merged, k)
+		}
+	}
+
+	var keys []string
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if len(kvList) == count {
+			break
+		}
+		kvList = append(kvList, merged[k])
+	}
+	return
+}